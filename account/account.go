@@ -6,18 +6,23 @@ package account
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/mode"
 	"github.com/bitmark-inc/bitmarkd/util"
 	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/sha3"
+	"math/big"
 )
 
 // enumeration of supported key algorithms
 const (
 	// list of valid algorithms
-	Nothing = iota // zero keytype **Just for Testing**
-	ED25519 = iota
+	Nothing   = iota // zero keytype **Just for Testing**
+	ED25519   = iota
+	ECDSAP256 = iota // ECDSA with the NIST P-256 curve, for hardware keys
 	// end of list (one greater than last item)
 	algorithmLimit = iota
 )
@@ -34,8 +39,15 @@ const (
 
 	algorithmShift = 4 // shift 4 bits to get algorithm
 
+	// ECDSA P-256 sizes: uncompressed point, scalar and r ++ s
+	p256PublicKeySize  = 65
+	p256PrivateKeySize = 32
+	p256SignatureSize  = 64
 )
 
+// half of the P-256 group order, the largest s of a signature
+var p256HalfOrder = new(big.Int).Rsh(elliptic.P256().Params().N, 1)
+
 // base type for accounts
 type Account struct {
 	AccountInterface
@@ -56,6 +68,14 @@ type ED25519Account struct {
 	PublicKey []byte
 }
 
+// for ecdsa P-256 signatures
+//
+// the public key is the uncompressed point: 0x04 ++ X ++ Y
+type ECDSAP256Account struct {
+	Test      bool
+	PublicKey []byte
+}
+
 // just for debugging
 type NothingAccount struct {
 	Test      bool
@@ -120,6 +140,21 @@ func AccountFromBase58(accountBase58Encoded string) (*Account, error) {
 			},
 		}
 		return account, nil
+	case ECDSAP256:
+		if keyLength != p256PublicKeySize {
+			return nil, fault.ErrInvalidKeyLength
+		}
+		publicKey := accountDecoded[keyVariantLength:checksumStart]
+		if x, _ := elliptic.Unmarshal(elliptic.P256(), publicKey); nil == x {
+			return nil, fault.ErrInvalidPublicKey
+		}
+		account := &Account{
+			AccountInterface: &ECDSAP256Account{
+				Test:      isTest,
+				PublicKey: publicKey,
+			},
+		}
+		return account, nil
 	case Nothing:
 		if 2 != keyLength {
 			return nil, fault.ErrInvalidKeyLength
@@ -183,6 +218,21 @@ func AccountFromBytes(accountBytes []byte) (*Account, error) {
 			},
 		}
 		return account, nil
+	case ECDSAP256:
+		if keyLength != p256PublicKeySize {
+			return nil, fault.ErrInvalidKeyLength
+		}
+		publicKey := accountBytes[keyVariantLength:]
+		if x, _ := elliptic.Unmarshal(elliptic.P256(), publicKey); nil == x {
+			return nil, fault.ErrInvalidPublicKey
+		}
+		account := &Account{
+			AccountInterface: &ECDSAP256Account{
+				Test:      isTest,
+				PublicKey: publicKey,
+			},
+		}
+		return account, nil
 	case Nothing:
 		if 2 != keyLength {
 			return nil, fault.ErrInvalidKeyLength
@@ -274,6 +324,76 @@ func (account ED25519Account) MarshalText() ([]byte, error) {
 	return []byte(account.String()), nil
 }
 
+// ECDSA P-256
+// -----------
+
+// key type code (see enumeration above)
+func (account *ECDSAP256Account) KeyType() int {
+	return ECDSAP256
+}
+
+// fetch the public key as byte slice
+func (account *ECDSAP256Account) PublicKeyBytes() []byte {
+	return account.PublicKey[:]
+}
+
+// check the signature of a message
+//
+// the signature is r ++ s, each 32 bytes big endian, over the SHA-256
+// of the message; only the low s form is accepted so that a signature
+// cannot be altered to give a different transaction id
+func (account *ECDSAP256Account) CheckSignature(message []byte, signature Signature) error {
+
+	if p256SignatureSize != len(signature) {
+		return fault.ErrInvalidSignature
+	}
+
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, account.PublicKey)
+	if nil == x {
+		return fault.ErrInvalidSignature
+	}
+
+	r := new(big.Int).SetBytes(signature[:p256SignatureSize/2])
+	s := new(big.Int).SetBytes(signature[p256SignatureSize/2:])
+	if s.Cmp(p256HalfOrder) > 0 {
+		return fault.ErrInvalidSignature
+	}
+
+	digest := sha256.Sum256(message)
+	publicKey := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     x,
+		Y:     y,
+	}
+	if !ecdsa.Verify(publicKey, digest[:], r, s) {
+		return fault.ErrInvalidSignature
+	}
+	return nil
+}
+
+// byte slice for encoded key
+func (account *ECDSAP256Account) Bytes() []byte {
+	keyVariant := byte(ECDSAP256<<algorithmShift) | publicKeyCode
+	if account.Test {
+		keyVariant |= testKeyCode
+	}
+	return append([]byte{keyVariant}, account.PublicKey[:]...)
+}
+
+// base58 encoding of encoded key
+func (account *ECDSAP256Account) String() string {
+	buffer := account.Bytes()
+	checksum := sha3.Sum256(buffer)
+	buffer = append(buffer, checksum[:checksumLength]...)
+	return util.ToBase58(buffer)
+}
+
+// convert an account to its Base58 JSON form
+func (account ECDSAP256Account) MarshalText() ([]byte, error) {
+	return []byte(account.String()), nil
+}
+
 // Nothing
 // -------
 
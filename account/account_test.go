@@ -6,10 +6,12 @@ package account_test
 
 import (
 	"bytes"
+	"crypto/elliptic"
 	"encoding/hex"
 	"encoding/json"
 	"github.com/bitmark-inc/bitmarkd/account"
 	"github.com/bitmark-inc/bitmarkd/fault"
+	"math/big"
 	"testing"
 )
 
@@ -24,6 +26,7 @@ type accountTest struct {
 // Valid account
 var testAccount = []accountTest{
 	{account.ED25519, decodeHex("60b3c6e20cfff7091a86488b1656b96ec0a2f69907e2c035175918f42c37d72e"), "anF8SWxSRY5vnN3Bbyz9buRYW1hfCAAZxfbv8Fw9SFXaktvLCj"},
+	{account.ECDSAP256, decodeHex("0460fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb67903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299"), "2T9ppne4ggWE1oY9rBXPQpVH6QWXWULGEoBHcihhcaSXvpPco159moMgzFfQeFTB2Q8pSjWfxFtJwWPzgYaHwKhL7wWBNw12"},
 	{account.Nothing, decodeHex("12fa"), "3MvykBZzN"},
 }
 
@@ -106,6 +109,83 @@ func TestInvalidBase58(t *testing.T) {
 	}
 }
 
+// ECDSA P-256 keys must be valid points and scalars
+func TestECDSAP256InvalidBytes(t *testing.T) {
+
+	publicKey := decodeHex("0460fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb67903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299")
+
+	notOnCurve := append([]byte{account.ECDSAP256<<4 | 0x01}, publicKey...)
+	notOnCurve[len(notOnCurve)-1] ^= 0x01
+	if _, err := account.AccountFromBytes(notOnCurve); fault.ErrInvalidPublicKey != err {
+		t.Errorf("point not on curve: error: %v  expected: %v", err, fault.ErrInvalidPublicKey)
+	}
+
+	compressed := append([]byte{account.ECDSAP256<<4 | 0x01}, 0x02)
+	compressed = append(compressed, publicKey[1:33]...)
+	if _, err := account.AccountFromBytes(compressed); fault.ErrInvalidKeyLength != err {
+		t.Errorf("compressed point: error: %v  expected: %v", err, fault.ErrInvalidKeyLength)
+	}
+
+	zero := append([]byte{account.ECDSAP256 << 4}, make([]byte, 32)...)
+	if _, err := account.PrivateKeyFromBytes(zero); fault.ErrInvalidPrivateKey != err {
+		t.Errorf("zero scalar: error: %v  expected: %v", err, fault.ErrInvalidPrivateKey)
+	}
+
+	order := append([]byte{account.ECDSAP256 << 4}, decodeHex("ffffffff00000000ffffffffffffffffbce6faada7179e84f3b9cac2fc632551")...)
+	if _, err := account.PrivateKeyFromBytes(order); fault.ErrInvalidPrivateKey != err {
+		t.Errorf("scalar equal to order: error: %v  expected: %v", err, fault.ErrInvalidPrivateKey)
+	}
+}
+
+// ECDSA P-256 signatures are r ++ s over the SHA-256 of the message
+func TestECDSAP256Signature(t *testing.T) {
+
+	// from RFC 6979 A.2.5 with message "sample" and SHA-256
+	publicKey := decodeHex("0460fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb67903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299")
+	rfcSignature := decodeHex("efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8")
+
+	acc, err := account.AccountFromBytes(append([]byte{account.ECDSAP256<<4 | 0x01}, publicKey...))
+	if nil != err {
+		t.Fatalf("account from bytes error: %s", err)
+	}
+
+	// the RFC signature has a high s so it is not accepted
+	if err := acc.CheckSignature([]byte("sample"), rfcSignature); fault.ErrInvalidSignature != err {
+		t.Errorf("high s signature: error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+
+	// but its low s form is
+	lowS := append(account.Signature{}, rfcSignature...)
+	s := new(big.Int).SetBytes(rfcSignature[32:])
+	s.Sub(elliptic.P256().Params().N, s).FillBytes(lowS[32:])
+	if err := acc.CheckSignature([]byte("sample"), lowS); nil != err {
+		t.Errorf("low s signature: error: %v", err)
+	}
+
+	privateKey, err := account.PrivateKeyFromBytes(append([]byte{account.ECDSAP256 << 4}, decodeHex("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721")...))
+	if nil != err {
+		t.Fatalf("private key from bytes error: %s", err)
+	}
+	if !bytes.Equal(acc.Bytes(), privateKey.Account().Bytes()) {
+		t.Errorf("account: %x  expected: %x", privateKey.Account().Bytes(), acc.Bytes())
+	}
+
+	message := []byte("the message to be signed")
+	signature, err := privateKey.PrivateKeyInterface.(*account.ECDSAP256PrivateKey).Sign(message)
+	if nil != err {
+		t.Fatalf("sign error: %s", err)
+	}
+	if err := acc.CheckSignature(message, signature); nil != err {
+		t.Errorf("check signature error: %s", err)
+	}
+	if err := acc.CheckSignature(append(message, '.'), signature); fault.ErrInvalidSignature != err {
+		t.Errorf("altered message: error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+	if err := acc.CheckSignature(message, signature[1:]); fault.ErrInvalidSignature != err {
+		t.Errorf("short signature: error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+}
+
 // Decode the hex string and return []byte.
 //
 // This is only used in the tests as the source is pre-prepared, so that there won't be any error
@@ -0,0 +1,113 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package account
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"golang.org/x/crypto/ed25519"
+	"strconv"
+	"strings"
+)
+
+// hierarchical deterministic derivation of Ed25519 keys as SLIP-0010
+//
+// Ed25519 only allows hardened children so every path element must be
+// hardened, e.g. "m/44'/731'/0'"
+const (
+	derivationRoot    = "m"
+	derivationKey     = "ed25519 seed"
+	hardenedOffset    = 0x80000000
+	hardenedSuffix    = "'"
+	maximumPathLength = 255
+)
+
+// an extended private key: the key and the chain code for its children
+type extendedKey struct {
+	key       []byte
+	chainCode []byte
+}
+
+// derive the Ed25519 private key for a path from a Base58 encoded seed
+//
+// the 32 byte secret of the seed is the master seed and the test/live
+// indication of the seed is kept, so one seed can be backed up and all
+// of its child accounts recreated
+func DeriveChild(seedBase58Encoded string, path string) (*PrivateKey, error) {
+
+	secretKey, isTest, err := decodeSeed(seedBase58Encoded)
+	if nil != err {
+		return nil, err
+	}
+
+	indexes, err := parseDerivationPath(path)
+	if nil != err {
+		return nil, err
+	}
+
+	k := deriveKey(secretKey[:], indexes)
+
+	_, priv, err := ed25519.GenerateKey(bytes.NewBuffer(k.key))
+	if nil != err {
+		return nil, err
+	}
+
+	privateKey := &PrivateKey{
+		PrivateKeyInterface: &ED25519PrivateKey{
+			Test:       isTest,
+			PrivateKey: priv,
+		},
+	}
+	return privateKey, nil
+}
+
+// convert "m/a'/b'/…" into hardened child indexes
+func parseDerivationPath(path string) ([]uint32, error) {
+
+	elements := strings.Split(strings.TrimSpace(path), "/")
+	if derivationRoot != elements[0] || len(elements) > maximumPathLength+1 {
+		return nil, fault.ErrInvalidDerivationPath
+	}
+
+	indexes := make([]uint32, 0, len(elements)-1)
+	for _, e := range elements[1:] {
+		if !strings.HasSuffix(e, hardenedSuffix) {
+			return nil, fault.ErrInvalidDerivationPath
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(e, hardenedSuffix), 10, 32)
+		if nil != err || n >= hardenedOffset {
+			return nil, fault.ErrInvalidDerivationPath
+		}
+		indexes = append(indexes, uint32(n)+hardenedOffset)
+	}
+	return indexes, nil
+}
+
+// the master key of a seed followed by each child in turn
+func deriveKey(seed []byte, indexes []uint32) *extendedKey {
+	k := splitHMAC([]byte(derivationKey), seed)
+	for _, index := range indexes {
+		data := make([]byte, 1, 1+len(k.key)+4)
+		data = append(data, k.key...)
+		data = append(data, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], index)
+		k = splitHMAC(k.chainCode, data)
+	}
+	return k
+}
+
+// HMAC-SHA512 split into the key (left) and chain code (right) halves
+func splitHMAC(key []byte, data []byte) *extendedKey {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return &extendedKey{
+		key:       sum[:32],
+		chainCode: sum[32:],
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package account
+
+// internal tests: the SLIP-0010 vectors use seeds that are not
+// in the 32 byte Base58 seed format
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"golang.org/x/crypto/ed25519"
+	"testing"
+)
+
+// SLIP-0010 test vector 1 for ed25519
+func TestDeriveKey(t *testing.T) {
+
+	seed := mustHex("000102030405060708090a0b0c0d0e0f")
+
+	tests := []struct {
+		path      string
+		chainCode string
+		key       string
+		publicKey string
+	}{
+		{
+			path:      "m",
+			chainCode: "90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb",
+			key:       "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7",
+			publicKey: "a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed",
+		},
+		{
+			path:      "m/0'",
+			chainCode: "8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69",
+			key:       "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			publicKey: "8c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c",
+		},
+		{
+			path:      "m/0'/1'",
+			chainCode: "a320425f77d1b5c2505a6b1b27382b37368ee640e3557c315416801243552f14",
+			key:       "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2",
+			publicKey: "1932a5270f335bed617d5b935c80aedb1a35bd9fc1e31acafd5372c30f5c1187",
+		},
+	}
+
+	for i, test := range tests {
+		indexes, err := parseDerivationPath(test.path)
+		if nil != err {
+			t.Errorf("%d: %s: parse error: %s", i, test.path, err)
+			continue
+		}
+		k := deriveKey(seed, indexes)
+		if !bytes.Equal(mustHex(test.chainCode), k.chainCode) {
+			t.Errorf("%d: %s: chain code: %x  expected: %s", i, test.path, k.chainCode, test.chainCode)
+		}
+		if !bytes.Equal(mustHex(test.key), k.key) {
+			t.Errorf("%d: %s: key: %x  expected: %s", i, test.path, k.key, test.key)
+		}
+		publicKey, _, err := ed25519.GenerateKey(bytes.NewBuffer(k.key))
+		if nil != err {
+			t.Fatalf("%d: %s: key generation error: %s", i, test.path, err)
+		}
+		if !bytes.Equal(mustHex(test.publicKey), publicKey) {
+			t.Errorf("%d: %s: public key: %x  expected: %s", i, test.path, publicKey, test.publicKey)
+		}
+	}
+}
+
+func TestDeriveChild(t *testing.T) {
+
+	const seed = "5XEECqhR7QBkJezUJiUJBmHaSmffDfVN5atuLnQBHnvfxbsWHuBfQLw"
+
+	first, err := DeriveChild(seed, "m/44'/731'/0'")
+	if nil != err {
+		t.Fatalf("derive error: %s", err)
+	}
+	again, err := DeriveChild(seed, "m/44'/731'/0'")
+	if nil != err {
+		t.Fatalf("derive error: %s", err)
+	}
+	second, err := DeriveChild(seed, "m/44'/731'/1'")
+	if nil != err {
+		t.Fatalf("derive error: %s", err)
+	}
+
+	if !bytes.Equal(first.PrivateKeyBytes(), again.PrivateKeyBytes()) {
+		t.Errorf("derivation is not repeatable: %x  and: %x", first.PrivateKeyBytes(), again.PrivateKeyBytes())
+	}
+	if bytes.Equal(first.PrivateKeyBytes(), second.PrivateKeyBytes()) {
+		t.Errorf("different paths give the same key: %x", first.PrivateKeyBytes())
+	}
+
+	// the account must be usable on the same network as the seed
+	a, err := AccountFromBase58(first.Account().String())
+	if nil != err {
+		t.Fatalf("account from base58 error: %s", err)
+	}
+	message := []byte("derived message")
+	signature := ed25519.Sign(first.PrivateKeyBytes(), message)
+	if err := a.CheckSignature(message, signature); nil != err {
+		t.Errorf("check signature error: %s", err)
+	}
+
+	invalid := []string{
+		"",
+		"44'/731'",
+		"m/",
+		"m/44'/731",
+		"m/44h",
+		"m/-1'",
+		"m/2147483648'",
+		"n/0'",
+	}
+	for i, path := range invalid {
+		if _, err := DeriveChild(seed, path); fault.ErrInvalidDerivationPath != err {
+			t.Errorf("%d: path: %q  error: %v  expected: %v", i, path, err, fault.ErrInvalidDerivationPath)
+		}
+	}
+
+	if _, err := DeriveChild("5XEECqhR7QBkJezUJiUJBmHaSmffDfVN5atuLnQBHnvfxbsWHuBfQLx", "m"); fault.ErrChecksumMismatch != err {
+		t.Errorf("corrupt seed: error: %v  expected: %v", err, fault.ErrChecksumMismatch)
+	}
+}
+
+// only used for the constant test vectors above
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if nil != err {
+		panic(err)
+	}
+	return b
+}
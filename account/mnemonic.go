@@ -0,0 +1,114 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package account
+
+import (
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/util"
+	"golang.org/x/crypto/sha3"
+	"math/big"
+	"strings"
+)
+
+// a recovery phrase holds the same data as a Base58 seed
+//
+// the 275 bits of: network prefix(8) ++ secret key(256) ++ checksum(11)
+// are split into 25 groups of 11 bits, each selecting a word; the
+// checksum is the start of SHA3-256(seed header ++ prefix ++ secret key)
+// so the seed header version is also checked
+const (
+	mnemonicWordCount      = 2048
+	mnemonicBitsPerWord    = 11
+	mnemonicChecksumBits   = 11
+	mnemonicWordsSeparator = " "
+)
+
+// number of words in a recovery phrase
+const MnemonicPhraseLength = 25
+
+// word → index lookup for decoding
+var mnemonicIndex = makeMnemonicIndex()
+
+func makeMnemonicIndex() map[string]int {
+	index := make(map[string]int, mnemonicWordCount)
+	for i, word := range mnemonicWords {
+		index[word] = i
+	}
+	return index
+}
+
+// convert a Base58 encoded seed to its recovery phrase
+func MnemonicFromBase58Seed(seedBase58Encoded string) (string, error) {
+
+	secretKey, isTest, err := decodeSeed(seedBase58Encoded)
+	if nil != err {
+		return "", err
+	}
+
+	prefix := byte(0x00)
+	if isTest {
+		prefix = 0x01
+	}
+	data := append([]byte{prefix}, secretKey[:]...)
+
+	n := new(big.Int).SetBytes(data)
+	n.Lsh(n, mnemonicChecksumBits)
+	n.Or(n, big.NewInt(int64(mnemonicChecksum(data))))
+
+	words := make([]string, MnemonicPhraseLength)
+	mask := big.NewInt(mnemonicWordCount - 1)
+	for i := MnemonicPhraseLength - 1; i >= 0; i -= 1 {
+		words[i] = mnemonicWords[new(big.Int).And(n, mask).Int64()]
+		n.Rsh(n, mnemonicBitsPerWord)
+	}
+	return strings.Join(words, mnemonicWordsSeparator), nil
+}
+
+// convert a recovery phrase back to the Base58 encoded seed
+//
+// words are separated by any white space and case is ignored
+func Base58SeedFromMnemonic(phrase string) (string, error) {
+
+	words := strings.Fields(strings.ToLower(phrase))
+	if MnemonicPhraseLength != len(words) {
+		return "", fault.ErrInvalidSeedLength
+	}
+
+	n := new(big.Int)
+	for _, word := range words {
+		i, ok := mnemonicIndex[word]
+		if !ok {
+			return "", fault.ErrInvalidMnemonicWord
+		}
+		n.Lsh(n, mnemonicBitsPerWord)
+		n.Or(n, big.NewInt(int64(i)))
+	}
+
+	checksum := new(big.Int).And(n, big.NewInt(1<<mnemonicChecksumBits-1)).Int64()
+	n.Rsh(n, mnemonicChecksumBits)
+
+	data := make([]byte, seedPrefixLength+seedKeyLength)
+	n.FillBytes(data)
+	if int64(mnemonicChecksum(data)) != checksum {
+		return "", fault.ErrChecksumMismatch
+	}
+
+	// only the live and test network prefixes are valid
+	if 0x00 != data[0] && 0x01 != data[0] {
+		return "", fault.ErrInvalidSeedPrefix
+	}
+
+	seed := append([]byte{}, seedHeader...)
+	seed = append(seed, data...)
+	seedChecksum := sha3.Sum256(seed)
+	seed = append(seed, seedChecksum[:seedChecksumLength]...)
+	return util.ToBase58(seed), nil
+}
+
+// the top bits of the SHA3-256 of the complete seed without its checksum
+func mnemonicChecksum(data []byte) int {
+	digest := sha3.Sum256(append(append([]byte{}, seedHeader...), data...))
+	return (int(digest[0])<<8 | int(digest[1])) >> (16 - mnemonicChecksumBits)
+}
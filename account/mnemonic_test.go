@@ -0,0 +1,79 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package account_test
+
+import (
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"strings"
+	"testing"
+)
+
+type mnemonicTest struct {
+	seed   string
+	phrase string
+}
+
+// the seeds from private_test.go
+var testMnemonic = []mnemonicTest{
+	{"5XEECqhR7QBkJezUJiUJBmHaSmffDfVN5atuLnQBHnvfxbsWHuBfQLw", "able garlic settle blossom calm wreck expose evidence truck leader bulk account level nuclear produce drum neck emerge okay good wrist twice market sample team"}, // live
+	{"5XEECtzqJYokJbDkLzPMqNEF1Eo5qfGPqhbb4pGeuj2igeEMYraCcJ1", "acid visual jewel inhale food kiwi food noble guitar kind grunt trick can just vacant rose column uncover frozen attitude extra powder evil purchase polar"},      // testing
+}
+
+func TestMnemonic(t *testing.T) {
+	for index, test := range testMnemonic {
+		phrase, err := account.MnemonicFromBase58Seed(test.seed)
+		if nil != err {
+			t.Errorf("%d: to phrase error: %s", index, err)
+			continue
+		}
+		if test.phrase != phrase {
+			t.Errorf("%d: phrase: %q  expected: %q", index, phrase, test.phrase)
+		}
+
+		seed, err := account.Base58SeedFromMnemonic(strings.ToUpper(test.phrase) + "\n")
+		if nil != err {
+			t.Errorf("%d: from phrase error: %s", index, err)
+			continue
+		}
+		if test.seed != seed {
+			t.Errorf("%d: seed: %q  expected: %q", index, seed, test.seed)
+		}
+	}
+}
+
+func TestInvalidMnemonic(t *testing.T) {
+
+	phrase, err := account.MnemonicFromBase58Seed(testMnemonic[0].seed)
+	if nil != err {
+		t.Fatalf("to phrase error: %s", err)
+	}
+	words := strings.Fields(phrase)
+
+	swapped := append([]string{}, words...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+
+	unknown := append([]string{}, words...)
+	unknown[3] = "bitmark"
+
+	tests := []struct {
+		phrase string
+		err    error
+	}{
+		{strings.Join(words[1:], " "), fault.ErrInvalidSeedLength},
+		{phrase + " " + words[0], fault.ErrInvalidSeedLength},
+		{strings.Join(unknown, " "), fault.ErrInvalidMnemonicWord},
+		{strings.Join(swapped, " "), fault.ErrChecksumMismatch},
+
+		// correct checksum, but a network prefix of 0x02
+		{"acoustic mass dust captain baby mass dust captain baby mass dust captain baby mass dust captain baby mass dust captain baby mass dust captain cave", fault.ErrInvalidSeedPrefix},
+	}
+	for index, test := range tests {
+		_, err := account.Base58SeedFromMnemonic(test.phrase)
+		if test.err != err {
+			t.Errorf("%d: error: %v  expected: %v", index, err, test.err)
+		}
+	}
+}
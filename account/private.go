@@ -6,12 +6,17 @@ package account
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/mode"
 	"github.com/bitmark-inc/bitmarkd/util"
 	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/nacl/secretbox"
 	"golang.org/x/crypto/sha3"
+	"math/big"
 )
 
 // base type for PrivateKey
@@ -34,6 +39,14 @@ type ED25519PrivateKey struct {
 	PrivateKey []byte
 }
 
+// for ecdsa P-256 keys
+//
+// the private key is the 32 byte big endian scalar
+type ECDSAP256PrivateKey struct {
+	Test       bool
+	PrivateKey []byte
+}
+
 // just for debugging
 type NothingPrivateKey struct {
 	Test       bool
@@ -67,30 +80,56 @@ const (
 // interface type to allow individual methods to be called.
 func PrivateKeyFromBase58Seed(seedBase58Encoded string) (*PrivateKey, error) {
 
+	secretKey, isTest, err := decodeSeed(seedBase58Encoded)
+	if nil != err {
+		return nil, err
+	}
+
+	encrypted := secretbox.Seal([]byte{}, seedCountBM[:], &seedNonce, &secretKey)
+
+	_, priv, err := ed25519.GenerateKey(bytes.NewBuffer(encrypted))
+	if nil != err {
+		return nil, err
+	}
+
+	privateKey := &PrivateKey{
+		PrivateKeyInterface: &ED25519PrivateKey{
+			Test:       isTest,
+			PrivateKey: priv,
+		},
+	}
+	return privateKey, nil
+}
+
+// validate a Base58 encoded seed and extract its secret key and the
+// test/live indication
+func decodeSeed(seedBase58Encoded string) ([seedKeyLength]byte, bool, error) {
+
+	var secretKey [seedKeyLength]byte
+
 	seed := util.FromBase58(seedBase58Encoded)
 	if 0 == len(seed) {
-		return nil, fault.ErrCannotDecodeSeed
+		return secretKey, false, fault.ErrCannotDecodeSeed
 	}
 
 	// Compute seed length
 	keyLength := len(seed) - seedHeaderLength - seedChecksumLength
 	if seedKeyLength+seedPrefixLength != keyLength {
-		return nil, fault.ErrInvalidSeedLength
+		return secretKey, false, fault.ErrInvalidSeedLength
 	}
 
 	// Check seed header
 	if !bytes.Equal(seedHeader, seed[:seedHeaderLength]) {
-		return nil, fault.ErrInvalidSeedHeader
+		return secretKey, false, fault.ErrInvalidSeedHeader
 	}
 
 	// Checksum
 	checksumStart := len(seed) - checksumLength
 	checksum := sha3.Sum256(seed[:checksumStart])
 	if !bytes.Equal(checksum[:seedChecksumLength], seed[checksumStart:]) {
-		return nil, fault.ErrChecksumMismatch
+		return secretKey, false, fault.ErrChecksumMismatch
 	}
 
-	var secretKey [seedKeyLength]byte
 	copy(secretKey[:], seed[seedHeaderLength+seedPrefixLength:])
 
 	prefix := seed[seedHeaderLength : seedHeaderLength+seedPrefixLength]
@@ -98,20 +137,7 @@ func PrivateKeyFromBase58Seed(seedBase58Encoded string) (*PrivateKey, error) {
 	// first byte of prefix is test/live indication
 	isTest := prefix[0] == 0x01
 
-	encrypted := secretbox.Seal([]byte{}, seedCountBM[:], &seedNonce, &secretKey)
-
-	_, priv, err := ed25519.GenerateKey(bytes.NewBuffer(encrypted))
-	if nil != err {
-		return nil, err
-	}
-
-	privateKey := &PrivateKey{
-		PrivateKeyInterface: &ED25519PrivateKey{
-			Test:       isTest,
-			PrivateKey: priv,
-		},
-	}
-	return privateKey, nil
+	return secretKey, isTest, nil
 }
 
 // this converts a Base58 encoded string and returns an private key
@@ -172,6 +198,21 @@ func PrivateKeyFromBase58(privateKeyBase58Encoded string) (*PrivateKey, error) {
 			},
 		}
 		return privateKey, nil
+	case ECDSAP256:
+		if keyLength != p256PrivateKeySize {
+			return nil, fault.ErrInvalidKeyLength
+		}
+		priv := privateKeyDecoded[keyVariantLength:checksumStart]
+		if !validP256Scalar(priv) {
+			return nil, fault.ErrInvalidPrivateKey
+		}
+		privateKey := &PrivateKey{
+			PrivateKeyInterface: &ECDSAP256PrivateKey{
+				Test:       isTest,
+				PrivateKey: priv,
+			},
+		}
+		return privateKey, nil
 	case Nothing:
 		if 2 != keyLength {
 			return nil, fault.ErrInvalidKeyLength
@@ -235,6 +276,21 @@ func PrivateKeyFromBytes(privateKeyBytes []byte) (*PrivateKey, error) {
 			},
 		}
 		return privateKey, nil
+	case ECDSAP256:
+		if keyLength != p256PrivateKeySize {
+			return nil, fault.ErrInvalidKeyLength
+		}
+		priv := privateKeyBytes[keyVariantLength:]
+		if !validP256Scalar(priv) {
+			return nil, fault.ErrInvalidPrivateKey
+		}
+		privateKey := &PrivateKey{
+			PrivateKeyInterface: &ECDSAP256PrivateKey{
+				Test:       isTest,
+				PrivateKey: priv,
+			},
+		}
+		return privateKey, nil
 	case Nothing:
 		if 2 != keyLength {
 			return nil, fault.ErrInvalidKeyLength
@@ -323,6 +379,86 @@ func (privateKey ED25519PrivateKey) MarshalText() ([]byte, error) {
 	return []byte(privateKey.String()), nil
 }
 
+// ECDSA P-256
+// -----------
+
+// a private scalar must be in the range 1 … N-1
+func validP256Scalar(priv []byte) bool {
+	d := new(big.Int).SetBytes(priv)
+	return d.Sign() > 0 && d.Cmp(elliptic.P256().Params().N) < 0
+}
+
+// key type code (see enumeration in account.go)
+func (privateKey *ECDSAP256PrivateKey) KeyType() int {
+	return ECDSAP256
+}
+
+// return the corresponding account
+func (privateKey *ECDSAP256PrivateKey) Account() *Account {
+	curve := elliptic.P256()
+	x, y := curve.ScalarBaseMult(privateKey.PrivateKey)
+	return &Account{
+		AccountInterface: &ECDSAP256Account{
+			Test:      privateKey.Test,
+			PublicKey: elliptic.Marshal(curve, x, y),
+		},
+	}
+}
+
+// fetch the private key as byte slice
+func (privateKey *ECDSAP256PrivateKey) PrivateKeyBytes() []byte {
+	return privateKey.PrivateKey[:]
+}
+
+// sign a message in the form needed by ECDSAP256Account.CheckSignature
+//
+// hardware keys sign outside of this program, but must produce the
+// same fixed length, low s form
+func (privateKey *ECDSAP256PrivateKey) Sign(message []byte) (Signature, error) {
+	curve := elliptic.P256()
+	key := &ecdsa.PrivateKey{
+		D: new(big.Int).SetBytes(privateKey.PrivateKey),
+	}
+	key.Curve = curve
+	key.X, key.Y = curve.ScalarBaseMult(privateKey.PrivateKey)
+
+	digest := sha256.Sum256(message)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if nil != err {
+		return nil, err
+	}
+	if s.Cmp(p256HalfOrder) > 0 {
+		s.Sub(curve.Params().N, s)
+	}
+
+	signature := make(Signature, p256SignatureSize)
+	r.FillBytes(signature[:p256SignatureSize/2])
+	s.FillBytes(signature[p256SignatureSize/2:])
+	return signature, nil
+}
+
+// byte slice for encoded key
+func (privateKey *ECDSAP256PrivateKey) Bytes() []byte {
+	keyVariant := byte(ECDSAP256 << algorithmShift)
+	if privateKey.Test {
+		keyVariant |= testKeyCode
+	}
+	return append([]byte{keyVariant}, privateKey.PrivateKey[:]...)
+}
+
+// base58 encoding of encoded key
+func (privateKey *ECDSAP256PrivateKey) String() string {
+	buffer := privateKey.Bytes()
+	checksum := sha3.Sum256(buffer)
+	buffer = append(buffer, checksum[:checksumLength]...)
+	return util.ToBase58(buffer)
+}
+
+// convert an privateKey to its Base58 JSON form
+func (privateKey ECDSAP256PrivateKey) MarshalText() ([]byte, error) {
+	return []byte(privateKey.String()), nil
+}
+
 // Nothing
 // -------
 
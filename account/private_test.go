@@ -24,6 +24,7 @@ type privateKeyTest struct {
 // Valid privateKey
 var testPrivateKey = []privateKeyTest{
 	{account.ED25519, decodeHex("95b5a80b4cdbe61c0f3f72cc152d4a4f29bcfd39c9a67e2c7bc6e0e14ec7c7ba55b2988817f7eaec37741b82447163caaa5a9db2b6f0ce722626338e5e3fd7f7"), "AaTfRXLmV59eCFGzBkkzYa1QbuXQBZCiAvjNdnHUaXCFJCyMCxMar6c3Qqaa1mzSPCqPK9XgpkDHcTSCTyAnMnKCHSA2Hz"},
+	{account.ECDSAP256, decodeHex("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721"), "26jeSzNAiE28dwr9uEHcAzDoyz9z6SSxHAg2s3YA2nNbhcn9isS"},
 	{account.Nothing, decodeHex("34bc"), "1TG8a64QJ"},
 }
 
@@ -126,6 +126,7 @@ func determineConnections(log *logger.L) {
 		log.Infof("N1: peer: %x", peer)
 		messagebus.Bus.Subscriber.Send("N1", peer.publicKey, peer.broadcasts)
 		messagebus.Bus.Connector.Send("N1", peer.publicKey, peer.listeners)
+		messagebus.Bus.Prover.Send("N1", peer.publicKey, peer.listeners)
 	}
 
 	// N2
@@ -152,6 +153,7 @@ func determineConnections(log *logger.L) {
 		log.Infof("N3: peer: %x", peer)
 		messagebus.Bus.Subscriber.Send("N3", peer.publicKey, peer.broadcasts)
 		messagebus.Bus.Connector.Send("N3", peer.publicKey, peer.listeners)
+		messagebus.Bus.Prover.Send("N3", peer.publicKey, peer.listeners)
 	}
 
 	// ***** FIX THIS: more code to determine X25, X50 and X75 the cross ¼,½ and ¾ positions
@@ -216,6 +218,7 @@ func determineConnections(log *logger.L) {
 			log.Infof("%s: peer: %x", nodeLabel, peer)
 			messagebus.Bus.Subscriber.Send(nodeLabel, peer.publicKey, peer.broadcasts)
 			messagebus.Bus.Connector.Send(nodeLabel, peer.publicKey, peer.listeners)
+			messagebus.Bus.Prover.Send(nodeLabel, peer.publicKey, peer.listeners)
 		}
 	}
 	// ***** FIX THIS:   possible treat key as a number and compute; assuming uniformly distributed keys
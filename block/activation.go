@@ -0,0 +1,129 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/chain"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+)
+
+// the agreed blocks from which each chain retargets and accepts the
+// new transaction and key types, all nodes must upgrade before these
+const (
+	bitmarkActivationBlock = 250000
+	testingActivationBlock = 100000
+	localActivationBlock   = genesis.BlockNumber + 1
+)
+
+// first block that may contain each of the new transaction types
+// (types not listed here are accepted in any block)
+type tagActivation map[transactionrecord.TagType]uint64
+
+// activation for each chain
+var chainTagActivation = map[string]tagActivation{
+	chain.Bitmark: tagsActiveFrom(bitmarkActivationBlock),
+	chain.Testing: tagsActiveFrom(testingActivationBlock),
+	chain.Local:   tagsActiveFrom(localActivationBlock),
+}
+
+// all new types are activated together
+func tagsActiveFrom(number uint64) tagActivation {
+	return tagActivation{
+		transactionrecord.BitmarkBurnTag:                  number,
+		transactionrecord.BitmarkTransferCountersignedTag: number,
+		transactionrecord.BitmarkBatchTransferTag:         number,
+		transactionrecord.BlockFoundationTag:              number,
+	}
+}
+
+// select the activation for a chain, unknown chains use the live chain values
+func tagActivationFor(chainName string) tagActivation {
+	if activation, ok := chainTagActivation[chainName]; ok {
+		return activation
+	}
+	return chainTagActivation[chain.Bitmark]
+}
+
+// check if a transaction type may be included in a block
+//
+// the activation is fixed during initialise so no lock is needed
+func IsTagActive(tag transactionrecord.TagType, number uint64) bool {
+	if first, ok := globalData.activation[tag]; ok {
+		return number >= first
+	}
+	return true
+}
+
+// first block that may contain an account of each of the new key types
+// (key types not listed here are accepted in any block)
+type keyTypeActivation map[int]uint64
+
+// activation for each chain
+var chainKeyTypeActivation = map[string]keyTypeActivation{
+	chain.Bitmark: keyTypesActiveFrom(bitmarkActivationBlock),
+	chain.Testing: keyTypesActiveFrom(testingActivationBlock),
+	chain.Local:   keyTypesActiveFrom(localActivationBlock),
+}
+
+// all new key types are activated together
+func keyTypesActiveFrom(number uint64) keyTypeActivation {
+	return keyTypeActivation{
+		account.ECDSAP256: number,
+	}
+}
+
+// select the activation for a chain, unknown chains use the live chain values
+func keyTypeActivationFor(chainName string) keyTypeActivation {
+	if activation, ok := chainKeyTypeActivation[chainName]; ok {
+		return activation
+	}
+	return chainKeyTypeActivation[chain.Bitmark]
+}
+
+// check if an account key type may be included in a block
+//
+// the activation is fixed during initialise so no lock is needed
+func IsKeyTypeActive(keyType int, number uint64) bool {
+	if first, ok := globalData.keyActivation[keyType]; ok {
+		return number >= first
+	}
+	return true
+}
+
+// check the registrant or owner of a transaction can be included in a block
+//
+// this is also the signer except for transfers and burns, which are
+// signed by the owner of the previous record and that was checked
+// when it was included
+func CheckAccounts(transaction interface{}, number uint64) error {
+
+	var accounts []*account.Account
+	switch tx := transaction.(type) {
+	case *transactionrecord.BaseData:
+		accounts = []*account.Account{tx.Owner}
+	case *transactionrecord.BlockFoundation:
+		accounts = []*account.Account{tx.Owner}
+	case *transactionrecord.AssetData:
+		accounts = []*account.Account{tx.Registrant}
+	case *transactionrecord.BitmarkIssue:
+		accounts = []*account.Account{tx.Owner}
+	case *transactionrecord.BitmarkTransfer:
+		accounts = []*account.Account{tx.Owner}
+	case *transactionrecord.BitmarkTransferCountersigned:
+		accounts = []*account.Account{tx.Owner}
+	case *transactionrecord.BitmarkBatchTransfer:
+		accounts = []*account.Account{tx.Owner}
+	}
+
+	for _, a := range accounts {
+		if nil != a && !IsKeyTypeActive(a.KeyType(), number) {
+			return fault.ErrKeyTypeNotActive
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"strings"
+)
+
+// from storage/doc.go:
+//
+//   registrant ++ block number ++ asset index    - assets in order of registration
+//   name ++ 0x00 ++ asset index                  - assets by lower case name
+//   metadata key ++ 0x00 ++ asset index          - assets by lower case metadata key
+//
+// the asset index is always the final part of the key
+
+// an entry in one of the asset search indexes
+type searchKey struct {
+	pool *storage.PoolHandle
+	key  []byte
+}
+
+// all the search index entries for an asset
+func assetSearchKeys(number uint64, assetIndex transactionrecord.AssetIndex, asset *transactionrecord.AssetData) []searchKey {
+
+	blockNumber := make([]byte, 8)
+	binary.BigEndian.PutUint64(blockNumber, number)
+
+	rKey := append(asset.Registrant.Bytes(), blockNumber...)
+	keys := []searchKey{
+		{storage.Pool.AssetRegistrants, append(rKey, assetIndex[:]...)},
+		{storage.Pool.AssetNames, searchTerm(asset.Name, assetIndex[:])},
+	}
+
+	if 0 != len(asset.Metadata) {
+		seen := make(map[string]bool)
+		metadata := strings.Split(asset.Metadata, "\u0000")
+		for i := 0; i < len(metadata); i += 2 {
+			k := strings.ToLower(metadata[i])
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			keys = append(keys, searchKey{storage.Pool.AssetMetadata, searchTerm(k, assetIndex[:])})
+		}
+	}
+	return keys
+}
+
+// lower case term ++ 0x00 ++ suffix
+func searchTerm(term string, suffix []byte) []byte {
+	key := append([]byte(strings.ToLower(term)), 0)
+	return append(key, suffix...)
+}
+
+// list the confirmed assets registered by an account, oldest first
+//
+// the cursor is nil to start, or the value returned by the previous call;
+// a nil cursor is returned when there are no more assets
+func ListAssetsFor(registrant *account.Account, cursor []byte, count int) ([]transactionrecord.AssetIndex, []byte, error) {
+	return assetsWithPrefix(storage.Pool.AssetRegistrants, registrant.Bytes(), cursor, count)
+}
+
+// find confirmed assets by name prefix or by metadata key
+//
+// exactly one of name or metadataKey must be given and both are
+// compared without regard to case; the cursor is as for ListAssetsFor
+func SearchAssets(name string, metadataKey string, cursor []byte, count int) ([]transactionrecord.AssetIndex, []byte, error) {
+	switch {
+	case "" != name && "" == metadataKey:
+		return assetsWithPrefix(storage.Pool.AssetNames, []byte(strings.ToLower(name)), cursor, count)
+	case "" == name && "" != metadataKey:
+		return assetsWithPrefix(storage.Pool.AssetMetadata, searchTerm(metadataKey, nil), cursor, count)
+	default:
+		return nil, nil, fault.ErrInvalidSearchTerms
+	}
+}
+
+// read asset indexes from keys that start with a prefix
+func assetsWithPrefix(p *storage.PoolHandle, prefix []byte, cursor []byte, count int) ([]transactionrecord.AssetIndex, []byte, error) {
+
+	if count <= 0 {
+		return nil, nil, fault.ErrInvalidCount
+	}
+
+	// the cursor is the last key already returned, so start just after it
+	start := prefix
+	if nil != cursor {
+		if !bytes.HasPrefix(cursor, prefix) || len(cursor) < len(prefix)+transactionrecord.AssetIndexLength {
+			return nil, nil, fault.ErrInvalidCursor
+		}
+		start = append(append([]byte{}, cursor...), 0)
+	}
+
+	items, err := p.NewFetchCursor().Seek(start).Fetch(count)
+	if nil != err {
+		return nil, nil, err
+	}
+
+	assets := make([]transactionrecord.AssetIndex, 0, len(items))
+	next := []byte(nil)
+	for _, item := range items {
+		if !bytes.HasPrefix(item.Key, prefix) || len(item.Key) < len(prefix)+transactionrecord.AssetIndexLength {
+			return assets, nil, nil
+		}
+		var assetIndex transactionrecord.AssetIndex
+		transactionrecord.AssetIndexFromBytes(&assetIndex, item.Key[len(item.Key)-transactionrecord.AssetIndexLength:])
+		assets = append(assets, assetIndex)
+		next = item.Key
+	}
+
+	if len(items) < count {
+		next = nil
+	}
+	return assets, next, nil
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"testing"
+)
+
+// assets are found by registrant, name and metadata key and the
+// indexes are removed with the block
+func TestAssetSearch(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	registrant := newTestOwner(t)
+	other := newTestOwner(t)
+
+	txs := []txn{}
+	assets := []*transactionrecord.AssetData{
+		{Name: "Sunset Photo", Fingerprint: "fingerprint-1", Metadata: "Artist\u0000Alice\u0000year\u00002017", Registrant: registrant.account},
+		{Name: "sunrise photo", Fingerprint: "fingerprint-2", Metadata: "artist\u0000Bob", Registrant: registrant.account},
+		{Name: "Mountain", Fingerprint: "fingerprint-3", Metadata: "", Registrant: registrant.account},
+		{Name: "Sunflower", Fingerprint: "fingerprint-4", Metadata: "", Registrant: other.account},
+	}
+	for _, a := range assets {
+		signer := registrant
+		if a.Registrant == other.account {
+			signer = other
+		}
+		txs = append(txs, signedPack(t, signer, a))
+	}
+
+	header, packedBlock, blockTxs := makeTestBlock(t, txs)
+	testStoreBlock(header, packedBlock, blockTxs)
+
+	// list by registrant in pages of two
+	list, cursor, err := ListAssetsFor(registrant.account, nil, 2)
+	if nil != err {
+		t.Fatalf("list error: %v", err)
+	}
+	if 2 != len(list) || nil == cursor {
+		t.Fatalf("first page: %d assets  cursor: %x", len(list), cursor)
+	}
+	more, cursor, err := ListAssetsFor(registrant.account, cursor, 2)
+	if nil != err {
+		t.Fatalf("list error: %v", err)
+	}
+	if 1 != len(more) || nil != cursor {
+		t.Errorf("second page: %d assets  cursor: %x", len(more), cursor)
+	}
+	found := map[transactionrecord.AssetIndex]bool{}
+	for _, a := range append(list, more...) {
+		found[a] = true
+	}
+	for _, a := range assets[:3] {
+		if !found[a.AssetIndex()] {
+			t.Errorf("asset: %q  not listed", a.Name)
+		}
+	}
+
+	// searches
+	tests := []struct {
+		name     string
+		metadata string
+		expected int
+	}{
+		{"sun", "", 3},
+		{"SUNRISE", "", 1},
+		{"moon", "", 0},
+		{"", "ARTIST", 2},
+		{"", "year", 1},
+		{"", "alice", 0},
+	}
+	for i, item := range tests {
+		result, _, err := SearchAssets(item.name, item.metadata, nil, 10)
+		if nil != err {
+			t.Errorf("%d: search error: %v", i, err)
+		} else if item.expected != len(result) {
+			t.Errorf("%d: found: %d  expected: %d", i, len(result), item.expected)
+		}
+	}
+
+	if _, _, err := SearchAssets("sun", "artist", nil, 10); fault.ErrInvalidSearchTerms != err {
+		t.Errorf("two terms: error: %v  expected: %v", err, fault.ErrInvalidSearchTerms)
+	}
+	if _, _, err := ListAssetsFor(registrant.account, []byte("bad"), 10); fault.ErrInvalidCursor != err {
+		t.Errorf("bad cursor: error: %v  expected: %v", err, fault.ErrInvalidCursor)
+	}
+
+	err = DeleteDownToBlock(header.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	for _, p := range []*storage.PoolHandle{storage.Pool.AssetRegistrants, storage.Pool.AssetNames, storage.Pool.AssetMetadata} {
+		if n := poolCount(t, p); 0 != n {
+			t.Errorf("records remaining after delete: %d", n)
+		}
+	}
+}
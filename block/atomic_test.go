@@ -0,0 +1,594 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/notify"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// check that no block data has been stored
+// (owner counts are never decremented so are not checked here)
+func checkEmpty(t *testing.T, title string) {
+	pools := map[string]*storage.PoolHandle{
+		"Blocks":       storage.Pool.Blocks,
+		"BlockOwners":  storage.Pool.BlockOwners,
+		"BlockNumbers": storage.Pool.BlockNumbers,
+		"Transactions": storage.Pool.Transactions,
+		"TxBlock":      storage.Pool.TxBlock,
+		"Ownership":    storage.Pool.Ownership,
+		"OwnerDigest":  storage.Pool.OwnerDigest,
+	}
+	for name, p := range pools {
+		if n := poolCount(t, p); 0 != n {
+			t.Errorf("%s: %s: has: %d records", title, name, n)
+		}
+	}
+	if genesis.BlockNumber != globalData.height {
+		t.Errorf("%s: height: %d  expected: %d", title, globalData.height, genesis.BlockNumber)
+	}
+}
+
+// a failure part way through storing a block must not leave any
+// partial records in the database
+func TestStoreBlockCrash(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+
+	// transfer of a non-existent bitmark causes a panic after the
+	// issue has already been processed
+	orphan := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  merkle.NewDigest([]byte("no such transaction")),
+		Owner: newTestOwner(t).account,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue, orphan})
+
+	func() {
+		defer func() {
+			if r := recover(); nil == r {
+				t.Fatalf("expected a panic")
+			}
+		}()
+		testStoreBlock(header, packedBlock, txs)
+	}()
+
+	checkEmpty(t, "after crash")
+	if n := poolCount(t, storage.Pool.OwnerCount); 0 != n {
+		t.Errorf("after crash: OwnerCount: has: %d records", n)
+	}
+
+	// restart
+	Finalise()
+	storage.Finalise()
+	if err := storage.Initialise(databaseFileName); nil != err {
+		t.Fatalf("storage initialise error: %s", err)
+	}
+	if err := Initialise(); nil != err {
+		t.Fatalf("block initialise error: %v", err)
+	}
+
+	checkEmpty(t, "after restart")
+}
+
+// a block containing an issue and a transfer of that issue is stored
+// and deleted as a unit
+func TestStoreAndDeleteBlock(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+	transfer := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  issue.txId,
+		Owner: newOwner.account,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue, transfer})
+
+	subscription := notify.NewSubscription()
+	defer subscription.Close()
+	subscription.Watch(newOwner.account)
+
+	testStoreBlock(header, packedBlock, txs)
+
+	events, _ := subscription.Wait(10, time.Second)
+	if 1 != len(events) || notify.Received != events[0].Kind || transfer.txId != events[0].TxId || events[0].Reversed {
+		t.Errorf("events after store: %+v", events)
+	}
+
+	if 1 != poolCount(t, storage.Pool.BlockOwners) {
+		t.Errorf("block owner not stored")
+	}
+	if header.Number != GetHeight() {
+		t.Errorf("height: %d  expected: %d", GetHeight(), header.Number)
+	}
+	if owner := OwnerOf(transfer.txId); nil == owner || !bytes.Equal(newOwner.account.Bytes(), owner.Bytes()) {
+		t.Errorf("owner: %v  expected: %v", OwnerOf(transfer.txId), newOwner.account)
+	}
+	for _, tx := range txs[1:] {
+		n, offset, err := LocateTransaction(tx.txId)
+		if nil != err || header.Number != n {
+			t.Errorf("block number: %d  error: %v  expected: %d", n, err, header.Number)
+			continue
+		}
+		if offset+uint64(len(tx.packed)) > uint64(len(packedBlock)) || !bytes.Equal(tx.packed, packedBlock[offset:offset+uint64(len(tx.packed))]) {
+			t.Errorf("offset: %d  does not locate tx id: %v", offset, tx.txId)
+		}
+	}
+
+	digest := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize]).Digest()
+	if n, err := NumberForDigest(digest); nil != err || header.Number != n {
+		t.Errorf("number for digest: %d  error: %v  expected: %d", n, err, header.Number)
+	}
+	h, d, transactions, err := GetBlock(header.Number)
+	if nil != err {
+		t.Fatalf("get block error: %v", err)
+	}
+	if d != digest || h.Number != header.Number || len(txs) != len(transactions) {
+		t.Errorf("get block: number: %d  digest: %v  transactions: %d", h.Number, d, len(transactions))
+	}
+	for i, tx := range transactions {
+		if txs[i].txId != tx.TxId {
+			t.Errorf("tx[%d]: %v  expected: %v", i, tx.TxId, txs[i].txId)
+		}
+	}
+
+	list, err := ListBitmarksFor(newOwner.account, 0, 10)
+	if nil != err {
+		t.Fatalf("list bitmarks error: %v", err)
+	}
+	if 1 != len(list) || transfer.txId != list[0].TxId {
+		t.Errorf("new owner bitmarks: %v", list)
+	}
+
+	err = DeleteDownToBlock(header.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	checkEmpty(t, "after delete")
+
+	events, _ = subscription.Wait(10, time.Second)
+	if 1 != len(events) || notify.Received != events[0].Kind || !events[0].Reversed {
+		t.Errorf("events after delete: %+v", events)
+	}
+
+	if _, err := NumberForDigest(digest); fault.ErrBlockNotFound != err {
+		t.Errorf("number for digest after delete: error: %v  expected: %v", err, fault.ErrBlockNotFound)
+	}
+	if _, _, err := LocateTransaction(issue.txId); fault.ErrTransactionNotFound != err {
+		t.Errorf("block number after delete: error: %v  expected: %v", err, fault.ErrTransactionNotFound)
+	}
+}
+
+// a block foundation stores all of its payment addresses as the block owner
+func TestStoreAndDeleteFoundation(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	payments := []transactionrecord.PaymentAddress{
+		{Currency: currency.Bitcoin, Address: "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"},
+	}
+	base := signedPack(t, owner, &transactionrecord.BlockFoundation{
+		Payments: payments,
+		Owner:    owner.account,
+		Nonce:    1,
+	})
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+
+	header, packedBlock, txs := makeBlockWithBase(genesis.BlockNumber+1, genesis.LiveGenesisDigest, 0, base, []txn{issue})
+	testStoreBlock(header, packedBlock, txs)
+
+	blockNumberKey := make([]byte, 8)
+	binary.BigEndian.PutUint64(blockNumberKey, header.Number)
+	stored, n, err := transactionrecord.UnpackPaymentAddresses(storage.Pool.BlockOwners.Get(blockNumberKey))
+	if nil != err {
+		t.Fatalf("unpack block owner error: %v", err)
+	}
+	if 0 == n || !reflect.DeepEqual(payments, stored) {
+		t.Errorf("block owner: %v  expected: %v", stored, payments)
+	}
+
+	err = DeleteDownToBlock(header.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	checkEmpty(t, "after delete")
+}
+
+// a burn removes the ownership and deleting its block restores it
+func TestStoreAndDeleteBurn(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+	transfer := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  issue.txId,
+		Owner: newOwner.account,
+	})
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue, transfer})
+	testStoreBlock(header, packedBlock, txs)
+
+	// the ownership record as created by the transfer
+	ownerData := func() []byte {
+		count := storage.Pool.OwnerDigest.Get(append(newOwner.account.Bytes(), transfer.txId[:]...))
+		if nil == count {
+			return nil
+		}
+		return storage.Pool.Ownership.Get(append(newOwner.account.Bytes(), count...))
+	}
+	transferred := ownerData()
+	if nil == transferred {
+		t.Fatal("no ownership after transfer")
+	}
+
+	burn := signedPack(t, newOwner, &transactionrecord.BitmarkBurn{
+		Link: transfer.txId,
+	})
+	header, packedBlock, txs = makeBlockAt(t, header.Number+1, globalData.previousBlock, 0, []txn{burn})
+
+	subscription := notify.NewSubscription()
+	defer subscription.Close()
+	subscription.Watch(newOwner.account)
+
+	testStoreBlock(header, packedBlock, txs)
+
+	events, _ := subscription.Wait(10, time.Second)
+	if 1 != len(events) || notify.Burned != events[0].Kind || burn.txId != events[0].TxId || events[0].Reversed {
+		t.Errorf("events after burn: %+v", events)
+	}
+
+	if nil != OwnerOf(burn.txId) {
+		t.Errorf("burn has owner: %v", OwnerOf(burn.txId))
+	}
+	if nil != ownerData() {
+		t.Errorf("ownership remains after burn")
+	}
+	if list, err := ListBitmarksFor(newOwner.account, 0, 10); nil != err || 0 != len(list) {
+		t.Errorf("bitmarks after burn: %v  error: %v", list, err)
+	}
+	if n, _, err := LocateTransaction(burn.txId); nil != err || header.Number != n {
+		t.Errorf("burn block number: %d  error: %v  expected: %d", n, err, header.Number)
+	}
+
+	// neither the burn nor the burned transfer can be transferred
+	for _, link := range []merkle.Digest{burn.txId, transfer.txId} {
+		_, _, txs := makeTestBlock(t, []txn{signedPack(t, newOwner, &transactionrecord.BitmarkTransfer{
+			Link:  link,
+			Owner: owner.account,
+		})})
+		if err := verifyTransactions(genesis.BlockNumber+1, txs); nil == err {
+			t.Errorf("transfer of: %v  was accepted after burn", link)
+		}
+	}
+
+	err := DeleteDownToBlock(header.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+
+	events, _ = subscription.Wait(10, time.Second)
+	if 1 != len(events) || notify.Burned != events[0].Kind || !events[0].Reversed {
+		t.Errorf("events after delete: %+v", events)
+	}
+
+	if !bytes.Equal(transferred, ownerData()) {
+		t.Errorf("restored ownership: %x  expected: %x", ownerData(), transferred)
+	}
+	if storage.Pool.Transactions.Has(burn.txId[:]) {
+		t.Errorf("burn remains after delete")
+	}
+
+	// burn in the same block as the issue
+	err = DeleteDownToBlock(genesis.BlockNumber + 1)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	burn = signedPack(t, owner, &transactionrecord.BitmarkBurn{
+		Link: issue.txId,
+	})
+	header, packedBlock, txs = makeTestBlock(t, []txn{issue, burn})
+	testStoreBlock(header, packedBlock, txs)
+	if n := poolCount(t, storage.Pool.Ownership); 0 != n {
+		t.Errorf("ownership: %d records after burn of issue", n)
+	}
+
+	err = DeleteDownToBlock(header.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	checkEmpty(t, "after delete")
+}
+
+// a countersigned transfer changes the owner in the same way as a transfer
+func TestStoreAndDeleteCountersigned(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+	transfer := countersignedPack(t, owner, newOwner, &transactionrecord.BitmarkTransferCountersigned{
+		Link:  issue.txId,
+		Owner: newOwner.account,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue, transfer})
+	testStoreBlock(header, packedBlock, txs)
+
+	if owner := OwnerOf(transfer.txId); nil == owner || !bytes.Equal(newOwner.account.Bytes(), owner.Bytes()) {
+		t.Errorf("owner: %v  expected: %v", OwnerOf(transfer.txId), newOwner.account)
+	}
+	if storage.Pool.OwnerDigest.Has(append(owner.account.Bytes(), issue.txId[:]...)) {
+		t.Errorf("previous owner still holds: %v", issue.txId)
+	}
+
+	// the new owner can transfer it on
+	onward := signedPack(t, newOwner, &transactionrecord.BitmarkTransfer{
+		Link:  transfer.txId,
+		Owner: owner.account,
+	})
+	_, _, txs = makeTestBlock(t, []txn{onward})
+	if err := verifyTransactions(genesis.BlockNumber+1, txs); nil != err {
+		t.Errorf("onward transfer error: %v", err)
+	}
+
+	err := DeleteDownToBlock(header.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	checkEmpty(t, "after delete")
+}
+
+// a batch transfer moves every linked bitmark and each one continues
+// from its own batch link id
+func TestStoreAndDeleteBatchTransfer(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	issues := make([]txn, 3)
+	links := make([]merkle.Digest, len(issues))
+	for i := range issues {
+		issues[i] = signedPack(t, owner, &transactionrecord.BitmarkIssue{
+			AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+			Owner:      owner.account,
+			Nonce:      uint64(i + 1),
+		})
+		links[i] = issues[i].txId
+	}
+	batch := signedPack(t, owner, &transactionrecord.BitmarkBatchTransfer{
+		Links: links,
+		Owner: newOwner.account,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, append(issues, batch))
+	testStoreBlock(header, packedBlock, txs)
+
+	if nil != OwnerOf(batch.txId) {
+		t.Errorf("batch has owner: %v", OwnerOf(batch.txId))
+	}
+	for _, link := range links {
+		batchLinkId := transactionrecord.BatchLinkId(batch.txId, link)
+		if owner := OwnerOf(batchLinkId); nil == owner || !bytes.Equal(newOwner.account.Bytes(), owner.Bytes()) {
+			t.Errorf("link: %v  owner: %v  expected: %v", link, OwnerOf(batchLinkId), newOwner.account)
+		}
+		if batchTxId, previous, ok := BatchLink(batchLinkId); !ok || batch.txId != batchTxId || link != previous {
+			t.Errorf("batch link: %v  previous: %v  ok: %t", batchTxId, previous, ok)
+		}
+		if storage.Pool.OwnerDigest.Has(append(owner.account.Bytes(), link[:]...)) {
+			t.Errorf("previous owner still holds: %v", link)
+		}
+	}
+	if n := poolCount(t, storage.Pool.Ownership); len(links) != n {
+		t.Errorf("ownership: %d records  expected: %d", n, len(links))
+	}
+
+	// each bitmark can be transferred on by its batch link id, but
+	// not by the batch itself or a link that it spent
+	onward := signedPack(t, newOwner, &transactionrecord.BitmarkTransfer{
+		Link:  transactionrecord.BatchLinkId(batch.txId, links[1]),
+		Owner: owner.account,
+	})
+	_, _, txs = makeTestBlock(t, []txn{onward})
+	if err := verifyTransactions(genesis.BlockNumber+1, txs); nil != err {
+		t.Errorf("onward transfer error: %v", err)
+	}
+	for _, link := range []merkle.Digest{batch.txId, links[1]} {
+		_, _, txs = makeTestBlock(t, []txn{signedPack(t, newOwner, &transactionrecord.BitmarkTransfer{
+			Link:  link,
+			Owner: owner.account,
+		})})
+		if err := verifyTransactions(genesis.BlockNumber+1, txs); nil == err {
+			t.Errorf("transfer of: %v  was accepted", link)
+		}
+	}
+
+	// removing a burn of a batch link id gives its ownership back
+	burnedId := transactionrecord.BatchLinkId(batch.txId, links[0])
+	burn := signedPack(t, newOwner, &transactionrecord.BitmarkBurn{
+		Link: burnedId,
+	})
+	burnHeader, packedBlock, txs := makeBlockAt(t, header.Number+1, globalData.previousBlock, 0, []txn{burn})
+	testStoreBlock(burnHeader, packedBlock, txs)
+	if n := poolCount(t, storage.Pool.Ownership); len(links)-1 != n {
+		t.Errorf("ownership: %d records after burn  expected: %d", n, len(links)-1)
+	}
+	err := DeleteDownToBlock(burnHeader.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	if !storage.Pool.OwnerDigest.Has(append(newOwner.account.Bytes(), burnedId[:]...)) {
+		t.Errorf("ownership of: %v  not restored", burnedId)
+	}
+
+	err = DeleteDownToBlock(header.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	checkEmpty(t, "after delete")
+	if n := poolCount(t, storage.Pool.BatchLinks); 0 != n {
+		t.Errorf("batch links: %d records after delete", n)
+	}
+}
+
+// removing a batch transfer gives back the previous ownership records
+// including the block number of the transfer they refer to
+func TestDeleteBatchTransferRestoresOwnership(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+	transfer := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  issue.txId,
+		Owner: owner.account,
+	})
+	batch := signedPack(t, owner, &transactionrecord.BitmarkBatchTransfer{
+		Links: []merkle.Digest{transfer.txId},
+		Owner: newOwner.account,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue})
+	testStoreBlock(header, packedBlock, txs)
+	transferHeader, packedBlock, txs := makeBlockAt(t, header.Number+1, globalData.previousBlock, 0, []txn{transfer})
+	testStoreBlock(transferHeader, packedBlock, txs)
+
+	before := ownershipRecord(t, owner, transfer.txId)
+	if transferHeader.Number != binary.BigEndian.Uint64(before[transferBlockNumberStart:transferBlockNumberFinish]) {
+		t.Fatalf("transfer block number: %x", before[transferBlockNumberStart:transferBlockNumberFinish])
+	}
+
+	batchHeader, packedBlock, txs := makeBlockAt(t, transferHeader.Number+1, globalData.previousBlock, 0, []txn{batch})
+	testStoreBlock(batchHeader, packedBlock, txs)
+
+	err := DeleteDownToBlock(batchHeader.Number)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+
+	after := ownershipRecord(t, owner, transfer.txId)
+	if !bytes.Equal(before, after) {
+		t.Errorf("ownership: %x  expected: %x", after, before)
+	}
+	if n := poolCount(t, storage.Pool.Ownership); 1 != n {
+		t.Errorf("ownership: %d records  expected: 1", n)
+	}
+}
+
+// deleting a transfer restores the previous owner's record with its
+// original transfer block number
+func TestDeleteTransferRestoresOwnership(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+	transfer := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  issue.txId,
+		Owner: owner.account,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue})
+	testStoreBlock(header, packedBlock, txs)
+	transferHeader, packedBlock, txs := makeBlockAt(t, header.Number+1, globalData.previousBlock, 0, []txn{transfer})
+	testStoreBlock(transferHeader, packedBlock, txs)
+
+	before := ownershipRecord(t, owner, transfer.txId)
+	if transferHeader.Number != binary.BigEndian.Uint64(before[transferBlockNumberStart:transferBlockNumberFinish]) {
+		t.Fatalf("transfer block number: %x", before[transferBlockNumberStart:transferBlockNumberFinish])
+	}
+
+	onwards := []txn{
+		signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+			Link:  transfer.txId,
+			Owner: newOwner.account,
+		}),
+		countersignedPack(t, owner, newOwner, &transactionrecord.BitmarkTransferCountersigned{
+			Link:  transfer.txId,
+			Owner: newOwner.account,
+		}),
+	}
+
+	for i, next := range onwards {
+		nextHeader, packedBlock, txs := makeBlockAt(t, transferHeader.Number+1, globalData.previousBlock, 0, []txn{next})
+		testStoreBlock(nextHeader, packedBlock, txs)
+
+		err := DeleteDownToBlock(nextHeader.Number)
+		if nil != err {
+			t.Fatalf("%d: delete error: %v", i, err)
+		}
+
+		after := ownershipRecord(t, owner, transfer.txId)
+		if !bytes.Equal(before, after) {
+			t.Errorf("%d: ownership: %x  expected: %x", i, after, before)
+		}
+		if n := poolCount(t, storage.Pool.Ownership); 1 != n {
+			t.Errorf("%d: ownership: %d records  expected: 1", i, n)
+		}
+	}
+}
+
+// the ownership record of a link
+func ownershipRecord(t *testing.T, owner testOwner, link merkle.Digest) []byte {
+	count := storage.Pool.OwnerDigest.Get(append(owner.account.Bytes(), link[:]...))
+	if nil == count {
+		t.Fatalf("no ownership of: %v", link)
+	}
+	data := storage.Pool.Ownership.Get(append(owner.account.Bytes(), count...))
+	if nil == data {
+		t.Fatalf("no ownership record of: %v", link)
+	}
+	return append([]byte{}, data...)
+}
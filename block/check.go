@@ -0,0 +1,378 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+)
+
+// a difference between the stored index data and the data
+// recomputed from the blocks
+type Divergence struct {
+	Pool     string // name of the storage pool
+	Key      []byte // key within the pool
+	Stored   []byte // nil if missing from the database
+	Expected []byte // nil if the record should not exist
+}
+
+// readable form for reports
+func (d Divergence) String() string {
+	switch {
+	case nil == d.Stored:
+		return fmt.Sprintf("%s: missing key: %x  expected: %x", d.Pool, d.Key, d.Expected)
+	case nil == d.Expected:
+		return fmt.Sprintf("%s: extra key: %x  stored: %x", d.Pool, d.Key, d.Stored)
+	default:
+		return fmt.Sprintf("%s: key: %x  stored: %x  expected: %x", d.Pool, d.Key, d.Stored, d.Expected)
+	}
+}
+
+// number of records to read from a pool at a time
+const fetchCount = 1000
+
+// pools that are compared record by record
+var checkedPools = []struct {
+	name string
+	pool **storage.PoolHandle
+}{
+	{"BlockOwners", &storage.Pool.BlockOwners},
+	{"BlockNumbers", &storage.Pool.BlockNumbers},
+	{"Assets", &storage.Pool.Assets},
+	{"AssetRegistrants", &storage.Pool.AssetRegistrants},
+	{"AssetNames", &storage.Pool.AssetNames},
+	{"AssetMetadata", &storage.Pool.AssetMetadata},
+	{"Transactions", &storage.Pool.Transactions},
+	{"TxBlock", &storage.Pool.TxBlock},
+	{"BatchLinks", &storage.Pool.BatchLinks},
+}
+
+// walk all stored blocks from genesis and recompute every index
+// derived from them, returning all differences to the stored indexes
+//
+// if repair is set then the stored indexes are replaced by the
+// recomputed ones in a single batch
+//
+// the ownership list positions (count values) may legitimately differ
+// after a fork was deleted, so ownership is compared by content
+//
+// this is intended for offline use on a database that is not open in
+// a running node; the block data itself cannot be repaired and any
+// damage to it is returned as an error
+func CheckIndexes(repair bool) ([]Divergence, error) {
+
+	if mode.IsLight() {
+		return nil, fault.ErrNotAvailableInLightMode
+	}
+
+	expected, err := rebuildIndexes()
+	if nil != err {
+		return nil, err
+	}
+
+	divergences := []Divergence{}
+	fix := storage.NewBatch()
+
+	// record by record comparison
+	for _, item := range checkedPools {
+		p := *item.pool
+		stored, err := allElements(p)
+		if nil != err {
+			return nil, err
+		}
+		for _, d := range compareElements(item.name, stored, expected.Elements(p)) {
+			divergences = append(divergences, d)
+			if nil == d.Expected {
+				fix.Delete(p, d.Key)
+			} else {
+				fix.Put(p, d.Key, d.Expected)
+			}
+		}
+	}
+
+	// ownership comparison
+	ownership, err := checkOwnership(expected)
+	if nil != err {
+		return nil, err
+	}
+	if 0 != len(ownership) {
+		divergences = append(divergences, ownership...)
+
+		// the three pools are interlinked so replace them completely
+		for _, p := range []*storage.PoolHandle{storage.Pool.OwnerCount, storage.Pool.Ownership, storage.Pool.OwnerDigest} {
+			stored, err := allElements(p)
+			if nil != err {
+				return nil, err
+			}
+			for _, e := range stored {
+				fix.Delete(p, e.Key)
+			}
+			for _, e := range expected.Elements(p) {
+				fix.Put(p, e.Key, e.Value)
+			}
+		}
+	}
+
+	if repair && 0 != fix.Len() {
+		fix.Commit()
+	}
+
+	return divergences, nil
+}
+
+// replay all stored blocks into a batch that ignores the current indexes
+func rebuildIndexes() (*storage.Batch, error) {
+
+	expected := storage.NewIsolatedBatch(
+		storage.Pool.BlockOwners,
+		storage.Pool.BlockNumbers,
+		storage.Pool.Assets,
+		storage.Pool.AssetRegistrants,
+		storage.Pool.AssetNames,
+		storage.Pool.AssetMetadata,
+		storage.Pool.Transactions,
+		storage.Pool.TxBlock,
+		storage.Pool.BatchLinks,
+		storage.Pool.OwnerCount,
+		storage.Pool.Ownership,
+		storage.Pool.OwnerDigest,
+	)
+
+	cursor := storage.Pool.Blocks.NewFetchCursor()
+	number := genesis.BlockNumber + 1
+	for {
+		blocks, err := cursor.Fetch(fetchCount)
+		if nil != err {
+			return nil, err
+		}
+
+		for _, item := range blocks {
+			if 8 != len(item.Key) || number != binary.BigEndian.Uint64(item.Key) {
+				return nil, fmt.Errorf("block key: %x  expected block: %d", item.Key, number)
+			}
+
+			txs, err := unpackStoredBlock(number, item.Value)
+			if nil != err {
+				return nil, fmt.Errorf("block: %d  error: %v", number, err)
+			}
+
+			err = indexStoredBlock(expected, number, txs)
+			if nil != err {
+				return nil, fmt.Errorf("block: %d  error: %v", number, err)
+			}
+			digest := blockrecord.PackedHeader(item.Value[:blockrecord.TotalBlockSize]).Digest()
+			expected.Put(storage.Pool.BlockNumbers, digest[:], item.Key)
+			number += 1
+		}
+
+		if len(blocks) < fetchCount {
+			return expected, nil
+		}
+	}
+}
+
+// unpack a block and check that it matches its own header
+func unpackStoredBlock(number uint64, packedBlock []byte) ([]txn, error) {
+
+	if len(packedBlock) < blockrecord.TotalBlockSize {
+		return nil, fault.ErrInvalidBlockHeader
+	}
+	header, err := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize]).Unpack()
+	if nil != err {
+		return nil, err
+	}
+	if number != header.Number {
+		return nil, fault.ErrBlockNumberDoesNotMatch
+	}
+
+	data := packedBlock[blockrecord.TotalBlockSize:]
+	txs := make([]txn, header.TransactionCount)
+	txIds := make([]merkle.Digest, header.TransactionCount)
+	for i := range txs {
+		transaction, n, err := transactionrecord.Packed(data).Unpack()
+		if nil != err {
+			return nil, err
+		}
+		txIds[i] = merkle.NewDigest(data[:n])
+		txs[i].txId = txIds[i]
+		txs[i].packed = transactionrecord.Packed(data[:n])
+		txs[i].unpacked = transaction
+		data = data[n:]
+	}
+	if 0 != len(data) {
+		return nil, fault.ErrInvalidCount
+	}
+
+	fullMerkleTree := merkle.FullMerkleTree(txIds)
+	if fullMerkleTree[len(fullMerkleTree)-1] != header.MerkleRoot {
+		return nil, fault.ErrMerkleRootDoesNotMatch
+	}
+	return txs, nil
+}
+
+// index one block, converting corruption panics into errors
+func indexStoredBlock(expected *storage.Batch, number uint64, txs []txn) (err error) {
+	defer func() {
+		if r := recover(); nil != r {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	indexTransactions(expected, number, txs)
+	return nil
+}
+
+// read the whole of a pool
+func allElements(p *storage.PoolHandle) ([]storage.Element, error) {
+	cursor := p.NewFetchCursor()
+	elements := []storage.Element{}
+	for {
+		items, err := cursor.Fetch(fetchCount)
+		if nil != err {
+			return nil, err
+		}
+		elements = append(elements, items...)
+		if len(items) < fetchCount {
+			return elements, nil
+		}
+	}
+}
+
+// compare two key ordered lists of elements
+func compareElements(name string, stored []storage.Element, expected []storage.Element) []Divergence {
+	divergences := []Divergence{}
+	i := 0
+	j := 0
+	for i < len(stored) || j < len(expected) {
+		c := 0
+		if i >= len(stored) {
+			c = 1
+		} else if j >= len(expected) {
+			c = -1
+		} else {
+			c = bytes.Compare(stored[i].Key, expected[j].Key)
+		}
+
+		switch {
+		case c < 0:
+			divergences = append(divergences, Divergence{Pool: name, Key: stored[i].Key, Stored: stored[i].Value})
+			i += 1
+		case c > 0:
+			divergences = append(divergences, Divergence{Pool: name, Key: expected[j].Key, Expected: expected[j].Value})
+			j += 1
+		default:
+			if !bytes.Equal(stored[i].Value, expected[j].Value) {
+				divergences = append(divergences, Divergence{Pool: name, Key: stored[i].Key, Stored: stored[i].Value, Expected: expected[j].Value})
+			}
+			i += 1
+			j += 1
+		}
+	}
+	return divergences
+}
+
+// map each owner digest key to the ownership data it refers to
+// also returns the highest count used for each owner
+func ownershipView(ownership []storage.Element, digests []storage.Element) (map[string][]byte, map[string]uint64, []Divergence) {
+
+	records := make(map[string][]byte)
+	highest := make(map[string]uint64)
+	for _, e := range ownership {
+		records[string(e.Key)] = e.Value
+		n := len(e.Key) - 8
+		if n >= 0 {
+			owner := string(e.Key[:n])
+			if count := binary.BigEndian.Uint64(e.Key[n:]); count >= highest[owner] {
+				highest[owner] = count
+			}
+		}
+	}
+
+	divergences := []Divergence{}
+	referenced := make(map[string]bool)
+	view := make(map[string][]byte)
+	for _, e := range digests {
+		n := len(e.Key) - merkle.DigestLength
+		if n < 0 || 8 != len(e.Value) {
+			divergences = append(divergences, Divergence{Pool: "OwnerDigest", Key: e.Key, Stored: e.Value})
+			continue
+		}
+		oKey := string(e.Key[:n]) + string(e.Value)
+		data, ok := records[oKey]
+		if !ok {
+			divergences = append(divergences, Divergence{Pool: "OwnerDigest", Key: e.Key, Stored: e.Value})
+			continue
+		}
+		referenced[oKey] = true
+		view[string(e.Key)] = data
+	}
+
+	// every ownership record must be reachable from a digest
+	for _, e := range ownership {
+		if !referenced[string(e.Key)] {
+			divergences = append(divergences, Divergence{Pool: "Ownership", Key: e.Key, Stored: e.Value})
+		}
+	}
+
+	return view, highest, divergences
+}
+
+// compare ownership by content rather than by list position
+func checkOwnership(expected *storage.Batch) ([]Divergence, error) {
+
+	storedOwnership, err := allElements(storage.Pool.Ownership)
+	if nil != err {
+		return nil, err
+	}
+	storedDigests, err := allElements(storage.Pool.OwnerDigest)
+	if nil != err {
+		return nil, err
+	}
+	storedCounts, err := allElements(storage.Pool.OwnerCount)
+	if nil != err {
+		return nil, err
+	}
+
+	stored, highest, divergences := ownershipView(storedOwnership, storedDigests)
+	wanted, _, _ := ownershipView(expected.Elements(storage.Pool.Ownership), expected.Elements(storage.Pool.OwnerDigest))
+
+	for k, data := range wanted {
+		if s, ok := stored[k]; !ok || !bytes.Equal(s, data) {
+			divergences = append(divergences, Divergence{Pool: "OwnerDigest", Key: []byte(k), Stored: s, Expected: data})
+		}
+	}
+	for k, s := range stored {
+		if _, ok := wanted[k]; !ok {
+			divergences = append(divergences, Divergence{Pool: "OwnerDigest", Key: []byte(k), Stored: s})
+		}
+	}
+
+	// the next count for each owner must be beyond all of its records
+	counts := make(map[string]uint64)
+	for _, e := range storedCounts {
+		if 8 == len(e.Value) {
+			counts[string(e.Key)] = binary.BigEndian.Uint64(e.Value)
+		}
+	}
+	for owner, h := range highest {
+		if c, ok := counts[owner]; !ok || c <= h {
+			divergences = append(divergences, Divergence{
+				Pool:     "OwnerCount",
+				Key:      []byte(owner),
+				Stored:   storage.Pool.OwnerCount.Get([]byte(owner)),
+				Expected: expected.Get(storage.Pool.OwnerCount, []byte(owner)),
+			})
+		}
+	}
+
+	return divergences, nil
+}
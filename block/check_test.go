@@ -0,0 +1,85 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"testing"
+)
+
+// damaged indexes are detected and repaired
+func TestCheckIndexes(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+	transfer := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  issue.txId,
+		Owner: newTestOwner(t).account,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue, transfer})
+	testStoreBlock(header, packedBlock, txs)
+
+	divergences, err := CheckIndexes(false)
+	if nil != err {
+		t.Fatalf("check error: %v", err)
+	}
+	if 0 != len(divergences) {
+		t.Fatalf("unexpected divergences: %v", divergences)
+	}
+
+	// damage the indexes
+	storage.Pool.Transactions.Delete(issue.txId[:])
+	storage.Pool.TxBlock.Delete(issue.txId[:])
+	storage.Pool.Assets.Put([]byte("no-such-asset"), []byte("data"))
+	storage.Pool.BlockOwners.Put([]byte{0, 0, 0, 0, 0, 0, 0, 2}, []byte("wrong"))
+	digests, err := storage.Pool.OwnerDigest.NewFetchCursor().Fetch(10)
+	if nil != err || 0 == len(digests) {
+		t.Fatalf("owner digest fetch: %d  error: %v", len(digests), err)
+	}
+	storage.Pool.OwnerDigest.Delete(digests[0].Key)
+
+	pools := map[string]bool{}
+	divergences, err = CheckIndexes(false)
+	if nil != err {
+		t.Fatalf("check error: %v", err)
+	}
+	for _, d := range divergences {
+		pools[d.Pool] = true
+	}
+	for _, name := range []string{"Transactions", "TxBlock", "Assets", "BlockOwners", "OwnerDigest", "Ownership"} {
+		if !pools[name] {
+			t.Errorf("no divergence detected for: %s  in: %v", name, divergences)
+		}
+	}
+
+	// report only: nothing changed
+	if storage.Pool.Transactions.Has(issue.txId[:]) {
+		t.Errorf("check without repair modified the database")
+	}
+
+	// repair then check again
+	_, err = CheckIndexes(true)
+	if nil != err {
+		t.Fatalf("repair error: %v", err)
+	}
+	divergences, err = CheckIndexes(false)
+	if nil != err {
+		t.Fatalf("check error: %v", err)
+	}
+	if 0 != len(divergences) {
+		t.Errorf("divergences after repair: %v", divergences)
+	}
+	if !storage.Pool.Transactions.Has(issue.txId[:]) {
+		t.Errorf("issue transaction was not restored")
+	}
+}
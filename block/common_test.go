@@ -0,0 +1,289 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+// internal tests: the proof of work for a real block is too expensive
+// to compute in a test so the storage stage is called directly
+
+import (
+	"crypto/rand"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/blockdigest"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/chain"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/reservoir"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"golang.org/x/crypto/ed25519"
+	"os"
+	"testing"
+	"time"
+)
+
+// test database file
+const (
+	databaseFileName = "test.leveldb"
+)
+
+// the difficulty with the smallest target value
+const hardestBits = 0xbf00000000000000
+
+// blocks with this nonce are rejected by testStore
+const failingNonce = 0x5a5a
+
+// common test setup routines
+
+// remove all files created by test
+func removeFiles() {
+	os.RemoveAll(databaseFileName)
+}
+
+// configure for testing
+func setup(t *testing.T) {
+	removeFiles()
+	err := storage.Initialise(databaseFileName)
+	if nil != err {
+		t.Fatalf("storage initialise error: %s", err)
+	}
+
+	err = Initialise()
+	if nil != err {
+		t.Fatalf("block initialise error: %v", err)
+	}
+
+	// tests use all transaction and key types from the first block
+	globalData.activation = tagActivationFor(chain.Local)
+	globalData.keyActivation = keyTypeActivationFor(chain.Local)
+}
+
+// post test cleanup
+func teardown(t *testing.T) {
+	Finalise()
+	storage.Finalise()
+	removeFiles()
+}
+
+// an account with its signing key
+type testOwner struct {
+	account    *account.Account
+	privateKey ed25519.PrivateKey
+}
+
+func newTestOwner(t *testing.T) testOwner {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if nil != err {
+		t.Fatalf("key pair generation error: %v", err)
+	}
+	return testOwner{
+		account: &account.Account{
+			AccountInterface: &account.ED25519Account{
+				Test:      false,
+				PublicKey: publicKey,
+			},
+		},
+		privateKey: privateKey,
+	}
+}
+
+// any record that is signed by a single account
+type signable interface {
+	Pack(address *account.Account) (transactionrecord.Packed, error)
+}
+
+// pack a record, signing it with the given owner
+func signedPack(t *testing.T, signer testOwner, record signable) txn {
+
+	var signature *account.Signature
+	switch tx := record.(type) {
+	case *transactionrecord.BlockFoundation:
+		signature = &tx.Signature
+	case *transactionrecord.AssetData:
+		signature = &tx.Signature
+	case *transactionrecord.BitmarkIssue:
+		signature = &tx.Signature
+	case *transactionrecord.BitmarkTransfer:
+		signature = &tx.Signature
+	case *transactionrecord.BitmarkBurn:
+		signature = &tx.Signature
+	case *transactionrecord.BitmarkBatchTransfer:
+		signature = &tx.Signature
+	default:
+		t.Fatalf("cannot sign: %v", record)
+	}
+
+	message, _ := record.Pack(signer.account)
+	*signature = ed25519.Sign(signer.privateKey, message)
+	packed, err := record.Pack(signer.account)
+	if nil != err {
+		t.Fatalf("pack error: %v", err)
+	}
+
+	return txn{
+		txId:     packed.MakeLink(),
+		packed:   packed,
+		unpacked: record,
+	}
+}
+
+// pack a countersigned transfer, signing it by both parties
+func countersignedPack(t *testing.T, signer testOwner, recipient testOwner, record *transactionrecord.BitmarkTransferCountersigned) txn {
+	message, _ := record.PackOffer(signer.account)
+	record.Signature = ed25519.Sign(signer.privateKey, message)
+	record.Countersignature = ed25519.Sign(recipient.privateKey, message)
+	packed, err := record.Pack(signer.account)
+	if nil != err {
+		t.Fatalf("pack error: %v", err)
+	}
+
+	return txn{
+		txId:     packed.MakeLink(),
+		packed:   packed,
+		unpacked: record,
+	}
+}
+
+// the base record of the live genesis block, reused for new blocks
+func genesisBase(t *testing.T) txn {
+	packed := transactionrecord.Packed(genesis.LiveGenesisBlock[blockrecord.TotalBlockSize:])
+	base, _, err := packed.Unpack()
+	if nil != err {
+		t.Fatalf("unpack base error: %v", err)
+	}
+	return txn{
+		txId:     packed.MakeLink(),
+		packed:   packed,
+		unpacked: base,
+	}
+}
+
+// a header for the transactions with the merkle root already set
+func newHeader(number uint64, previous blockdigest.Digest, nonce blockrecord.NonceType, txs []txn) *blockrecord.Header {
+
+	txIds := make([]merkle.Digest, len(txs))
+	for i, tx := range txs {
+		txIds[i] = tx.txId
+	}
+	tree := merkle.FullMerkleTree(txIds)
+
+	header := blockrecord.New()
+	header.Version = blockrecord.Version
+	header.TransactionCount = uint16(len(txs))
+	header.Number = number
+	header.PreviousBlock = previous
+	header.MerkleRoot = tree[len(tree)-1]
+	header.Nonce = nonce
+	return header
+}
+
+// header for the block following the live genesis block with a
+// timestamp that passes validation
+func nextHeader(t *testing.T, txs []txn) *blockrecord.Header {
+	g, err := blockrecord.PackedHeader(genesis.LiveGenesisBlock[:blockrecord.TotalBlockSize]).Unpack()
+	if nil != err {
+		t.Fatalf("unpack genesis header error: %v", err)
+	}
+
+	header := newHeader(genesis.BlockNumber+1, genesis.LiveGenesisDigest, 0, txs)
+	header.Timestamp = g.Timestamp + 120
+	return header
+}
+
+// pack a header followed by its transactions
+func packBlock(header *blockrecord.Header, txs []txn) []byte {
+	packedBlock := []byte(header.Pack())
+	for _, tx := range txs {
+		packedBlock = append(packedBlock, tx.packed...)
+	}
+	return packedBlock
+}
+
+// the block following genesis with the base record prepended to the transactions
+func makeTestBlock(t *testing.T, txs []txn) (*blockrecord.Header, []byte, []txn) {
+	return makeBlockAt(t, genesis.BlockNumber+1, genesis.LiveGenesisDigest, 0, txs)
+}
+
+// a block with the base record prepended to the transactions
+func makeBlockAt(t *testing.T, number uint64, previous blockdigest.Digest, nonce blockrecord.NonceType, txs []txn) (*blockrecord.Header, []byte, []txn) {
+	return makeBlockWithBase(number, previous, nonce, genesisBase(t), txs)
+}
+
+// a block with the given base record prepended to the transactions
+func makeBlockWithBase(number uint64, previous blockdigest.Digest, nonce blockrecord.NonceType, baseTx txn, txs []txn) (*blockrecord.Header, []byte, []txn) {
+	txs = append([]txn{baseTx}, txs...)
+	header := newHeader(number, previous, nonce, txs)
+	return header, packBlock(header, txs), txs
+}
+
+// run a storage stage as the exported functions do: with the lock
+// held and the reservoir disabled
+func locked(f func() error) error {
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	reservoir.Disable()
+	defer reservoir.Enable()
+
+	return f()
+}
+
+// store a block without the validation done by StoreIncoming
+func testStoreBlock(header *blockrecord.Header, packedBlock []byte, txs []txn) {
+	locked(func() error {
+		digest := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize]).Digest()
+		storeBlock(header, digest, packedBlock, txs)
+		return nil
+	})
+}
+
+// pass a block through storeIncoming with a current timestamp
+func testStoreIncoming(header *blockrecord.Header, txs []txn) error {
+	header.Timestamp = uint64(time.Now().Unix())
+	return locked(func() error {
+		return storeIncoming(packBlock(header, txs))
+	})
+}
+
+// store a header without the validation done by StoreHeader
+func testStoreHeader(header *blockrecord.Header, packedHeader []byte) {
+	locked(func() error {
+		digest := blockrecord.PackedHeader(packedHeader).Digest()
+		storeAndUpdate(storage.NewBatch(), header, digest, packedHeader)
+		return nil
+	})
+}
+
+// store without proof of work so that branches can be built in tests
+// hold lock before calling this
+func testStore(packedBlock []byte) error {
+	packedHeader := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize])
+	header, err := packedHeader.Unpack()
+	if nil != err {
+		return err
+	}
+	if failingNonce == header.Nonce {
+		return fault.ErrInvalidNonce
+	}
+	if globalData.height+1 != header.Number {
+		return fault.ErrBlockNumberDoesNotMatch
+	}
+	txs, err := unpackStoredBlock(header.Number, packedBlock)
+	if nil != err {
+		return err
+	}
+	storeBlock(header, packedHeader.Digest(), packedBlock, txs)
+	return nil
+}
+
+// count all records in a pool
+func poolCount(t *testing.T, p *storage.PoolHandle) int {
+	items, err := p.NewFetchCursor().Fetch(100)
+	if nil != err {
+		t.Fatalf("fetch error: %v", err)
+	}
+	return len(items)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/bitmark-inc/bitmarkd/asset"
 	"github.com/bitmark-inc/bitmarkd/blockrecord"
 	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/notify"
 	"github.com/bitmark-inc/bitmarkd/reservoir"
 	"github.com/bitmark-inc/bitmarkd/storage"
 	"github.com/bitmark-inc/bitmarkd/transactionrecord"
@@ -19,6 +20,15 @@ func DeleteDownToBlock(finalBlockNumber uint64) error {
 	globalData.Lock()
 	defer globalData.Unlock()
 
+	reservoir.Disable()
+	defer reservoir.Enable()
+
+	return deleteDownToBlock(finalBlockNumber)
+}
+
+// hold lock and disable reservoir before calling this
+func deleteDownToBlock(finalBlockNumber uint64) error {
+
 	log := globalData.log
 
 	log.Infof("Delete down to block: %d", finalBlockNumber)
@@ -28,9 +38,6 @@ func DeleteDownToBlock(finalBlockNumber uint64) error {
 		return nil // block store is already empty
 	}
 
-	reservoir.Disable()
-	defer reservoir.Enable()
-
 	packedBlock := last.Value
 
 	for {
@@ -44,16 +51,15 @@ func DeleteDownToBlock(finalBlockNumber uint64) error {
 		// finished
 		if header.Number < finalBlockNumber {
 			log.Infof("finish: _NOT_ Deleting: %d", header.Number)
-			fillRingBuffer(log)
-			return nil
+			return fillRingBuffer(log)
 		}
 
 		log.Infof("Delete block: %d  transactions: %d", header.Number, header.TransactionCount)
 
 		// packed transactions
+		txs := make([]txn, 0, header.TransactionCount)
 		data := packedBlock[blockrecord.TotalBlockSize:]
-	loop:
-		for i := 1; true; i += 1 {
+		for i := 1; 0 != len(data); i += 1 {
 			transaction, n, err := transactionrecord.Packed(data).Unpack()
 			if nil != err {
 				log.Errorf("tx[%d]: error: %v", i, err)
@@ -61,61 +67,136 @@ func DeleteDownToBlock(finalBlockNumber uint64) error {
 			}
 
 			packedTransaction := transactionrecord.Packed(data[:n])
-			switch tx := transaction.(type) {
-			case *transactionrecord.BaseData:
-				// currently not stored separately
+			txs = append(txs, txn{
+				txId:     packedTransaction.MakeLink(),
+				packed:   packedTransaction,
+				unpacked: transaction,
+			})
+			data = data[n:]
+		}
+
+		// owners are found before any of the block is removed
+		events := ownerEvents(nil, header.Number, txs, true)
+
+		// the whole block is removed as a single batch
+		batch := storage.NewBatch()
+
+		// undo in reverse order so that transfers are removed
+		// before any issue or transfer they link to in the same block
+		for i := len(txs) - 1; i >= 0; i -= 1 {
+			txId := txs[i].txId
+			switch tx := txs[i].unpacked.(type) {
+			case *transactionrecord.BaseData, *transactionrecord.BlockFoundation:
+				blockNumber := make([]byte, 8)
+				binary.BigEndian.PutUint64(blockNumber, header.Number)
+				batch.Delete(storage.Pool.BlockOwners, blockNumber)
 
 			case *transactionrecord.AssetData:
 				assetIndex := tx.AssetIndex()
 				key := assetIndex[:]
-				storage.Pool.Assets.Delete(key)
+				batch.Delete(storage.Pool.Assets, key)
+				for _, s := range assetSearchKeys(header.Number, assetIndex, tx) {
+					batch.Delete(s.pool, s.key)
+				}
 				asset.Delete(assetIndex)
 
 			case *transactionrecord.BitmarkIssue:
-				txId := packedTransaction.MakeLink()
 				key := txId[:]
-				storage.Pool.Transactions.Delete(key)
-				reservoir.DeleteByTxId(txId)
-				TransferOwnership(txId, txId, 0, tx.Owner, nil)
+				batch.Delete(storage.Pool.Transactions, key)
+				batch.Delete(storage.Pool.TxBlock, key)
+				reservoir.DeleteByTxId(batch, txId)
+				TransferOwnership(batch, txId, txId, 0, tx.Owner, nil)
 
 			case *transactionrecord.BitmarkTransfer:
-				txId := packedTransaction.MakeLink()
 				key := txId[:]
-				storage.Pool.Transactions.Delete(key)
-				reservoir.DeleteByTxId(txId)
+				batch.Delete(storage.Pool.Transactions, key)
+				batch.Delete(storage.Pool.TxBlock, key)
+				reservoir.DeleteByTxId(batch, txId)
 
-				linkOwner := OwnerOf(tx.Link)
+				linkOwner := ownerOf(batch, tx.Link)
 				if nil == linkOwner {
 					log.Criticalf("missing transaction record for: %v", tx.Link)
 					fault.Panic("Transactions database is corrupt")
 				}
-				// just use zero here, as the fork restore should overwrite with new chain, including updated block number
-				// ***** FIX THIS: is the above statement sufficient
-				TransferOwnership(txId, tx.Link, 0, tx.Owner, linkOwner)
+				// remove from the new owner and rebuild the previous
+				// owner's record with its original block numbers
+				TransferOwnership(batch, txId, txId, 0, tx.Owner, nil)
+				RestoreOwnership(batch, tx.Link, linkOwner)
 
-			default:
-				fault.Panicf("unexpected transaction: %v", transaction)
-			}
+			case *transactionrecord.BitmarkTransferCountersigned:
+				key := txId[:]
+				batch.Delete(storage.Pool.Transactions, key)
+				batch.Delete(storage.Pool.TxBlock, key)
+				reservoir.DeleteByTxId(batch, txId)
 
-			data = data[n:]
-			if 0 == len(data) {
-				break loop
+				linkOwner := ownerOf(batch, tx.Link)
+				if nil == linkOwner {
+					log.Criticalf("missing transaction record for: %v", tx.Link)
+					fault.Panic("Transactions database is corrupt")
+				}
+				// remove from the new owner and rebuild the previous
+				// owner's record with its original block numbers
+				TransferOwnership(batch, txId, txId, 0, tx.Owner, nil)
+				RestoreOwnership(batch, tx.Link, linkOwner)
+
+			case *transactionrecord.BitmarkBurn:
+				key := txId[:]
+				batch.Delete(storage.Pool.Transactions, key)
+				batch.Delete(storage.Pool.TxBlock, key)
+				reservoir.DeleteByTxId(batch, txId)
+
+				linkOwner := ownerOf(batch, tx.Link)
+				if nil == linkOwner {
+					log.Criticalf("missing transaction record for: %v", tx.Link)
+					fault.Panic("Transactions database is corrupt")
+				}
+				RestoreOwnership(batch, tx.Link, linkOwner)
+
+			case *transactionrecord.BitmarkBatchTransfer:
+				key := txId[:]
+				batch.Delete(storage.Pool.Transactions, key)
+				batch.Delete(storage.Pool.TxBlock, key)
+				reservoir.DeleteByTxId(batch, txId)
+
+				for _, link := range tx.Links {
+					linkOwner := ownerOf(batch, link)
+					if nil == linkOwner {
+						log.Criticalf("missing transaction record for: %v", link)
+						fault.Panic("Transactions database is corrupt")
+					}
+					batchLinkId := transactionrecord.BatchLinkId(txId, link)
+					batch.Delete(storage.Pool.BatchLinks, batchLinkId[:])
+
+					// remove from the new owner and rebuild the previous
+					// owner's record with its original block numbers
+					TransferOwnership(batch, batchLinkId, link, 0, tx.Owner, nil)
+					RestoreOwnership(batch, link, linkOwner)
+				}
+
+			default:
+				fault.Panicf("unexpected transaction: %v", tx)
 			}
 		}
 
 		// delete the block data
 		key := make([]byte, 8)
 		binary.BigEndian.PutUint64(key, header.Number)
-		storage.Pool.Blocks.Delete(key)
+		batch.Delete(storage.Pool.Blocks, key)
+		digest := packedHeader.Digest()
+		batch.Delete(storage.Pool.BlockNumbers, digest[:])
+		batch.Commit()
+		globalData.work.Sub(globalData.work, header.Difficulty.Work())
+
+		notify.Publish(events...)
 
 		// fetch previous block number
 		binary.BigEndian.PutUint64(key, header.Number-1)
 		packedBlock = storage.Pool.Blocks.Get(key)
 
 		if nil == packedBlock {
-			break
+			log.Info("finish: all blocks deleted")
+			return fillRingBuffer(log)
 		}
 
 	}
-	return nil
 }
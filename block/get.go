@@ -9,19 +9,22 @@ import (
 	"github.com/bitmark-inc/bitmarkd/blockdigest"
 	"github.com/bitmark-inc/bitmarkd/blockrecord"
 	"github.com/bitmark-inc/bitmarkd/blockring"
+	"github.com/bitmark-inc/bitmarkd/difficulty"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/merkle"
 	"github.com/bitmark-inc/bitmarkd/mode"
 	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
 )
 
 // get block data for initialising a new block
-// returns: previous block digest and the number for the new block
-func Get() (blockdigest.Digest, uint64) {
+// returns: previous block digest, the number and the difficulty for the new block
+func Get() (blockdigest.Digest, uint64, *difficulty.Difficulty) {
 	globalData.Lock()
 	defer globalData.Unlock()
 	nextBlockNumber := globalData.height + 1
-	return globalData.previousBlock, nextBlockNumber
+	return globalData.previousBlock, nextBlockNumber, difficulty.New().SetBits(globalData.difficulty)
 }
 
 // get the current height
@@ -32,9 +35,89 @@ func GetHeight() uint64 {
 	return height
 }
 
+// find a confirmed transaction
+// returns: the block number and the byte offset of the transaction within the packed block
+func LocateTransaction(txId merkle.Digest) (uint64, uint64, error) {
+	location := storage.Pool.TxBlock.Get(txId[:])
+	if 16 != len(location) {
+		return 0, 0, fault.ErrTransactionNotFound
+	}
+	return binary.BigEndian.Uint64(location[:8]), binary.BigEndian.Uint64(location[8:]), nil
+}
+
+// get the header of a block
+func HeaderForBlock(number uint64) (*blockrecord.Header, error) {
+	globalData.Lock()
+	defer globalData.Unlock()
+	return headerForBlock(number)
+}
+
+// a transaction decoded from a stored block
+type Transaction struct {
+	TxId     merkle.Digest
+	Packed   transactionrecord.Packed
+	Unpacked interface{}
+}
+
+// fetch and decode a block, including the genesis block
+// returns: the header, the block digest and the transactions in block order
+func GetBlock(number uint64) (*blockrecord.Header, blockdigest.Digest, []Transaction, error) {
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	if mode.IsLight() {
+		return nil, blockdigest.Digest{}, nil, fault.ErrNotAvailableInLightMode
+	}
+	if number < genesis.BlockNumber || number > globalData.height {
+		return nil, blockdigest.Digest{}, nil, fault.ErrBlockNotFound
+	}
+
+	packedBlock := packedBlockFor(number)
+	if nil == packedBlock {
+		return nil, blockdigest.Digest{}, nil, fault.ErrBlockNotFound
+	}
+	txs, err := unpackStoredBlock(number, packedBlock)
+	if nil != err {
+		return nil, blockdigest.Digest{}, nil, err
+	}
+
+	packedHeader := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize])
+	header, err := packedHeader.Unpack()
+	if nil != err {
+		return nil, blockdigest.Digest{}, nil, err
+	}
+
+	transactions := make([]Transaction, len(txs))
+	for i, tx := range txs {
+		transactions[i] = Transaction{
+			TxId:     tx.txId,
+			Packed:   tx.packed,
+			Unpacked: tx.unpacked,
+		}
+	}
+	return header, packedHeader.Digest(), transactions, nil
+}
+
+// find the number of a block from its digest
+func NumberForDigest(digest blockdigest.Digest) (uint64, error) {
+	if d, _ := digestForBlock(genesis.BlockNumber); d == digest {
+		return genesis.BlockNumber, nil
+	}
+	n := storage.Pool.BlockNumbers.Get(digest[:])
+	if 8 != len(n) {
+		return 0, fault.ErrBlockNotFound
+	}
+	return binary.BigEndian.Uint64(n), nil
+}
+
 func DigestForBlock(number uint64) (blockdigest.Digest, error) {
 	globalData.Lock()
 	defer globalData.Unlock()
+	return digestForBlock(number)
+}
+
+// hold lock before calling this
+func digestForBlock(number uint64) (blockdigest.Digest, error) {
 
 	// valid block number
 	if number <= genesis.BlockNumber {
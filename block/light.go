@@ -0,0 +1,92 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/reservoir"
+	"github.com/bitmark-inc/bitmarkd/storage"
+)
+
+// most headers returned by a single request
+const MaximumHeaders = 100
+
+// in light mode only the header of each block is kept: it is stored
+// in place of the packed block so that the digest, difficulty and
+// work of the chain are maintained exactly as for full blocks, but
+// none of the transaction indexes are written
+
+// store an incoming header checking that it extends the chain
+// and carries a valid proof of work
+func StoreHeader(packedHeader []byte) error {
+
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	reservoir.Disable()
+	defer reservoir.Enable()
+
+	if blockrecord.TotalBlockSize != len(packedHeader) {
+		return fault.ErrInvalidBlockHeader
+	}
+	return storeHeader(packedHeader)
+}
+
+// validate and store the header part of a block
+// hold lock and disable reservoir before calling this
+func storeHeader(packedBlock []byte) error {
+
+	if len(packedBlock) < blockrecord.TotalBlockSize {
+		return fault.ErrInvalidBlockHeader
+	}
+
+	packedHeader := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize])
+	header, err := packedHeader.Unpack()
+	if nil != err {
+		return err
+	}
+
+	err = validateHeader(header)
+	if nil != err {
+		return err
+	}
+
+	digest := packedHeader.Digest()
+	err = header.ValidateProof(digest)
+	if nil != err {
+		return err
+	}
+
+	storeAndUpdate(storage.NewBatch(), header, digest, packedHeader)
+
+	return nil
+}
+
+// fetch consecutive packed headers starting from a block number
+// returns: the headers concatenated, stopping at the current height
+func GetPackedHeaders(first uint64, count int) ([]byte, error) {
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	if count <= 0 || count > MaximumHeaders {
+		return nil, fault.ErrInvalidCount
+	}
+	if first < genesis.BlockNumber || first > globalData.height {
+		return nil, fault.ErrBlockNotFound
+	}
+
+	headers := make([]byte, 0, count*blockrecord.TotalBlockSize)
+	for n := first; n <= globalData.height && count > 0; n += 1 {
+		packed := packedBlockFor(n)
+		if len(packed) < blockrecord.TotalBlockSize {
+			return nil, fault.ErrBlockNotFound
+		}
+		headers = append(headers, packed[:blockrecord.TotalBlockSize]...)
+		count -= 1
+	}
+	return headers, nil
+}
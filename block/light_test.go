@@ -0,0 +1,96 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"bytes"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"testing"
+)
+
+// in light mode only headers are stored and incoming blocks are
+// reduced to their validated header
+func TestLightHeaders(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	mode.SetLight(true)
+	defer mode.SetLight(false)
+
+	owner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("light")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+
+	header, packedBlock, _ := makeTestBlock(t, []txn{issue})
+	packedHeader := packedBlock[:blockrecord.TotalBlockSize]
+	testStoreHeader(header, packedHeader)
+
+	if header.Number != GetHeight() {
+		t.Errorf("height: %d  expected: %d", GetHeight(), header.Number)
+	}
+	pools := []struct {
+		name string
+		pool *storage.PoolHandle
+	}{
+		{"transactions", storage.Pool.Transactions},
+		{"transaction blocks", storage.Pool.TxBlock},
+		{"block owners", storage.Pool.BlockOwners},
+		{"ownership", storage.Pool.Ownership},
+	}
+	for _, p := range pools {
+		if n := poolCount(t, p.pool); 0 != n {
+			t.Errorf("%s: %d records in light mode", p.name, n)
+		}
+	}
+
+	headers, err := GetPackedHeaders(genesis.BlockNumber, MaximumHeaders)
+	if nil != err {
+		t.Fatalf("get packed headers error: %v", err)
+	}
+	if 2*blockrecord.TotalBlockSize != len(headers) || !bytes.Equal(packedHeader, headers[blockrecord.TotalBlockSize:]) {
+		t.Errorf("packed headers: %x", headers)
+	}
+	if _, err := GetPackedHeaders(header.Number+1, 1); fault.ErrBlockNotFound != err {
+		t.Errorf("headers above height: error: %v  expected: %v", err, fault.ErrBlockNotFound)
+	}
+
+	if _, _, _, err := GetBlock(header.Number); fault.ErrNotAvailableInLightMode != err {
+		t.Errorf("get block: error: %v  expected: %v", err, fault.ErrNotAvailableInLightMode)
+	}
+	if _, err := ProveTransaction(issue.txId); fault.ErrNotAvailableInLightMode != err {
+		t.Errorf("prove transaction: error: %v  expected: %v", err, fault.ErrNotAvailableInLightMode)
+	}
+
+	// incoming blocks are checked as headers
+	digest := blockrecord.PackedHeader(packedHeader).Digest()
+	next, _, nextTxs := makeBlockAt(t, header.Number+1, genesis.LiveGenesisDigest, 0, nil)
+	if err := testStoreIncoming(next, nextTxs); fault.ErrPreviousBlockDigestDoesNotMatch != err {
+		t.Errorf("wrong previous: error: %v  expected: %v", err, fault.ErrPreviousBlockDigestDoesNotMatch)
+	}
+	next, _, nextTxs = makeBlockAt(t, header.Number+1, digest, 0, nil)
+	if err := testStoreIncoming(next, nextTxs); fault.ErrDifficultyNotMet != err {
+		t.Errorf("no proof of work: error: %v  expected: %v", err, fault.ErrDifficultyNotMet)
+	}
+	if err := StoreHeader(packedBlock); fault.ErrInvalidBlockHeader != err {
+		t.Errorf("store full block as header: error: %v  expected: %v", err, fault.ErrInvalidBlockHeader)
+	}
+	if header.Number != GetHeight() {
+		t.Errorf("height after rejects: %d  expected: %d", GetHeight(), header.Number)
+	}
+
+	// header only blocks can be removed
+	if err := DeleteDownToBlock(header.Number); nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	checkEmpty(t, "after delete")
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/notify"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+)
+
+// the ownership changes made by the transactions of a block
+//
+// a transfer is resolved to its previous owner through the batch so
+// that links within the same block are found; a nil batch only reads
+// committed transactions
+func ownerEvents(batch *storage.Batch, number uint64, txs []txn, reversed bool) []notify.Event {
+
+	events := make([]notify.Event, 0, len(txs))
+	for _, item := range txs {
+		switch tx := item.unpacked.(type) {
+
+		case *transactionrecord.BitmarkIssue:
+			events = append(events, notify.Event{
+				Kind:        notify.Issued,
+				Owner:       tx.Owner,
+				TxId:        item.txId,
+				BlockNumber: number,
+				Reversed:    reversed,
+			})
+
+		case *transactionrecord.BitmarkTransfer:
+			events = append(events, notify.Event{
+				Kind:        notify.Sent,
+				Owner:       ownerOf(batch, tx.Link),
+				TxId:        item.txId,
+				BlockNumber: number,
+				Reversed:    reversed,
+			}, notify.Event{
+				Kind:        notify.Received,
+				Owner:       tx.Owner,
+				TxId:        item.txId,
+				BlockNumber: number,
+				Reversed:    reversed,
+			})
+
+		case *transactionrecord.BitmarkTransferCountersigned:
+			events = append(events, notify.Event{
+				Kind:        notify.Sent,
+				Owner:       ownerOf(batch, tx.Link),
+				TxId:        item.txId,
+				BlockNumber: number,
+				Reversed:    reversed,
+			}, notify.Event{
+				Kind:        notify.Received,
+				Owner:       tx.Owner,
+				TxId:        item.txId,
+				BlockNumber: number,
+				Reversed:    reversed,
+			})
+
+		case *transactionrecord.BitmarkBatchTransfer:
+			// one pair of events for the whole batch
+			events = append(events, notify.Event{
+				Kind:        notify.Sent,
+				Owner:       ownerOf(batch, tx.Links[0]),
+				TxId:        item.txId,
+				BlockNumber: number,
+				Reversed:    reversed,
+			}, notify.Event{
+				Kind:        notify.Received,
+				Owner:       tx.Owner,
+				TxId:        item.txId,
+				BlockNumber: number,
+				Reversed:    reversed,
+			})
+
+		case *transactionrecord.BitmarkBurn:
+			events = append(events, notify.Event{
+				Kind:        notify.Burned,
+				Owner:       ownerOf(batch, tx.Link),
+				TxId:        item.txId,
+				BlockNumber: number,
+				Reversed:    reversed,
+			})
+		}
+	}
+	return events
+}
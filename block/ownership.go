@@ -49,7 +49,8 @@ const (
 )
 
 // need to have a lock
-func TransferOwnership(previousTxId merkle.Digest, transferTxId merkle.Digest, transferBlockNumber uint64, currentOwner *account.Account, newOwner *account.Account) {
+// changes are queued in the batch and are not visible until it is committed
+func TransferOwnership(batch *storage.Batch, previousTxId merkle.Digest, transferTxId merkle.Digest, transferBlockNumber uint64, currentOwner *account.Account, newOwner *account.Account) {
 
 	// ensure single threaded
 	toLock.Lock()
@@ -57,7 +58,7 @@ func TransferOwnership(previousTxId merkle.Digest, transferTxId merkle.Digest, t
 
 	// get count for current owner record
 	dKey := append(currentOwner.Bytes(), previousTxId[:]...)
-	dCount := storage.Pool.OwnerDigest.Get(dKey)
+	dCount := batch.Get(storage.Pool.OwnerDigest, dKey)
 	if nil == dCount {
 		fault.Criticalf("TransferOwnership: dKey: %x", dKey)
 		fault.Criticalf("TransferOwnership: block number: %d", transferBlockNumber)
@@ -78,12 +79,12 @@ func TransferOwnership(previousTxId merkle.Digest, transferTxId merkle.Digest, t
 
 	// delete the current owners records
 	oKey := append(currentOwner.Bytes(), dCount...)
-	ownerData := storage.Pool.Ownership.Get(oKey)
+	ownerData := batch.Get(storage.Pool.Ownership, oKey)
 	if nil == ownerData {
 		fault.Panic("TransferOwnership: Ownership database corrupt")
 	}
-	storage.Pool.Ownership.Delete(oKey)
-	storage.Pool.OwnerDigest.Delete(dKey)
+	batch.Delete(storage.Pool.Ownership, oKey)
+	batch.Delete(storage.Pool.OwnerDigest, dKey)
 
 	// if no new owner only above delete was needed
 	if nil == newOwner {
@@ -92,15 +93,15 @@ func TransferOwnership(previousTxId merkle.Digest, transferTxId merkle.Digest, t
 
 	copy(ownerData[txIdStart:txIdFinish], transferTxId[:])
 	binary.BigEndian.PutUint64(ownerData[transferBlockNumberStart:transferBlockNumberFinish], transferBlockNumber)
-	create(transferTxId, ownerData, newOwner)
+	create(batch, transferTxId, ownerData, newOwner)
 }
 
 // internal creation routine, must be called with lock held
-func create(txId merkle.Digest, ownerData []byte, owner *account.Account) {
+func create(batch *storage.Batch, txId merkle.Digest, ownerData []byte, owner *account.Account) {
 
 	// increment the count for new owner
 	nKey := owner.Bytes()
-	count := storage.Pool.OwnerCount.Get(nKey)
+	count := batch.Get(storage.Pool.OwnerCount, nKey)
 	if nil == count {
 		count = []byte{0, 0, 0, 0, 0, 0, 0, 0}
 	} else if 8 != len(count) {
@@ -108,20 +109,21 @@ func create(txId merkle.Digest, ownerData []byte, owner *account.Account) {
 	}
 	newCount := make([]byte, 8)
 	binary.BigEndian.PutUint64(newCount, binary.BigEndian.Uint64(count)+1)
-	storage.Pool.OwnerCount.Put(nKey, newCount)
+	batch.Put(storage.Pool.OwnerCount, nKey, newCount)
 
 	// write the new owner
 	oKey := append(owner.Bytes(), count...)
 
 	// txId ++ last transfer block number ++ issue txId ++ issue block number ++ asset index
-	storage.Pool.Ownership.Put(oKey, ownerData)
+	batch.Put(storage.Pool.Ownership, oKey, ownerData)
 
 	// write new digest record
 	dKey := append(owner.Bytes(), txId[:]...)
-	storage.Pool.OwnerDigest.Put(dKey, count)
+	batch.Put(storage.Pool.OwnerDigest, dKey, count)
 }
 
-func CreateOwnership(issueTxId merkle.Digest, issueBlockNumber uint64, assetIndex transactionrecord.AssetIndex, newOwner *account.Account) {
+// changes are queued in the batch and are not visible until it is committed
+func CreateOwnership(batch *storage.Batch, issueTxId merkle.Digest, issueBlockNumber uint64, assetIndex transactionrecord.AssetIndex, newOwner *account.Account) {
 	// ensure single threaded
 	toLock.Lock()
 	defer toLock.Unlock()
@@ -140,17 +142,107 @@ func CreateOwnership(issueTxId merkle.Digest, issueBlockNumber uint64, assetInde
 	newData = append(newData, assetIndex[:]...)
 
 	// store to database
-	create(issueTxId, newData, newOwner)
+	create(batch, issueTxId, newData, newOwner)
+}
+
+// give the ownership of a transferred or burned link back to its owner
+//
+// the ownership record is rebuilt by following the links back to the
+// issue, the queued changes of the batch are included so that this
+// works while the rest of a block is being removed
+func RestoreOwnership(batch *storage.Batch, link merkle.Digest, owner *account.Account) {
+	// ensure single threaded
+	toLock.Lock()
+	defer toLock.Unlock()
+
+	blockNumberOf := func(txId merkle.Digest) []byte {
+		location := batch.Get(storage.Pool.TxBlock, txId[:])
+		if 16 != len(location) {
+			fault.Panicf("RestoreOwnership: no block for tx id: %v", txId)
+		}
+		return location[:8]
+	}
+
+	// an issue has a zero transfer block number
+	transferBlockNumber := []byte{0, 0, 0, 0, 0, 0, 0, 0}
+
+	id := link
+	for {
+		packed := batch.Get(storage.Pool.Transactions, id[:])
+		if nil == packed {
+
+			// one of the bitmarks moved by a batch transfer
+			batchTxId, previous, ok := batchLinkOf(batch, id)
+			if !ok {
+				fault.Panicf("RestoreOwnership: missing transaction record for: %v", id)
+			}
+			if id == link {
+				transferBlockNumber = blockNumberOf(batchTxId)
+			}
+			id = previous
+			continue
+		}
+		transaction, _, err := transactionrecord.Packed(packed).Unpack()
+		fault.PanicIfError("RestoreOwnership", err)
+
+		switch tx := transaction.(type) {
+		case *transactionrecord.BitmarkIssue:
+			// txId ++ last transfer block number ++ issue txId ++ issue block number ++ asset index
+			ownerData := append(link[:], transferBlockNumber...)
+			ownerData = append(ownerData, id[:]...)
+			ownerData = append(ownerData, blockNumberOf(id)...)
+			ownerData = append(ownerData, tx.AssetIndex[:]...)
+			create(batch, link, ownerData, owner)
+			return
+
+		case *transactionrecord.BitmarkTransfer:
+			if id == link {
+				transferBlockNumber = blockNumberOf(id)
+			}
+			id = tx.Link
+
+		case *transactionrecord.BitmarkTransferCountersigned:
+			if id == link {
+				transferBlockNumber = blockNumberOf(id)
+			}
+			id = tx.Link
+
+		default:
+			fault.Panicf("RestoreOwnership: incorrect transaction: %v", transaction)
+		}
+	}
 }
 
 // find the owner of a specific transaction
-// (only issue or transfer is allowed)
+// (only issue, transfers, burn or a batch link id is allowed)
 func OwnerOf(txId merkle.Digest) *account.Account {
+	return ownerOf(nil, txId)
+}
 
-	key := txId[:]
-	packed := storage.Pool.Transactions.Get(key)
+// find the owner including any transactions queued in a batch
+// a nil batch only reads committed transactions
+func ownerOf(batch *storage.Batch, txId merkle.Digest) *account.Account {
+
+	packed := batchGet(batch, storage.Pool.Transactions, txId[:])
 	if nil == packed {
-		return nil
+
+		// one of the bitmarks moved by a batch transfer
+		batchTxId, _, ok := batchLinkOf(batch, txId)
+		if !ok {
+			return nil
+		}
+		packed = batchGet(batch, storage.Pool.Transactions, batchTxId[:])
+		if nil == packed {
+			fault.Panicf("block.OwnerOf: missing batch transfer: %v", batchTxId)
+		}
+		transaction, _, err := transactionrecord.Packed(packed).Unpack()
+		fault.PanicIfError("block.OwnerOf", err)
+
+		tx, ok := transaction.(*transactionrecord.BitmarkBatchTransfer)
+		if !ok {
+			fault.Panicf("block.OwnerOf: incorrect batch transfer: %v", transaction)
+		}
+		return tx.Owner
 	}
 
 	transaction, _, err := transactionrecord.Packed(packed).Unpack()
@@ -163,12 +255,50 @@ func OwnerOf(txId merkle.Digest) *account.Account {
 	case *transactionrecord.BitmarkTransfer:
 		return tx.Owner
 
+	case *transactionrecord.BitmarkTransferCountersigned:
+		return tx.Owner
+
+	case *transactionrecord.BitmarkBurn:
+		return nil // a burned bitmark has no owner
+
+	case *transactionrecord.BitmarkBatchTransfer:
+		return nil // each bitmark is identified by its batch link id
+
 	default:
 		fault.Panicf("block.OwnerOf: incorrect transaction: %v", transaction)
 		return nil
 	}
 }
 
+// the confirmed batch transfer that moved a bitmark and the link it replaced
+func BatchLink(batchLinkId merkle.Digest) (merkle.Digest, merkle.Digest, bool) {
+	return batchLinkOf(nil, batchLinkId)
+}
+
+// the batch transfer that moved a bitmark and the link it replaced
+// a nil batch only reads committed records
+func batchLinkOf(batch *storage.Batch, batchLinkId merkle.Digest) (merkle.Digest, merkle.Digest, bool) {
+	var batchTxId merkle.Digest
+	var previous merkle.Digest
+
+	data := batchGet(batch, storage.Pool.BatchLinks, batchLinkId[:])
+	if 2*merkle.DigestLength != len(data) {
+		return batchTxId, previous, false
+	}
+	merkle.DigestFromBytes(&batchTxId, data[:merkle.DigestLength])
+	merkle.DigestFromBytes(&previous, data[merkle.DigestLength:])
+	return batchTxId, previous, true
+}
+
+// read a record including any changes queued in a batch
+// a nil batch only reads committed records
+func batchGet(batch *storage.Batch, p *storage.PoolHandle, key []byte) []byte {
+	if nil == batch {
+		return p.Get(key)
+	}
+	return batch.Get(p, key)
+}
+
 // type to represent an ownership record
 type Ownership struct {
 	N          uint64                       `json:"n,string"`
@@ -0,0 +1,160 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"github.com/bitmark-inc/bitmarkd/util"
+)
+
+// more than enough levels for the largest transaction count of a block
+const maximumSiblings = 32
+
+// a confirmed transaction with its position in its block and the
+// digests needed to recompute the merkle root of the block header
+type TransactionProof struct {
+	BlockNumber uint64
+	Index       int
+	Packed      transactionrecord.Packed
+	Siblings    []merkle.Digest
+}
+
+// create the inclusion proof of a confirmed transaction
+// (needs the full blocks so is not available in light mode)
+func ProveTransaction(txId merkle.Digest) (*TransactionProof, error) {
+
+	if mode.IsLight() {
+		return nil, fault.ErrNotAvailableInLightMode
+	}
+
+	blockNumber, _, err := LocateTransaction(txId)
+	if nil != err {
+		return nil, err
+	}
+	_, _, txs, err := GetBlock(blockNumber)
+	if nil != err {
+		return nil, err
+	}
+
+	proof := &TransactionProof{
+		BlockNumber: blockNumber,
+		Index:       -1,
+	}
+	txIds := make([]merkle.Digest, len(txs))
+	for i, tx := range txs {
+		txIds[i] = tx.TxId
+		if txId == tx.TxId {
+			proof.Index = i
+			proof.Packed = tx.Packed
+		}
+	}
+	if proof.Index < 0 {
+		return nil, fault.ErrTransactionNotFound
+	}
+
+	proof.Siblings, err = merkle.InclusionProof(txIds, proof.Index)
+	if nil != err {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// check that a proof shows the transaction is in a locally stored block
+//
+// the transaction id may also be the batch link id of one of the
+// bitmarks moved by the proved batch transfer
+func (proof *TransactionProof) Verify(txId merkle.Digest) error {
+
+	provedId := proof.Packed.MakeLink()
+	if provedId != txId && !isBatchLinkOf(proof.Packed, provedId, txId) {
+		return fault.ErrInvalidInclusionProof
+	}
+	header, err := HeaderForBlock(proof.BlockNumber)
+	if nil != err {
+		return err
+	}
+	if !merkle.VerifyInclusion(header.MerkleRoot, provedId, proof.Index, int(header.TransactionCount), proof.Siblings) {
+		return fault.ErrInvalidInclusionProof
+	}
+	return nil
+}
+
+// check if an id is the batch link id of one of the links of a batch transfer
+func isBatchLinkOf(packed transactionrecord.Packed, batchTxId merkle.Digest, batchLinkId merkle.Digest) bool {
+	transaction, _, err := packed.Unpack()
+	if nil != err {
+		return false
+	}
+	tx, ok := transaction.(*transactionrecord.BitmarkBatchTransfer)
+	if !ok {
+		return false
+	}
+	for _, link := range tx.Links {
+		if batchLinkId == transactionrecord.BatchLinkId(batchTxId, link) {
+			return true
+		}
+	}
+	return false
+}
+
+// pack a proof for sending to a peer
+//
+//	block number(8) ++ index(varint) ++ length(varint) ++ packed transaction ++ count(varint) ++ siblings
+func (proof *TransactionProof) Pack() []byte {
+
+	buffer := make([]byte, 8)
+	binary.BigEndian.PutUint64(buffer, proof.BlockNumber)
+	buffer = append(buffer, util.ToVarint64(uint64(proof.Index))...)
+	buffer = append(buffer, util.ToVarint64(uint64(len(proof.Packed)))...)
+	buffer = append(buffer, proof.Packed...)
+	buffer = append(buffer, util.ToVarint64(uint64(len(proof.Siblings)))...)
+	for _, sibling := range proof.Siblings {
+		buffer = append(buffer, sibling[:]...)
+	}
+	return buffer
+}
+
+// unpack a proof received from a peer
+// the result must still be checked with Verify
+func UnpackTransactionProof(buffer []byte) (*TransactionProof, error) {
+
+	if len(buffer) < 8 {
+		return nil, fault.ErrInvalidLength
+	}
+	proof := &TransactionProof{
+		BlockNumber: binary.BigEndian.Uint64(buffer),
+	}
+	buffer = buffer[8:]
+
+	index, n := util.FromVarint64(buffer)
+	if 0 == n || index > 0xffff {
+		return nil, fault.ErrInvalidCount
+	}
+	proof.Index = int(index)
+	buffer = buffer[n:]
+
+	length, n := util.FromVarint64(buffer)
+	if 0 == n || uint64(len(buffer)-n) < length {
+		return nil, fault.ErrInvalidLength
+	}
+	proof.Packed = transactionrecord.Packed(buffer[n : n+int(length)])
+	buffer = buffer[n+int(length):]
+
+	count, n := util.FromVarint64(buffer)
+	if 0 == n || count > maximumSiblings || uint64(len(buffer)-n) != count*merkle.DigestLength {
+		return nil, fault.ErrInvalidLength
+	}
+	buffer = buffer[n:]
+
+	proof.Siblings = make([]merkle.Digest, count)
+	for i := range proof.Siblings {
+		copy(proof.Siblings[i][:], buffer[i*merkle.DigestLength:])
+	}
+	return proof, nil
+}
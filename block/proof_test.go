@@ -0,0 +1,138 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"bytes"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"testing"
+)
+
+// proofs survive packing and only verify for their own transaction
+func TestTransactionProof(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	issues := []txn{}
+	for i := 1; i <= 4; i += 1 {
+		issues = append(issues, signedPack(t, owner, &transactionrecord.BitmarkIssue{
+			AssetIndex: transactionrecord.NewAssetIndex([]byte("proof")),
+			Owner:      owner.account,
+			Nonce:      uint64(i),
+		}))
+	}
+
+	header, packedBlock, txs := makeTestBlock(t, issues)
+	testStoreBlock(header, packedBlock, txs)
+
+	for i, tx := range issues {
+		proof, err := ProveTransaction(tx.txId)
+		if nil != err {
+			t.Fatalf("%d: prove error: %v", i, err)
+		}
+		if header.Number != proof.BlockNumber || i+1 != proof.Index || !bytes.Equal(tx.packed, proof.Packed) {
+			t.Errorf("%d: block: %d  index: %d", i, proof.BlockNumber, proof.Index)
+		}
+
+		received, err := UnpackTransactionProof(proof.Pack())
+		if nil != err {
+			t.Fatalf("%d: unpack error: %v", i, err)
+		}
+		if err := received.Verify(tx.txId); nil != err {
+			t.Errorf("%d: verify error: %v", i, err)
+		}
+	}
+
+	proof, err := ProveTransaction(issues[2].txId)
+	if nil != err {
+		t.Fatalf("prove error: %v", err)
+	}
+	packed := proof.Pack()
+
+	altered := func(f func(p *TransactionProof)) *TransactionProof {
+		p, err := UnpackTransactionProof(packed)
+		if nil != err {
+			t.Fatalf("unpack error: %v", err)
+		}
+		f(p)
+		return p
+	}
+
+	tests := []struct {
+		title string
+		proof *TransactionProof
+		txId  merkle.Digest
+		err   error
+	}{
+		{"other transaction", altered(func(p *TransactionProof) {}), issues[1].txId, fault.ErrInvalidInclusionProof},
+		{"other record", altered(func(p *TransactionProof) { p.Packed = issues[1].packed }), issues[1].txId, fault.ErrInvalidInclusionProof},
+		{"wrong index", altered(func(p *TransactionProof) { p.Index -= 1 }), issues[2].txId, fault.ErrInvalidInclusionProof},
+		{"wrong sibling", altered(func(p *TransactionProof) { p.Siblings[0][0] ^= 0xff }), issues[2].txId, fault.ErrInvalidInclusionProof},
+		{"missing sibling", altered(func(p *TransactionProof) { p.Siblings = p.Siblings[1:] }), issues[2].txId, fault.ErrInvalidInclusionProof},
+		{"unknown block", altered(func(p *TransactionProof) { p.BlockNumber += 1 }), issues[2].txId, fault.ErrBlockNotFound},
+	}
+	for _, test := range tests {
+		if err := test.proof.Verify(test.txId); test.err != err {
+			t.Errorf("%s: error: %v  expected: %v", test.title, err, test.err)
+		}
+	}
+
+	for _, n := range []int{0, 7, 12, len(packed) - 1} {
+		if _, err := UnpackTransactionProof(packed[:n]); nil == err {
+			t.Errorf("truncated to: %d bytes: unpack did not fail", n)
+		}
+	}
+	if _, err := UnpackTransactionProof(append(packed, 0)); nil == err {
+		t.Errorf("trailing byte: unpack did not fail")
+	}
+
+	if _, err := ProveTransaction(merkle.Digest{}); fault.ErrTransactionNotFound != err {
+		t.Errorf("unknown transaction: error: %v  expected: %v", err, fault.ErrTransactionNotFound)
+	}
+}
+
+// the proof of a batch transfer also verifies its batch link ids
+func TestBatchTransferProof(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("proof")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+	batch := signedPack(t, owner, &transactionrecord.BitmarkBatchTransfer{
+		Links: []merkle.Digest{issue.txId},
+		Owner: newOwner.account,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue, batch})
+	testStoreBlock(header, packedBlock, txs)
+
+	proof, err := ProveTransaction(batch.txId)
+	if nil != err {
+		t.Fatalf("prove error: %v", err)
+	}
+
+	tests := []struct {
+		txId merkle.Digest
+		err  error
+	}{
+		{batch.txId, nil},
+		{transactionrecord.BatchLinkId(batch.txId, issue.txId), nil},
+		{transactionrecord.BatchLinkId(batch.txId, batch.txId), fault.ErrInvalidInclusionProof},
+		{transactionrecord.BatchLinkId(issue.txId, issue.txId), fault.ErrInvalidInclusionProof},
+	}
+	for i, test := range tests {
+		if err := proof.Verify(test.txId); test.err != err {
+			t.Errorf("%d: error: %v  expected: %v", i, err, test.err)
+		}
+	}
+}
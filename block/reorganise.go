@@ -0,0 +1,141 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/reservoir"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"math/big"
+)
+
+// replace the local blocks from the first block of a branch upwards
+// by the blocks of that branch
+//
+// the branch must connect to the local chain and must carry more
+// total work than the local blocks it replaces; every header is
+// checked before any local block is removed and if any block then
+// fails full validation the original blocks are restored
+func Reorganise(packedBlocks [][]byte) error {
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	reservoir.Disable()
+	defer reservoir.Enable()
+
+	if 0 == len(packedBlocks) {
+		return fault.ErrInvalidCount
+	}
+
+	// check the headers and total the work
+	headers := make([]*blockrecord.Header, len(packedBlocks))
+	branchWork := big.NewInt(0)
+	for i, packedBlock := range packedBlocks {
+		if len(packedBlock) < blockrecord.TotalBlockSize {
+			return fault.ErrInvalidBlockHeader
+		}
+		header, err := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize]).Unpack()
+		if nil != err {
+			return err
+		}
+		if 0 != i && headers[i-1].Number+1 != header.Number {
+			return fault.ErrBlockNumberDoesNotMatch
+		}
+		err = header.Validate()
+		if nil != err {
+			return err
+		}
+		headers[i] = header
+		branchWork.Add(branchWork, header.Difficulty.Work())
+	}
+
+	// the branch must start on top of an existing block
+	first := headers[0].Number
+	if first <= genesis.BlockNumber || first > globalData.height+1 {
+		return fault.ErrBlockNumberDoesNotMatch
+	}
+
+	localWork, err := workOfBlocks(first, globalData.height)
+	if nil != err {
+		return err
+	}
+	if branchWork.Cmp(localWork) <= 0 {
+		globalData.log.Infof("branch from: %d  work: %d  not more than local work: %d", first, branchWork, localWork)
+		return fault.ErrBranchNotHeavier
+	}
+
+	// check the digest chain and proof of work
+	previous, err := digestForBlock(first - 1)
+	if nil != err {
+		return err
+	}
+	for i, header := range headers {
+		if previous != header.PreviousBlock {
+			return fault.ErrPreviousBlockDigestDoesNotMatch
+		}
+		digest := blockrecord.PackedHeader(packedBlocks[i][:blockrecord.TotalBlockSize]).Digest()
+		err := header.ValidateProof(digest)
+		if nil != err {
+			return err
+		}
+		previous = digest
+	}
+
+	return switchBranch(first, packedBlocks, storeIncoming)
+}
+
+// replace blocks from first upwards, restoring them if the new branch fails
+// hold lock and disable reservoir before calling this
+func switchBranch(first uint64, packedBlocks [][]byte, store func([]byte) error) error {
+
+	log := globalData.log
+
+	// keep a copy of the blocks being replaced
+	original := [][]byte{}
+	key := make([]byte, 8)
+	for n := first; n <= globalData.height; n += 1 {
+		binary.BigEndian.PutUint64(key, n)
+		packedBlock := storage.Pool.Blocks.Get(key)
+		if nil == packedBlock {
+			return fault.ErrBlockNotFound
+		}
+		original = append(original, append([]byte{}, packedBlock...))
+	}
+
+	log.Infof("switch to branch from: %d  replacing: %d blocks  with: %d blocks", first, len(original), len(packedBlocks))
+
+	err := deleteDownToBlock(first)
+	if nil != err {
+		return err
+	}
+
+	for i, packedBlock := range packedBlocks {
+		err = store(packedBlock)
+		if nil == err {
+			continue
+		}
+
+		log.Errorf("branch block: %d  error: %v  restoring original blocks", first+uint64(i), err)
+
+		e := deleteDownToBlock(first)
+		if nil != e {
+			log.Criticalf("cannot remove branch from: %d  error: %v", first, e)
+			fault.Panic("block.switchBranch: failed to remove branch")
+		}
+		for j, packedBlock := range original {
+			e := store(packedBlock)
+			if nil != e {
+				log.Criticalf("cannot restore block: %d  error: %v", first+uint64(j), e)
+				fault.Panic("block.switchBranch: failed to restore original blocks")
+			}
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"testing"
+)
+
+// switch branch with the lock held as Reorganise does
+func testSwitchBranch(first uint64, packedBlocks [][]byte) error {
+	return locked(func() error {
+		return switchBranch(first, packedBlocks, testStore)
+	})
+}
+
+// store a local block 2 containing an issue
+func setupLocalChain(t *testing.T) txn {
+	owner := newTestOwner(t)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("fingerprint")),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+
+	header, packedBlock, txs := makeTestBlock(t, []txn{issue})
+	testStoreBlock(header, packedBlock, txs)
+	return issue
+}
+
+// a branch must have more work than the blocks it replaces
+func TestReorganiseRejects(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	issue := setupLocalChain(t)
+	digest, _ := DigestForBlock(2)
+	work := GetWork()
+
+	// same work as local block
+	_, equal, _ := makeBlockAt(t, 2, genesis.LiveGenesisDigest, 1, nil)
+	err := Reorganise([][]byte{equal})
+	if fault.ErrBranchNotHeavier != err {
+		t.Errorf("equal work: error: %v  expected: %v", err, fault.ErrBranchNotHeavier)
+	}
+
+	// more work claimed but not proved
+	header, _, _ := makeBlockAt(t, 2, genesis.LiveGenesisDigest, 1, nil)
+	header.Difficulty.SetBits(hardestBits)
+	_, heavy, _ := makeBlockAt(t, 2, genesis.LiveGenesisDigest, 1, nil)
+	copy(heavy, header.Pack())
+	err = Reorganise([][]byte{heavy})
+	if fault.ErrDifficultyNotMet != err {
+		t.Errorf("heavy: error: %v  expected: %v", err, fault.ErrDifficultyNotMet)
+	}
+
+	// not connected to the local chain
+	_, gap, _ := makeBlockAt(t, 4, genesis.LiveGenesisDigest, 1, nil)
+	err = Reorganise([][]byte{gap})
+	if fault.ErrBlockNumberDoesNotMatch != err {
+		t.Errorf("gap: error: %v  expected: %v", err, fault.ErrBlockNumberDoesNotMatch)
+	}
+
+	// local chain is unchanged
+	if d, _ := DigestForBlock(2); d != digest {
+		t.Errorf("block 2 digest changed")
+	}
+	if 0 != work.Cmp(GetWork()) {
+		t.Errorf("work: %d  expected: %d", GetWork(), work)
+	}
+	if nil == OwnerOf(issue.txId) {
+		t.Errorf("issue was removed")
+	}
+}
+
+// a branch that fails part way is removed and the original blocks restored
+func TestSwitchBranchRestores(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	issue := setupLocalChain(t)
+	digest, _ := DigestForBlock(2)
+	work := GetWork()
+
+	header2, block2, _ := makeBlockAt(t, 2, genesis.LiveGenesisDigest, 1, nil)
+	_, block3, _ := makeBlockAt(t, 3, blockrecord.PackedHeader(block2[:blockrecord.TotalBlockSize]).Digest(), failingNonce, nil)
+
+	err := testSwitchBranch(header2.Number, [][]byte{block2, block3})
+	if fault.ErrInvalidNonce != err {
+		t.Fatalf("switch error: %v  expected: %v", err, fault.ErrInvalidNonce)
+	}
+
+	if 2 != GetHeight() {
+		t.Errorf("height: %d  expected: 2", GetHeight())
+	}
+	if d, _ := DigestForBlock(2); d != digest {
+		t.Errorf("block 2 digest not restored")
+	}
+	if 0 != work.Cmp(GetWork()) {
+		t.Errorf("work: %d  expected: %d", GetWork(), work)
+	}
+	if nil == OwnerOf(issue.txId) {
+		t.Errorf("issue not restored")
+	}
+}
+
+// a valid branch replaces the local blocks
+func TestSwitchBranch(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	issue := setupLocalChain(t)
+	work := GetWork()
+
+	header2, block2, _ := makeBlockAt(t, 2, genesis.LiveGenesisDigest, 1, nil)
+	header3, block3, _ := makeBlockAt(t, 3, blockrecord.PackedHeader(block2[:blockrecord.TotalBlockSize]).Digest(), 1, nil)
+
+	err := testSwitchBranch(header2.Number, [][]byte{block2, block3})
+	if nil != err {
+		t.Fatalf("switch error: %v", err)
+	}
+
+	if 3 != GetHeight() {
+		t.Errorf("height: %d  expected: 3", GetHeight())
+	}
+	if d, _ := DigestForBlock(3); d != blockrecord.PackedHeader(block3[:blockrecord.TotalBlockSize]).Digest() {
+		t.Errorf("block 3 digest mismatch")
+	}
+	expected := work.Add(work, header3.Difficulty.Work())
+	if 0 != expected.Cmp(GetWork()) {
+		t.Errorf("work: %d  expected: %d", GetWork(), expected)
+	}
+	if nil != OwnerOf(issue.txId) {
+		t.Errorf("issue from replaced block still present")
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/chain"
+	"github.com/bitmark-inc/bitmarkd/difficulty"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// parameters for adjusting the difficulty of a chain
+type retargetRules struct {
+	activation uint64        // first block that must carry the computed difficulty
+	blockTime  time.Duration // desired interval between blocks
+	window     uint64        // number of block intervals used for each calculation
+	median     int           // number of estimates in the moving median (odd)
+	average    int           // number of medians in the weighted moving average
+}
+
+// a single interval can move the estimate by at most this factor
+const maximumIntervalFactor = 4
+
+// fraction bits of the fixed point reciprocal difficulty
+const reciprocalShift = 64
+
+// the reciprocal of a difficulty is one/target, which is 1 for the
+// minimum difficulty, kept as an integer scaled by 2^reciprocalShift
+var (
+	reciprocalNumerator = new(big.Int).Lsh(difficulty.New().BigInt(), reciprocalShift)
+	minimumReciprocal   = new(big.Int).Lsh(big.NewInt(1), reciprocalShift)
+)
+
+// rules for each chain
+var chainRetargetRules = map[string]retargetRules{
+	chain.Bitmark: {
+		activation: bitmarkActivationBlock,
+		blockTime:  2 * time.Minute,
+		window:     60,
+		median:     21,
+		average:    41,
+	},
+	chain.Testing: {
+		activation: testingActivationBlock,
+		blockTime:  2 * time.Minute,
+		window:     60,
+		median:     21,
+		average:    41,
+	},
+	chain.Local: {
+		activation: localActivationBlock,
+		blockTime:  2 * time.Minute,
+		window:     60,
+		median:     21,
+		average:    41,
+	},
+}
+
+// select the rules for a chain, unknown chains use the live chain rules
+func retargetRulesFor(chainName string) retargetRules {
+	if rules, ok := chainRetargetRules[chainName]; ok {
+		return rules
+	}
+	return chainRetargetRules[chain.Bitmark]
+}
+
+// compute the difficulty that a block must have from the stored
+// headers of the blocks before it
+//
+// each interval in the window gives an estimate of the difficulty
+// that would have produced the desired block time, i.e. the
+// difficulty of that block scaled by expected/actual; the estimates
+// pass through a moving median and then a weighted moving average,
+// both starting from the difficulty of the first block of the window
+//
+// all of the arithmetic is on integers so that the result only
+// depends on the stored headers and is the same on every node
+//
+// hold lock before calling this
+func expectedDifficulty(rules retargetRules, number uint64) (uint64, error) {
+
+	if number < rules.activation || number <= genesis.BlockNumber {
+		return difficulty.OneUint64, nil
+	}
+
+	last := number - 1
+	first := genesis.BlockNumber
+	if last > first+rules.window {
+		first = last - rules.window
+	}
+
+	header, err := headerForBlock(first)
+	if nil != err {
+		return 0, err
+	}
+
+	start := reciprocalOf(header.Difficulty)
+	estimates := make([]*big.Int, rules.median)
+	for i := range estimates {
+		estimates[i] = start
+	}
+	medians := make([]*big.Int, rules.average)
+	for i := range medians {
+		medians[i] = start
+	}
+
+	expected := int64(rules.blockTime / time.Second)
+	previous := int64(header.Timestamp)
+
+	for n := first + 1; n <= last; n += 1 {
+		header, err := headerForBlock(n)
+		if nil != err {
+			return 0, err
+		}
+
+		// timestamps need not increase so limit the effect of any one interval
+		actual := int64(header.Timestamp) - previous
+		if actual < expected/maximumIntervalFactor {
+			actual = expected / maximumIntervalFactor
+		} else if actual > expected*maximumIntervalFactor {
+			actual = expected * maximumIntervalFactor
+		}
+		previous = int64(header.Timestamp)
+
+		estimate := reciprocalOf(header.Difficulty)
+		estimate.Mul(estimate, big.NewInt(expected))
+		estimate.Quo(estimate, big.NewInt(actual))
+		if estimate.Cmp(minimumReciprocal) < 0 {
+			estimate.Set(minimumReciprocal)
+		}
+
+		estimates = append(estimates[1:], estimate)
+		medians = append(medians[1:], median(estimates))
+	}
+
+	target := new(big.Int).Quo(reciprocalNumerator, weightedAverage(medians))
+	return difficulty.New().SetBigInt(target).Bits(), nil
+}
+
+// the fixed point reciprocal of a difficulty
+func reciprocalOf(d *difficulty.Difficulty) *big.Int {
+	return new(big.Int).Quo(reciprocalNumerator, d.BigInt())
+}
+
+// the middle value, the number of values must be odd
+func median(values []*big.Int) *big.Int {
+	sorted := make([]*big.Int, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i int, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+	return sorted[len(sorted)/2]
+}
+
+// average with the weights 1, 2, … n from the oldest to the newest value
+func weightedAverage(values []*big.Int) *big.Int {
+	total := new(big.Int)
+	weights := int64(0)
+	for i, v := range values {
+		w := int64(i + 1)
+		total.Add(total, new(big.Int).Mul(v, big.NewInt(w)))
+		weights += w
+	}
+	return total.Quo(total, big.NewInt(weights))
+}
+
+// recompute the difficulty for the block after the current height
+// hold lock before calling this
+func updateDifficulty() error {
+
+	bits, err := expectedDifficulty(globalData.retarget, globalData.height+1)
+	if nil != err {
+		return err
+	}
+
+	globalData.difficulty = bits
+	difficulty.Current.SetBits(bits)
+	return nil
+}
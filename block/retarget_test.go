@@ -0,0 +1,213 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/chain"
+	"github.com/bitmark-inc/bitmarkd/difficulty"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"testing"
+	"time"
+)
+
+// start of the test block timestamps
+const retargetStartTime = 1500000000
+
+// short filters so that only a few blocks are needed
+var testRetargetRules = retargetRules{
+	activation: genesis.BlockNumber + 1,
+	blockTime:  2 * time.Minute,
+	window:     10,
+	median:     3,
+	average:    5,
+}
+
+// use the retargeting rules of a chain
+func setRetargetRules(t *testing.T, rules retargetRules) {
+	err := locked(func() error {
+		globalData.retarget = rules
+		return updateDifficulty()
+	})
+	if nil != err {
+		t.Fatalf("update difficulty error: %v", err)
+	}
+}
+
+// store blocks with the required difficulty at a fixed interval
+func storeTimedBlocks(t *testing.T, count int, interval uint64) {
+	for i := 0; i < count; i += 1 {
+		number := globalData.height + 1
+		header, packedBlock, txs := makeBlockAt(t, number, globalData.previousBlock, 0, nil)
+		header.Timestamp = retargetStartTime + number*interval
+		header.Difficulty.SetBits(globalData.difficulty)
+		copy(packedBlock, header.Pack())
+		testStoreBlock(header, packedBlock, txs)
+	}
+}
+
+// fast blocks raise the difficulty and slow blocks lower it
+func TestRetarget(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	setRetargetRules(t, testRetargetRules)
+	if difficulty.OneUint64 != globalData.difficulty {
+		t.Errorf("initial bits: %016x  expected: %016x", globalData.difficulty, difficulty.OneUint64)
+	}
+
+	storeTimedBlocks(t, 8, 30)
+
+	fast := difficulty.Current.Reciprocal()
+	if fast <= difficulty.MinimumReciprocal {
+		t.Errorf("fast blocks: reciprocal: %g  expected more than: %g", fast, difficulty.MinimumReciprocal)
+	}
+	if globalData.difficulty != difficulty.Current.Bits() {
+		t.Errorf("current: %016x  expected: %016x", difficulty.Current.Bits(), globalData.difficulty)
+	}
+
+	// recomputing from storage gives the same result
+	bits, err := expectedDifficulty(globalData.retarget, globalData.height+1)
+	if nil != err {
+		t.Fatalf("expected difficulty error: %v", err)
+	}
+	if globalData.difficulty != bits {
+		t.Errorf("recomputed: %016x  expected: %016x", bits, globalData.difficulty)
+	}
+
+	storeTimedBlocks(t, 8, 600)
+
+	slow := difficulty.Current.Reciprocal()
+	if slow >= fast || slow < difficulty.MinimumReciprocal {
+		t.Errorf("slow blocks: reciprocal: %g  expected: %g .. %g", slow, difficulty.MinimumReciprocal, fast)
+	}
+}
+
+// the required difficulty after each block stored at a fixed interval
+// from retargetStartTime, the values are from a separate calculation
+// with the same integer arithmetic
+func TestRetargetVector(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	setRetargetRules(t, testRetargetRules)
+
+	tests := []struct {
+		interval uint64
+		bits     uint64
+	}{
+		{30, 0x00ffffffffffffff},  // after block 2
+		{30, 0x00ffffffffffffff},  // after block 3
+		{30, 0x01ffffffffffffff},  // after block 4
+		{30, 0x016db6db6db6db6d},  // after block 5
+		{30, 0x02b0ad12073615a1},  // after block 6
+		{30, 0x0219d5b98a919d5b},  // after block 7
+		{30, 0x035e26e7e0e018e5},  // after block 8
+		{30, 0x04bf60ee9a18dab6},  // after block 9
+		{600, 0x04604b27ed3604b1}, // after block 10
+		{600, 0x04b45d1745d1745c}, // after block 11
+		{600, 0x032334073f6ae768}, // after block 12
+		{600, 0x03a24cf7a24cf7a1}, // after block 13
+		{600, 0x02511ade56e5ee37}, // after block 14
+		{600, 0x01145465b0775776}, // after block 15
+		{600, 0x016d94865c35915f}, // after block 16
+		{600, 0x01fd8772fe1717f6}, // after block 17
+	}
+
+	for i, test := range tests {
+		storeTimedBlocks(t, 1, test.interval)
+		if test.bits != globalData.difficulty {
+			t.Errorf("%d: height: %d  bits: %016x  expected: %016x", i, globalData.height, globalData.difficulty, test.bits)
+		}
+	}
+}
+
+// deleting blocks restores the difficulty for the new height
+func TestRetargetDelete(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	setRetargetRules(t, testRetargetRules)
+
+	required := make(map[uint64]uint64)
+	for i := 0; i < 6; i += 1 {
+		required[globalData.height] = globalData.difficulty
+		storeTimedBlocks(t, 1, 20)
+	}
+
+	err := DeleteDownToBlock(5)
+	if nil != err {
+		t.Fatalf("delete error: %v", err)
+	}
+	if 4 != globalData.height {
+		t.Fatalf("height: %d  expected: 4", globalData.height)
+	}
+	if required[4] != globalData.difficulty || required[4] != difficulty.Current.Bits() {
+		t.Errorf("bits: %016x  current: %016x  expected: %016x", globalData.difficulty, difficulty.Current.Bits(), required[4])
+	}
+}
+
+// incoming headers must carry the required difficulty once retargeting is active
+func TestRetargetValidate(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	setRetargetRules(t, testRetargetRules)
+	storeTimedBlocks(t, 5, 30)
+
+	header := blockrecord.New()
+	header.Version = blockrecord.Version
+	header.TransactionCount = 1
+	header.Number = globalData.height + 1
+	header.PreviousBlock = globalData.previousBlock
+	header.Timestamp = retargetStartTime + header.Number*30
+
+	header.Difficulty.SetBits(difficulty.OneUint64)
+	err := validateHeader(header)
+	if fault.ErrDifficultyDoesNotMatch != err {
+		t.Errorf("minimum difficulty: error: %v  expected: %v", err, fault.ErrDifficultyDoesNotMatch)
+	}
+
+	header.Difficulty.SetBits(globalData.difficulty)
+	err = validateHeader(header)
+	if nil != err {
+		t.Errorf("required difficulty: error: %v", err)
+	}
+
+	// before activation any difficulty is accepted
+	rules := testRetargetRules
+	rules.activation = header.Number + 1
+	setRetargetRules(t, rules)
+
+	header.Difficulty.SetBits(difficulty.OneUint64)
+	err = validateHeader(header)
+	if nil != err {
+		t.Errorf("before activation: error: %v", err)
+	}
+	if difficulty.OneUint64 != globalData.difficulty {
+		t.Errorf("before activation: bits: %016x  expected: %016x", globalData.difficulty, difficulty.OneUint64)
+	}
+}
+
+// only the local chain retargets from the start
+func TestRetargetRules(t *testing.T) {
+	tests := []struct {
+		chainName  string
+		activation uint64
+	}{
+		{chain.Bitmark, bitmarkActivationBlock},
+		{chain.Testing, testingActivationBlock},
+		{chain.Local, genesis.BlockNumber + 1},
+		{"unknown", bitmarkActivationBlock},
+	}
+
+	for i, test := range tests {
+		activation := retargetRulesFor(test.chainName).activation
+		if test.activation != activation {
+			t.Errorf("%d: %q activation: %d  expected: %d", i, test.chainName, activation, test.activation)
+		}
+	}
+}
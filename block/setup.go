@@ -18,6 +18,7 @@ import (
 	"github.com/bitmark-inc/bitmarkd/storage"
 	"github.com/bitmark-inc/bitmarkd/transactionrecord"
 	"github.com/bitmark-inc/logger"
+	"math/big"
 	"sync"
 )
 
@@ -29,6 +30,12 @@ type blockData struct {
 
 	height        uint64             // this is the current block Height
 	previousBlock blockdigest.Digest // and its digest
+	work          *big.Int           // total work of all blocks up to height
+
+	retarget      retargetRules     // difficulty adjustment for this chain
+	difficulty    uint64            // difficulty bits required for the next block
+	activation    tagActivation     // first block for each new transaction type
+	keyActivation keyTypeActivation // first block for each new account key type
 
 	blk blockstore // for sequencing block storage
 
@@ -59,6 +66,10 @@ func Initialise() error {
 	globalData.log = log
 	log.Info("starting…")
 
+	globalData.retarget = retargetRulesFor(mode.ChainName())
+	globalData.activation = tagActivationFor(mode.ChainName())
+	globalData.keyActivation = keyTypeActivationFor(mode.ChainName())
+
 	// check storage is initialised
 	if nil == storage.Pool.Blocks {
 		log.Critical("storage pool is not initialise")
@@ -80,6 +91,14 @@ func Initialise() error {
 		return err
 	}
 
+	// total work of the stored chain
+	work, err := chainWork(globalData.height)
+	if nil != err {
+		return err
+	}
+	globalData.work = work
+	log.Infof("chain work: %d", work)
+
 	// initialise background tasks
 	if err := globalData.blk.initialise(); nil != err {
 		return err
@@ -124,8 +143,13 @@ func Finalise() error {
 // must hold lock to call this
 func fillRingBuffer(log *logger.L) error {
 
-	// reset ring to default
+	// reset ring and block data to default
 	blockring.Clear(log)
+	globalData.height = genesis.BlockNumber
+	globalData.previousBlock = genesis.LiveGenesisDigest
+	if mode.IsTesting() {
+		globalData.previousBlock = genesis.TestGenesisDigest
+	}
 
 	// detect if any blocks on file
 	if last, ok := storage.Pool.Blocks.LastElement(); ok {
@@ -223,5 +247,7 @@ func fillRingBuffer(log *logger.L) error {
 			})
 		}
 	}
-	return nil
+
+	// difficulty for the next block
+	return updateDifficulty()
 }
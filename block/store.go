@@ -12,6 +12,8 @@ import (
 	"github.com/bitmark-inc/bitmarkd/blockring"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/notify"
 	"github.com/bitmark-inc/bitmarkd/reservoir"
 	"github.com/bitmark-inc/bitmarkd/storage"
 	"github.com/bitmark-inc/bitmarkd/transactionrecord"
@@ -26,23 +28,34 @@ func StoreIncoming(packedBlock []byte) error {
 	reservoir.Disable()
 	defer reservoir.Enable()
 
+	return storeIncoming(packedBlock)
+}
+
+// validate and store a block
+// hold lock and disable reservoir before calling this
+func storeIncoming(packedBlock []byte) error {
+
+	if mode.IsLight() {
+		return storeHeader(packedBlock)
+	}
+
+	if len(packedBlock) < blockrecord.TotalBlockSize {
+		return fault.ErrInvalidBlockHeader
+	}
+
 	packedHeader := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize])
 	header, err := packedHeader.Unpack()
 	if nil != err {
 		return err
 	}
 
-	if globalData.previousBlock != header.PreviousBlock {
-		return fault.ErrPreviousBlockDigestDoesNotMatch
+	err = validateHeader(header)
+	if nil != err {
+		return err
 	}
 
 	data := packedBlock[blockrecord.TotalBlockSize:]
 
-	type txn struct {
-		packed   transactionrecord.Packed
-		unpacked interface{}
-	}
-
 	txs := make([]txn, header.TransactionCount)
 	txIds := make([]merkle.Digest, header.TransactionCount)
 
@@ -53,9 +66,22 @@ func StoreIncoming(packedBlock []byte) error {
 			return err
 		}
 
+		// only the first transaction can be a base record
+		isBase := false
+		switch transaction.(type) {
+		case *transactionrecord.BaseData, *transactionrecord.BlockFoundation:
+			isBase = true
+		}
+		if 0 == i && !isBase {
+			return fault.ErrMissingBaseRecord
+		} else if 0 != i && isBase {
+			return fault.ErrUnexpectedBaseRecord
+		}
+
+		txIds[i] = merkle.NewDigest(data[:n])
+		txs[i].txId = txIds[i]
 		txs[i].packed = transactionrecord.Packed(data[:n])
 		txs[i].unpacked = transaction
-		txIds[i] = merkle.NewDigest(data[:n])
 		data = data[n:]
 	}
 
@@ -67,79 +93,215 @@ func StoreIncoming(packedBlock []byte) error {
 		return fault.ErrMerkleRootDoesNotMatch
 	}
 
+	// signatures, assets and links must all be valid
+	err = verifyTransactions(header.Number, txs)
+	if nil != err {
+		return err
+	}
+
+	// the most expensive check is done last
 	digest := packedHeader.Digest()
-	storeAndUpdate(header, digest, packedBlock)
+	err = header.ValidateProof(digest)
+	if nil != err {
+		return err
+	}
+
+	storeBlock(header, digest, packedBlock, txs)
+
+	return nil
+}
+
+// an unpacked transaction from a block
+type txn struct {
+	txId     merkle.Digest
+	packed   transactionrecord.Packed
+	unpacked interface{}
+}
+
+// write a validated block and all of its transactions
+//
+// all records are committed to storage as a single batch so that a
+// failure part way through a block leaves the database unchanged
+//
+// hold lock before calling this
+func storeBlock(header *blockrecord.Header, digest blockdigest.Digest, packedBlock []byte, txs []txn) {
+
+	batch := storage.NewBatch()
+
+	// remove confirmed items from the memory caches, their journal
+	// records are removed in the same batch as the block is stored
+	for _, item := range txs {
+		switch tx := item.unpacked.(type) {
+
+		case *transactionrecord.AssetData:
+			asset.Delete(tx.AssetIndex())
+
+		case *transactionrecord.BitmarkIssue:
+			reservoir.DeleteByTxId(batch, item.txId)
+
+		case *transactionrecord.BitmarkTransfer:
+			reservoir.DeleteByTxId(batch, item.txId)
+
+			// when deleting a pending it is possible that the tx id
+			// it was holding was different to this tx id
+			// i.e. it is a duplicate so it also must be removed
+			// to prevent the possibility of a double-spend
+			reservoir.DeleteByLink(batch, tx.Link)
+
+		case *transactionrecord.BitmarkTransferCountersigned:
+			reservoir.DeleteByTxId(batch, item.txId)
+			reservoir.DeleteByLink(batch, tx.Link)
+
+		case *transactionrecord.BitmarkBurn:
+			reservoir.DeleteByTxId(batch, item.txId)
+			reservoir.DeleteByLink(batch, tx.Link)
+
+		case *transactionrecord.BitmarkBatchTransfer:
+			reservoir.DeleteByTxId(batch, item.txId)
+			for _, link := range tx.Links {
+				reservoir.DeleteByLink(batch, link)
+			}
+		}
+	}
+
+	indexTransactions(batch, header.Number, txs)
+	events := ownerEvents(batch, header.Number, txs, false)
+	storeAndUpdate(batch, header, digest, packedBlock)
+
+	notify.Publish(events...)
+}
+
+// queue all the index records derived from the transactions of a block
+func indexTransactions(batch *storage.Batch, number uint64, txs []txn) {
 
-	// store transactions
-	for i, item := range txs {
-		txId := txIds[i]
+	blockNumber := make([]byte, 8)
+	binary.BigEndian.PutUint64(blockNumber, number)
+
+	// transactions follow the header without gaps
+	offset := uint64(blockrecord.TotalBlockSize)
+
+	for _, item := range txs {
+		txId := item.txId
 		packed := item.packed
+
+		location := make([]byte, 16)
+		copy(location, blockNumber)
+		binary.BigEndian.PutUint64(location[8:], offset)
+		offset += uint64(len(packed))
+
 		switch tx := item.unpacked.(type) {
 
 		case *transactionrecord.BaseData:
-			blockNumber := make([]byte, 8)
-			binary.BigEndian.PutUint64(blockNumber, header.Number)
-			data := make([]byte, 8, 8+len(tx.PaymentAddress))
-			binary.BigEndian.PutUint64(data[:8], tx.Currency.Uint64())
-			data = append(data, tx.PaymentAddress...)
-			storage.Pool.BlockOwners.Put(blockNumber, data)
+			data := transactionrecord.PackPaymentAddresses([]transactionrecord.PaymentAddress{
+				{
+					Currency: tx.Currency,
+					Address:  tx.PaymentAddress,
+				},
+			})
+			batch.Put(storage.Pool.BlockOwners, blockNumber, data)
 			// currently not stored separately
 
+		case *transactionrecord.BlockFoundation:
+			data := transactionrecord.PackPaymentAddresses(tx.Payments)
+			batch.Put(storage.Pool.BlockOwners, blockNumber, data)
+
 		case *transactionrecord.AssetData:
 			assetIndex := tx.AssetIndex()
 			key := assetIndex[:]
-			asset.Delete(assetIndex)
-			storage.Pool.Assets.Put(key, packed)
+			batch.Put(storage.Pool.Assets, key, packed)
+			for _, s := range assetSearchKeys(number, assetIndex, tx) {
+				batch.Put(s.pool, s.key, []byte{})
+			}
 
 		case *transactionrecord.BitmarkIssue:
 			key := txId[:]
-			reservoir.DeleteByTxId(txId)
-			storage.Pool.Transactions.Put(key, packed)
-			CreateOwnership(txId, header.Number, tx.AssetIndex, tx.Owner)
+			batch.Put(storage.Pool.Transactions, key, packed)
+			batch.Put(storage.Pool.TxBlock, key, location)
+			CreateOwnership(batch, txId, number, tx.AssetIndex, tx.Owner)
 
 		case *transactionrecord.BitmarkTransfer:
 			key := txId[:]
-			reservoir.DeleteByTxId(txId)
+			batch.Put(storage.Pool.Transactions, key, packed)
+			batch.Put(storage.Pool.TxBlock, key, location)
+			linkOwner := ownerOf(batch, tx.Link)
+			if nil == linkOwner {
+				fault.Criticalf("missing transaction record for link: %v refererenced by tx id: %v", tx.Link, txId)
+				fault.Panic("Transactions database is corrupt")
+			}
+			TransferOwnership(batch, tx.Link, txId, number, linkOwner, tx.Owner)
 
-			// when deleting a pending it is possible that the tx id
-			// it was holding was different to this tx id
-			// i.e. it is a duplicate so it also must be removed
-			// to prevent the possibility of a double-spend
-			reservoir.DeleteByLink(tx.Link)
+		case *transactionrecord.BitmarkTransferCountersigned:
+			key := txId[:]
+			batch.Put(storage.Pool.Transactions, key, packed)
+			batch.Put(storage.Pool.TxBlock, key, location)
+			linkOwner := ownerOf(batch, tx.Link)
+			if nil == linkOwner {
+				fault.Criticalf("missing transaction record for link: %v refererenced by tx id: %v", tx.Link, txId)
+				fault.Panic("Transactions database is corrupt")
+			}
+			TransferOwnership(batch, tx.Link, txId, number, linkOwner, tx.Owner)
 
-			storage.Pool.Transactions.Put(key, packed)
-			linkOwner := OwnerOf(tx.Link)
+		case *transactionrecord.BitmarkBurn:
+			key := txId[:]
+			batch.Put(storage.Pool.Transactions, key, packed)
+			batch.Put(storage.Pool.TxBlock, key, location)
+			linkOwner := ownerOf(batch, tx.Link)
 			if nil == linkOwner {
 				fault.Criticalf("missing transaction record for link: %v refererenced by tx id: %v", tx.Link, txId)
 				fault.Panic("Transactions database is corrupt")
 			}
-			TransferOwnership(tx.Link, txId, header.Number, linkOwner, tx.Owner)
+			// no new owner: only the ownership of the link is removed
+			TransferOwnership(batch, tx.Link, txId, number, linkOwner, nil)
+
+		case *transactionrecord.BitmarkBatchTransfer:
+			key := txId[:]
+			batch.Put(storage.Pool.Transactions, key, packed)
+			batch.Put(storage.Pool.TxBlock, key, location)
+			for _, link := range tx.Links {
+				linkOwner := ownerOf(batch, link)
+				if nil == linkOwner {
+					fault.Criticalf("missing transaction record for link: %v refererenced by tx id: %v", link, txId)
+					fault.Panic("Transactions database is corrupt")
+				}
+				// each bitmark continues from its own batch link id
+				batchLinkId := transactionrecord.BatchLinkId(txId, link)
+				batch.Put(storage.Pool.BatchLinks, batchLinkId[:], append(key, link[:]...))
+				TransferOwnership(batch, link, batchLinkId, number, linkOwner, tx.Owner)
+			}
 
 		default:
-			globalData.log.Criticalf("unhandled transaction: %v", tx)
+			fault.Criticalf("unhandled transaction: %v", tx)
 			fault.Panicf("unhandled transaction: %v", tx)
 		}
 	}
-
-	return nil
 }
 
 // store the block and update block data
 // hold lock before calling this
-func storeAndUpdate(header *blockrecord.Header, digest blockdigest.Digest, packedBlock []byte) {
+// the batch is committed before the height, work, ring and difficulty
+// are changed; the caller may already have removed items from the asset
+// cache and reservoir, a failure after that panics and the reservoir
+// is reloaded from its journal on restart
+func storeAndUpdate(batch *storage.Batch, header *blockrecord.Header, digest blockdigest.Digest, packedBlock []byte) {
 
 	expectedBlockNumber := globalData.height + 1
 	if expectedBlockNumber != header.Number {
 		fault.Panicf("block.Store: out of sequence block: actual: %d  expected: %d", header.Number, expectedBlockNumber)
 	}
 
+	blockNumber := make([]byte, 8)
+	binary.BigEndian.PutUint64(blockNumber, header.Number)
+
+	batch.Put(storage.Pool.Blocks, blockNumber, packedBlock)
+	batch.Put(storage.Pool.BlockNumbers, digest[:], blockNumber)
+	batch.Commit()
+
 	globalData.previousBlock = digest
 	globalData.height = header.Number
+	globalData.work.Add(globalData.work, header.Difficulty.Work())
 
 	blockring.Put(header.Number, digest, packedBlock)
 
-	blockNumber := make([]byte, 8)
-	binary.BigEndian.PutUint64(blockNumber, header.Number)
-
-	storage.Pool.Blocks.Put(blockNumber, packedBlock)
+	err := updateDifficulty()
+	fault.PanicIfError("block.storeAndUpdate: difficulty", err)
 }
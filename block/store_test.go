@@ -0,0 +1,163 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/blockdigest"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"testing"
+	"time"
+)
+
+// each invalid block must be rejected with a specific error
+func TestStoreIncomingRejects(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	base := genesisBase(t)
+	registrant := newTestOwner(t)
+	asset := signedPack(t, registrant, &transactionrecord.AssetData{
+		Name:        "Item's Name",
+		Fingerprint: "0123456789abcdef",
+		Metadata:    "description\x00Just the description",
+		Registrant:  registrant.account,
+	})
+	future := uint64(time.Now().Add(3 * time.Hour).Unix())
+
+	items := []struct {
+		title  string
+		modify func(*blockrecord.Header)
+		txs    []txn
+		err    error
+	}{
+		{
+			title:  "previous block",
+			modify: func(h *blockrecord.Header) { h.PreviousBlock = blockdigest.Digest{} },
+			txs:    []txn{base},
+			err:    fault.ErrPreviousBlockDigestDoesNotMatch,
+		},
+		{
+			title:  "number",
+			modify: func(h *blockrecord.Header) { h.Number += 1 },
+			txs:    []txn{base},
+			err:    fault.ErrBlockNumberDoesNotMatch,
+		},
+		{
+			title:  "version",
+			modify: func(h *blockrecord.Header) { h.Version = blockrecord.Version + 1 },
+			txs:    []txn{base},
+			err:    fault.ErrInvalidBlockVersion,
+		},
+		{
+			title:  "zero count",
+			modify: func(h *blockrecord.Header) { h.TransactionCount = 0 },
+			txs:    []txn{},
+			err:    fault.ErrTransactionCountOutOfRange,
+		},
+		{
+			title:  "excess count",
+			modify: func(h *blockrecord.Header) { h.TransactionCount = blockrecord.MaximumTransactions + 1 },
+			txs:    []txn{base},
+			err:    fault.ErrTransactionCountOutOfRange,
+		},
+		{
+			title:  "timestamp not after median",
+			modify: func(h *blockrecord.Header) { h.Timestamp -= 120 },
+			txs:    []txn{base},
+			err:    fault.ErrTimestampTooEarly,
+		},
+		{
+			title:  "timestamp in future",
+			modify: func(h *blockrecord.Header) { h.Timestamp = future },
+			txs:    []txn{base},
+			err:    fault.ErrTimestampTooFarInFuture,
+		},
+		{
+			title:  "missing base",
+			modify: func(h *blockrecord.Header) { h.TransactionCount = 2 },
+			txs:    []txn{asset, base},
+			err:    fault.ErrMissingBaseRecord,
+		},
+		{
+			title:  "second base",
+			modify: func(h *blockrecord.Header) { h.TransactionCount = 2 },
+			txs:    []txn{base, base},
+			err:    fault.ErrUnexpectedBaseRecord,
+		},
+		{
+			title:  "merkle root",
+			modify: func(h *blockrecord.Header) { h.MerkleRoot = merkle.NewDigest([]byte("wrong")) },
+			txs:    []txn{base},
+			err:    fault.ErrMerkleRootDoesNotMatch,
+		},
+		{
+			title:  "proof of work",
+			modify: func(h *blockrecord.Header) { h.Difficulty.SetBits(hardestBits) },
+			txs:    []txn{base, asset},
+			err:    fault.ErrDifficultyNotMet,
+		},
+	}
+
+	for i, item := range items {
+		header := nextHeader(t, item.txs)
+		item.modify(header)
+
+		err := StoreIncoming(packBlock(header, item.txs))
+		if item.err != err {
+			t.Errorf("%d: %s: error: %v  expected: %v", i, item.title, err, item.err)
+		}
+	}
+
+	// truncated header
+	err := StoreIncoming(genesis.LiveGenesisBlock[:blockrecord.TotalBlockSize-1])
+	if fault.ErrInvalidBlockHeader != err {
+		t.Errorf("short block: error: %v  expected: %v", err, fault.ErrInvalidBlockHeader)
+	}
+
+	// nothing was stored
+	if height := GetHeight(); genesis.BlockNumber != height {
+		t.Errorf("height: %d  expected: %d", height, genesis.BlockNumber)
+	}
+}
+
+// a block with a valid header, base record and proof of work is stored
+func TestStoreIncomingAccepts(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	// nonce found by search for the fixed header from nextHeader
+	const validNonce = 18
+
+	txs := []txn{genesisBase(t)}
+	header := nextHeader(t, txs)
+	header.Nonce = validNonce
+	packedBlock := packBlock(header, txs)
+
+	err := StoreIncoming(packedBlock)
+	if nil != err {
+		t.Fatalf("store error: %v", err)
+	}
+
+	if height := GetHeight(); header.Number != height {
+		t.Errorf("height: %d  expected: %d", height, header.Number)
+	}
+	digest, err := DigestForBlock(header.Number)
+	if nil != err {
+		t.Fatalf("digest for block error: %v", err)
+	}
+	if blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize]).Digest() != digest {
+		t.Errorf("digest: %v  does not match stored block", digest)
+	}
+
+	// the same block cannot be stored twice
+	err = StoreIncoming(packedBlock)
+	if fault.ErrPreviousBlockDigestDoesNotMatch != err {
+		t.Errorf("repeat: error: %v  expected: %v", err, fault.ErrPreviousBlockDigestDoesNotMatch)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"sort"
+	"time"
+)
+
+// timestamp rules
+const (
+	medianTimeBlocks      = 11            // number of previous blocks used to compute the median time
+	maximumTimestampDrift = 2 * time.Hour // how far a timestamp may be ahead of local time
+)
+
+// for sorting timestamps
+type timestampList []uint64
+
+func (t timestampList) Len() int           { return len(t) }
+func (t timestampList) Less(i, j int) bool { return t[i] < t[j] }
+func (t timestampList) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
+// check the header of an incoming block against the current chain
+// hold lock before calling this
+func validateHeader(header *blockrecord.Header) error {
+
+	if globalData.previousBlock != header.PreviousBlock {
+		return fault.ErrPreviousBlockDigestDoesNotMatch
+	}
+
+	if globalData.height+1 != header.Number {
+		return fault.ErrBlockNumberDoesNotMatch
+	}
+
+	if err := header.Validate(); nil != err {
+		return err
+	}
+
+	if header.Number >= globalData.retarget.activation && globalData.difficulty != header.Difficulty.Bits() {
+		return fault.ErrDifficultyDoesNotMatch
+	}
+
+	limit := uint64(time.Now().Add(maximumTimestampDrift).Unix())
+	if header.Timestamp > limit {
+		return fault.ErrTimestampTooFarInFuture
+	}
+
+	median, err := medianTimestamp(header.Number)
+	if nil != err {
+		return err
+	}
+	if header.Timestamp <= median {
+		return fault.ErrTimestampTooEarly
+	}
+
+	return nil
+}
+
+// median of the timestamps of the blocks immediately preceding a block number
+// hold lock before calling this
+func medianTimestamp(number uint64) (uint64, error) {
+
+	first := genesis.BlockNumber
+	if number > first+medianTimeBlocks {
+		first = number - medianTimeBlocks
+	}
+
+	timestamps := make([]uint64, 0, medianTimeBlocks)
+	for n := first; n < number; n += 1 {
+		header, err := headerForBlock(n)
+		if nil != err {
+			return 0, err
+		}
+		timestamps = append(timestamps, header.Timestamp)
+	}
+
+	if 0 == len(timestamps) {
+		return 0, fault.ErrBlockNotFound
+	}
+
+	sort.Sort(timestampList(timestamps))
+	return timestamps[len(timestamps)/2], nil
+}
+
+// fetch a stored block
+// the genesis block is not in storage so it is taken from the genesis package
+func packedBlockFor(number uint64) []byte {
+	if number <= genesis.BlockNumber {
+		if mode.IsTesting() {
+			return genesis.TestGenesisBlock
+		}
+		return genesis.LiveGenesisBlock
+	}
+	n := make([]byte, 8)
+	binary.BigEndian.PutUint64(n, number)
+	return storage.Pool.Blocks.Get(n)
+}
+
+// fetch and unpack the header of a stored block
+func headerForBlock(number uint64) (*blockrecord.Header, error) {
+
+	packed := packedBlockFor(number)
+	if len(packed) < blockrecord.TotalBlockSize {
+		return nil, fault.ErrBlockNotFound
+	}
+	return blockrecord.PackedHeader(packed[:blockrecord.TotalBlockSize]).Unpack()
+}
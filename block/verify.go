@@ -0,0 +1,202 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"bytes"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+)
+
+// check all transactions of an incoming block before anything is written
+//
+// this applies the same rules as the reservoir: every signature is
+// checked, an asset can only be registered once, issues must refer to
+// an existing asset and transfers and burns must be signed by the
+// current owner of an unspent link; the earlier
+// transactions of the same block are taken into account so that an
+// asset or issue may be used in the block that creates it, but a link
+// can only be spent once; new transaction types and accounts of new
+// key types are only accepted from their activation block
+//
+// hold lock before calling this
+func verifyTransactions(number uint64, txs []txn) error {
+
+	assets := make(map[transactionrecord.AssetIndex]struct{}) // registered in this block
+	unspent := make(map[merkle.Digest]*account.Account)       // created in this block and not yet transferred
+	spent := make(map[merkle.Digest]struct{})                 // links transferred in this block
+	seen := make(map[merkle.Digest]struct{})                  // all tx ids in this block
+
+	for _, item := range txs {
+
+		if _, ok := seen[item.txId]; ok {
+			return fault.ErrTransactionAlreadyExists
+		}
+		seen[item.txId] = struct{}{}
+
+		if !IsTagActive(item.packed.Type(), number) {
+			return fault.ErrTransactionTypeNotActive
+		}
+		err := CheckAccounts(item.unpacked, number)
+		if nil != err {
+			return err
+		}
+
+		switch tx := item.unpacked.(type) {
+
+		case *transactionrecord.BaseData:
+			_, err := tx.Pack(tx.Owner)
+			if nil != err {
+				return err
+			}
+
+		case *transactionrecord.BlockFoundation:
+			_, err := tx.Pack(tx.Owner)
+			if nil != err {
+				return err
+			}
+
+		case *transactionrecord.AssetData:
+			_, err := tx.Pack(tx.Registrant)
+			if nil != err {
+				return err
+			}
+			assetIndex := tx.AssetIndex()
+			if _, ok := assets[assetIndex]; ok || storage.Pool.Assets.Has(assetIndex[:]) {
+				return fault.ErrAssetsAlreadyRegistered
+			}
+			assets[assetIndex] = struct{}{}
+
+		case *transactionrecord.BitmarkIssue:
+			_, err := tx.Pack(tx.Owner)
+			if nil != err {
+				return err
+			}
+			if _, ok := assets[tx.AssetIndex]; !ok && !storage.Pool.Assets.Has(tx.AssetIndex[:]) {
+				return fault.ErrAssetNotFound
+			}
+			if storage.Pool.Transactions.Has(item.txId[:]) {
+				return fault.ErrTransactionAlreadyExists
+			}
+			unspent[item.txId] = tx.Owner
+
+		case *transactionrecord.BitmarkTransfer:
+			currentOwner, err := unspentOwner(tx.Link, spent, unspent)
+			if nil != err {
+				return err
+			}
+
+			_, err = tx.Pack(currentOwner)
+			if nil != err {
+				return err
+			}
+			if storage.Pool.Transactions.Has(item.txId[:]) {
+				return fault.ErrTransactionAlreadyExists
+			}
+
+			spent[tx.Link] = struct{}{}
+			delete(unspent, tx.Link)
+			unspent[item.txId] = tx.Owner
+
+		case *transactionrecord.BitmarkTransferCountersigned:
+			currentOwner, err := unspentOwner(tx.Link, spent, unspent)
+			if nil != err {
+				return err
+			}
+
+			_, err = tx.Pack(currentOwner)
+			if nil != err {
+				return err
+			}
+			if storage.Pool.Transactions.Has(item.txId[:]) {
+				return fault.ErrTransactionAlreadyExists
+			}
+
+			spent[tx.Link] = struct{}{}
+			delete(unspent, tx.Link)
+			unspent[item.txId] = tx.Owner
+
+		case *transactionrecord.BitmarkBurn:
+			currentOwner, err := unspentOwner(tx.Link, spent, unspent)
+			if nil != err {
+				return err
+			}
+
+			_, err = tx.Pack(currentOwner)
+			if nil != err {
+				return err
+			}
+			if storage.Pool.Transactions.Has(item.txId[:]) {
+				return fault.ErrTransactionAlreadyExists
+			}
+
+			// nothing is created, so the bitmark ends here
+			spent[tx.Link] = struct{}{}
+			delete(unspent, tx.Link)
+
+		case *transactionrecord.BitmarkBatchTransfer:
+			var currentOwner *account.Account
+			for _, link := range tx.Links {
+				owner, err := unspentOwner(link, spent, unspent)
+				if nil != err {
+					return err
+				}
+				if nil == currentOwner {
+					currentOwner = owner
+				} else if !bytes.Equal(currentOwner.Bytes(), owner.Bytes()) {
+					return fault.ErrBatchOwnersDiffer
+				}
+			}
+
+			_, err := tx.Pack(currentOwner)
+			if nil != err {
+				return err
+			}
+			if storage.Pool.Transactions.Has(item.txId[:]) {
+				return fault.ErrTransactionAlreadyExists
+			}
+
+			for _, link := range tx.Links {
+				spent[link] = struct{}{}
+				delete(unspent, link)
+				unspent[transactionrecord.BatchLinkId(item.txId, link)] = tx.Owner
+			}
+
+		default:
+			return fault.ErrTransactionIsNotAnIssueOrATransfer
+		}
+	}
+	return nil
+}
+
+// the owner of a link that has not been spent by this block
+func unspentOwner(link merkle.Digest, spent map[merkle.Digest]struct{}, unspent map[merkle.Digest]*account.Account) (*account.Account, error) {
+	if _, ok := spent[link]; ok {
+		return nil, fault.ErrDoubleTransferAttempt
+	}
+	if currentOwner, ok := unspent[link]; ok {
+		return currentOwner, nil
+	}
+	return confirmedOwner(link)
+}
+
+// the owner of a confirmed issue or transfer that has not yet been transferred
+func confirmedOwner(link merkle.Digest) (*account.Account, error) {
+
+	owner := ownerOf(nil, link)
+	if nil == owner {
+		return nil, fault.ErrLinkToInvalidOrUnconfirmedTransaction
+	}
+
+	// only the latest transaction of a bitmark has an owner digest record
+	dKey := append(owner.Bytes(), link[:]...)
+	if !storage.Pool.OwnerDigest.Has(dKey) {
+		return nil, fault.ErrDoubleTransferAttempt
+	}
+	return owner, nil
+}
@@ -0,0 +1,362 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/chain"
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"testing"
+)
+
+// incoming blocks with invalid transactions are rejected before anything is written
+func TestVerifyTransactions(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	thief := newTestOwner(t)
+
+	registered := &transactionrecord.AssetData{
+		Name:        "registered",
+		Fingerprint: "fingerprint 1",
+		Metadata:    "",
+		Registrant:  owner.account,
+	}
+	asset := signedPack(t, owner, registered)
+
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: registered.AssetIndex(),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+
+	// confirm the asset and issue so later blocks can refer to them
+	header, packedBlock, txs := makeTestBlock(t, []txn{asset, issue})
+	testStoreBlock(header, packedBlock, txs)
+	next := globalData.height + 1
+	previous := globalData.previousBlock
+
+	transfer := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  issue.txId,
+		Owner: newOwner.account,
+	})
+
+	// the signature is the final field of a packed transfer
+	forged := transfer
+	forged.packed = append(transactionrecord.Packed{}, transfer.packed...)
+	forged.packed[len(forged.packed)-1] ^= 0x01
+	forged.txId = forged.packed.MakeLink()
+
+	tests := []struct {
+		title string
+		txs   func() []txn
+		err   error
+	}{
+		{
+			title: "transfer signed by other than the owner",
+			txs: func() []txn {
+				return []txn{signedPack(t, thief, &transactionrecord.BitmarkTransfer{
+					Link:  issue.txId,
+					Owner: thief.account,
+				})}
+			},
+			err: fault.ErrInvalidSignature,
+		},
+		{
+			title: "transfer with corrupt signature",
+			txs:   func() []txn { return []txn{forged} },
+			err:   fault.ErrInvalidSignature,
+		},
+		{
+			title: "transfer of unconfirmed link",
+			txs: func() []txn {
+				return []txn{signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+					Link:  merkle.NewDigest([]byte("no such transaction")),
+					Owner: newOwner.account,
+				})}
+			},
+			err: fault.ErrLinkToInvalidOrUnconfirmedTransaction,
+		},
+		{
+			title: "two transfers of one link",
+			txs: func() []txn {
+				return []txn{transfer, signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+					Link:  issue.txId,
+					Owner: thief.account,
+				})}
+			},
+			err: fault.ErrDoubleTransferAttempt,
+		},
+		{
+			title: "countersigned transfer with corrupt countersignature",
+			txs: func() []txn {
+				forged := countersignedPack(t, owner, newOwner, &transactionrecord.BitmarkTransferCountersigned{
+					Link:  issue.txId,
+					Owner: newOwner.account,
+				})
+				forged.packed[len(forged.packed)-1] ^= 0x01
+				forged.txId = forged.packed.MakeLink()
+				return []txn{forged}
+			},
+			err: fault.ErrInvalidCountersignature,
+		},
+		{
+			title: "burn signed by other than the owner",
+			txs: func() []txn {
+				return []txn{signedPack(t, thief, &transactionrecord.BitmarkBurn{
+					Link: issue.txId,
+				})}
+			},
+			err: fault.ErrInvalidSignature,
+		},
+		{
+			title: "transfer after burn",
+			txs: func() []txn {
+				return []txn{signedPack(t, owner, &transactionrecord.BitmarkBurn{
+					Link: issue.txId,
+				}), transfer}
+			},
+			err: fault.ErrDoubleTransferAttempt,
+		},
+		{
+			title: "batch transfer of links with different owners",
+			txs: func() []txn {
+				other := signedPack(t, thief, &transactionrecord.BitmarkIssue{
+					AssetIndex: registered.AssetIndex(),
+					Owner:      thief.account,
+					Nonce:      3,
+				})
+				return []txn{other, signedPack(t, owner, &transactionrecord.BitmarkBatchTransfer{
+					Links: []merkle.Digest{issue.txId, other.txId},
+					Owner: newOwner.account,
+				})}
+			},
+			err: fault.ErrBatchOwnersDiffer,
+		},
+		{
+			title: "transfer after batch transfer",
+			txs: func() []txn {
+				return []txn{signedPack(t, owner, &transactionrecord.BitmarkBatchTransfer{
+					Links: []merkle.Digest{issue.txId},
+					Owner: newOwner.account,
+				}), transfer}
+			},
+			err: fault.ErrDoubleTransferAttempt,
+		},
+		{
+			title: "issue of unregistered asset",
+			txs: func() []txn {
+				return []txn{signedPack(t, owner, &transactionrecord.BitmarkIssue{
+					AssetIndex: transactionrecord.NewAssetIndex([]byte("unregistered")),
+					Owner:      owner.account,
+					Nonce:      2,
+				})}
+			},
+			err: fault.ErrAssetNotFound,
+		},
+		{
+			title: "asset already confirmed",
+			txs:   func() []txn { return []txn{asset} },
+			err:   fault.ErrAssetsAlreadyRegistered,
+		},
+		{
+			title: "asset registered twice in block",
+			txs: func() []txn {
+				first := signedPack(t, owner, &transactionrecord.AssetData{
+					Name:        "twice",
+					Fingerprint: "fingerprint 3",
+					Metadata:    "",
+					Registrant:  owner.account,
+				})
+				second := signedPack(t, thief, &transactionrecord.AssetData{
+					Name:        "twice",
+					Fingerprint: "fingerprint 3",
+					Metadata:    "",
+					Registrant:  thief.account,
+				})
+				return []txn{first, second}
+			},
+			err: fault.ErrAssetsAlreadyRegistered,
+		},
+		{
+			title: "issue already confirmed",
+			txs:   func() []txn { return []txn{issue} },
+			err:   fault.ErrTransactionAlreadyExists,
+		},
+		{
+			title: "transaction repeated in block",
+			txs:   func() []txn { return []txn{transfer, transfer} },
+			err:   fault.ErrTransactionAlreadyExists,
+		},
+	}
+
+	for i, test := range tests {
+		header, _, txs := makeBlockAt(t, next, previous, 0, test.txs())
+		err := testStoreIncoming(header, txs)
+		if test.err != err {
+			t.Errorf("%d: %s: error: %v  expected: %v", i, test.title, err, test.err)
+		}
+		if next-1 != globalData.height {
+			t.Fatalf("%d: %s: block was stored", i, test.title)
+		}
+	}
+
+	if OwnerOf(issue.txId).String() != owner.account.String() {
+		t.Errorf("owner changed by a rejected block")
+	}
+}
+
+// assets and bitmarks created earlier in a block can be used later in the same block
+func TestVerifyWithinBlock(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+	finalOwner := newTestOwner(t)
+
+	registered := &transactionrecord.AssetData{
+		Name:        "in block",
+		Fingerprint: "fingerprint 2",
+		Metadata:    "",
+		Registrant:  owner.account,
+	}
+	asset := signedPack(t, owner, registered)
+	issue := signedPack(t, owner, &transactionrecord.BitmarkIssue{
+		AssetIndex: registered.AssetIndex(),
+		Owner:      owner.account,
+		Nonce:      1,
+	})
+	transfer := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  issue.txId,
+		Owner: newOwner.account,
+	})
+	second := signedPack(t, newOwner, &transactionrecord.BitmarkTransfer{
+		Link:  transfer.txId,
+		Owner: finalOwner.account,
+	})
+
+	_, _, txs := makeTestBlock(t, []txn{asset, issue, transfer, second})
+	err := verifyTransactions(genesis.BlockNumber+1, txs)
+	if nil != err {
+		t.Fatalf("verify error: %v", err)
+	}
+
+	// the old owner can no longer transfer
+	stale := signedPack(t, owner, &transactionrecord.BitmarkTransfer{
+		Link:  transfer.txId,
+		Owner: owner.account,
+	})
+	_, _, txs = makeTestBlock(t, []txn{asset, issue, transfer, stale})
+	err = verifyTransactions(genesis.BlockNumber+1, txs)
+	if fault.ErrInvalidSignature != err {
+		t.Errorf("stale owner error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+
+	// a valid block only fails at the proof of work
+	header, _, txs := makeTestBlock(t, []txn{asset, issue, transfer, second})
+	err = testStoreIncoming(header, txs)
+	if fault.ErrDifficultyNotMet != err {
+		t.Errorf("valid block error: %v  expected: %v", err, fault.ErrDifficultyNotMet)
+	}
+	checkEmpty(t, "valid block")
+}
+
+// new transaction types are rejected before their activation block
+func TestVerifyActivation(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	globalData.activation = tagActivationFor(chain.Bitmark)
+
+	owner := newTestOwner(t)
+
+	foundation := signedPack(t, owner, &transactionrecord.BlockFoundation{
+		Payments: []transactionrecord.PaymentAddress{
+			{Currency: currency.Bitcoin, Address: "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"},
+		},
+		Owner: owner.account,
+		Nonce: 1,
+	})
+	burn := signedPack(t, owner, &transactionrecord.BitmarkBurn{
+		Link: merkle.NewDigest([]byte("no such transaction")),
+	})
+
+	tests := []struct {
+		number uint64
+		txs    []txn
+		err    error
+	}{
+		{genesis.BlockNumber + 1, []txn{foundation}, fault.ErrTransactionTypeNotActive},
+		{bitmarkActivationBlock - 1, []txn{foundation}, fault.ErrTransactionTypeNotActive},
+		{bitmarkActivationBlock, []txn{foundation}, nil},
+		{bitmarkActivationBlock - 1, []txn{genesisBase(t), burn}, fault.ErrTransactionTypeNotActive},
+		{bitmarkActivationBlock, []txn{genesisBase(t), burn}, fault.ErrLinkToInvalidOrUnconfirmedTransaction},
+	}
+
+	for i, test := range tests {
+		err := verifyTransactions(test.number, test.txs)
+		if test.err != err {
+			t.Errorf("%d: block: %d  error: %v  expected: %v", i, test.number, err, test.err)
+		}
+	}
+}
+
+// accounts of new key types are rejected before their activation block
+func TestVerifyKeyTypeActivation(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	globalData.keyActivation = keyTypeActivationFor(chain.Bitmark)
+
+	privateKey, err := account.PrivateKeyFromBase58("26jeSzNAiE28dwr9uEHcAzDoyz9z6SSxHAg2s3YA2nNbhcn9isS")
+	if nil != err {
+		t.Fatalf("private key error: %v", err)
+	}
+	registrant := privateKey.Account()
+
+	record := &transactionrecord.AssetData{
+		Name:        "ECDSA asset",
+		Fingerprint: "0123456789abcdef",
+		Metadata:    "description\x00an asset with an ECDSA registrant",
+		Registrant:  registrant,
+	}
+	message, _ := record.Pack(registrant)
+	record.Signature, err = privateKey.PrivateKeyInterface.(*account.ECDSAP256PrivateKey).Sign(message)
+	if nil != err {
+		t.Fatalf("sign error: %v", err)
+	}
+	packed, err := record.Pack(registrant)
+	if nil != err {
+		t.Fatalf("pack error: %v", err)
+	}
+	asset := txn{
+		txId:     packed.MakeLink(),
+		packed:   packed,
+		unpacked: record,
+	}
+
+	tests := []struct {
+		number uint64
+		err    error
+	}{
+		{genesis.BlockNumber + 1, fault.ErrKeyTypeNotActive},
+		{bitmarkActivationBlock - 1, fault.ErrKeyTypeNotActive},
+		{bitmarkActivationBlock, nil},
+	}
+
+	for i, test := range tests {
+		err := verifyTransactions(test.number, []txn{genesisBase(t), asset})
+		if test.err != err {
+			t.Errorf("%d: block: %d  error: %v  expected: %v", i, test.number, err, test.err)
+		}
+	}
+}
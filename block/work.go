@@ -0,0 +1,76 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package block
+
+import (
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"math/big"
+)
+
+// get the total work of the current chain
+func GetWork() *big.Int {
+	globalData.Lock()
+	defer globalData.Unlock()
+	return new(big.Int).Set(globalData.work)
+}
+
+// get the current height and the total work up to that height
+func GetHeightAndWork() (uint64, *big.Int) {
+	globalData.Lock()
+	defer globalData.Unlock()
+	return globalData.height, new(big.Int).Set(globalData.work)
+}
+
+// total work of the genesis block and all stored blocks up to height
+func chainWork(height uint64) (*big.Int, error) {
+
+	header, err := headerForBlock(genesis.BlockNumber)
+	if nil != err {
+		return nil, err
+	}
+	work := header.Difficulty.Work()
+
+	stored, err := workOfBlocks(genesis.BlockNumber+1, height)
+	if nil != err {
+		return nil, err
+	}
+	return work.Add(work, stored), nil
+}
+
+// total work of the stored blocks: first..last inclusive
+func workOfBlocks(first uint64, last uint64) (*big.Int, error) {
+
+	work := big.NewInt(0)
+	if first > last {
+		return work, nil
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, first)
+	cursor := storage.Pool.Blocks.NewFetchCursor().Seek(key)
+
+	for {
+		items, err := cursor.Fetch(fetchCount)
+		if nil != err {
+			return nil, err
+		}
+		for _, item := range items {
+			if binary.BigEndian.Uint64(item.Key) > last {
+				return work, nil
+			}
+			header, err := blockrecord.PackedHeader(item.Value[:blockrecord.TotalBlockSize]).Unpack()
+			if nil != err {
+				return nil, err
+			}
+			work.Add(work, header.Difficulty.Work())
+		}
+		if len(items) < fetchCount {
+			return work, nil
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockrecord
+
+import (
+	"github.com/bitmark-inc/bitmarkd/blockdigest"
+	"github.com/bitmark-inc/bitmarkd/fault"
+)
+
+// lowest block version that is still accepted
+const (
+	MinimumVersion = 1
+)
+
+// check the header fields that do not depend on any previous block
+func (header *Header) Validate() error {
+
+	if header.Version < MinimumVersion || header.Version > Version {
+		return fault.ErrInvalidBlockVersion
+	}
+
+	// must at least contain the base record
+	if 0 == header.TransactionCount || header.TransactionCount > MaximumTransactions {
+		return fault.ErrTransactionCountOutOfRange
+	}
+
+	return nil
+}
+
+// check that the digest of the packed header meets the difficulty
+// stored in that header
+func (header *Header) ValidateProof(digest blockdigest.Digest) error {
+	if nil == header.Difficulty || digest.Cmp(header.Difficulty.BigInt()) > 0 {
+		return fault.ErrDifficultyNotMet
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockrecord_test
+
+import (
+	"github.com/bitmark-inc/bitmarkd/blockdigest"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"testing"
+)
+
+// unpack the header of a genesis block
+func unpackGenesis(t *testing.T, block []byte) *blockrecord.Header {
+	header, err := blockrecord.PackedHeader(block[:blockrecord.TotalBlockSize]).Unpack()
+	if nil != err {
+		t.Fatalf("unpack genesis header error: %v", err)
+	}
+	return header
+}
+
+// both genesis blocks must pass validation
+func TestValidateGenesis(t *testing.T) {
+
+	blocks := [][]byte{
+		genesis.LiveGenesisBlock,
+		genesis.TestGenesisBlock,
+	}
+
+	for i, block := range blocks {
+		header := unpackGenesis(t, block)
+
+		err := header.Validate()
+		if nil != err {
+			t.Errorf("%d: validate error: %v", i, err)
+		}
+
+		digest := blockrecord.PackedHeader(block).Digest()
+		err = header.ValidateProof(digest)
+		if nil != err {
+			t.Errorf("%d: validate proof error: %v", i, err)
+		}
+	}
+}
+
+// invalid header fields are detected
+func TestValidateInvalidFields(t *testing.T) {
+
+	items := []struct {
+		version          uint16
+		transactionCount uint16
+		err              error
+	}{
+		{0, 1, fault.ErrInvalidBlockVersion},
+		{blockrecord.Version + 1, 1, fault.ErrInvalidBlockVersion},
+		{blockrecord.Version, 0, fault.ErrTransactionCountOutOfRange},
+		{blockrecord.Version, blockrecord.MaximumTransactions + 1, fault.ErrTransactionCountOutOfRange},
+		{blockrecord.Version, blockrecord.MaximumTransactions, nil},
+	}
+
+	for i, item := range items {
+		header := unpackGenesis(t, genesis.LiveGenesisBlock)
+		header.Version = item.version
+		header.TransactionCount = item.transactionCount
+
+		err := header.Validate()
+		if item.err != err {
+			t.Errorf("%d: validate error: %v  expected: %v", i, err, item.err)
+		}
+	}
+}
+
+// the proof check compares the digest against the target of the difficulty
+func TestValidateProof(t *testing.T) {
+
+	// the largest digest is above every target
+	var largest blockdigest.Digest
+	for i := range largest {
+		largest[i] = 0xff
+	}
+	header := unpackGenesis(t, genesis.TestGenesisBlock)
+	err := header.ValidateProof(largest)
+	if fault.ErrDifficultyNotMet != err {
+		t.Errorf("largest digest: error: %v  expected: %v", err, fault.ErrDifficultyNotMet)
+	}
+
+	// the zero digest meets every difficulty
+	err = header.ValidateProof(blockdigest.Digest{})
+	if nil != err {
+		t.Errorf("zero digest: error: %v", err)
+	}
+
+	// no difficulty
+	header.Difficulty = nil
+	err = header.ValidateProof(blockdigest.Digest{})
+	if fault.ErrDifficultyNotMet != err {
+		t.Errorf("no difficulty: error: %v  expected: %v", err, fault.ErrDifficultyNotMet)
+	}
+}
@@ -0,0 +1,12 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Offline transaction builder and signer for the bitmark system
+//
+// This program builds asset, issue and transfer records from command
+// options or JSON, signs them with the key from a seed or private key
+// file and prints the JSON RPC arguments together with the packed
+// records.  Optionally the records are submitted to a bitmarkd and
+// the pay-nonce proof for free issues is solved and submitted.
+package main
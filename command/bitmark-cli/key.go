@@ -0,0 +1,108 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/keyfile"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"golang.org/x/crypto/ed25519"
+	"strings"
+)
+
+// tags for the key file data, the same as the proofer signing key
+const (
+	taggedSeed    = "SEED:"    // followed by base58 encoded seed or its recovery phrase
+	taggedPrivate = "PRIVATE:" // followed by hex of: key variant(varint) ++ private key
+)
+
+// any record that can be signed by this program
+type packer interface {
+	Pack(address *account.Account) (transactionrecord.Packed, error)
+}
+
+// read the signing key from a plain or encrypted key file
+//
+// a seed can be followed by a derivation path to select one of its
+// children
+func readPrivateKey(filename string, path string) (*account.PrivateKey, error) {
+
+	data, err := keyfile.Read(filename)
+	if nil != err {
+		return nil, err
+	}
+	s := strings.TrimSpace(string(data))
+
+	var privateKey *account.PrivateKey
+	if strings.HasPrefix(s, taggedSeed) {
+		seed := strings.TrimSpace(s[len(taggedSeed):])
+		if strings.ContainsAny(seed, " \t\n") {
+			seed, err = account.Base58SeedFromMnemonic(seed)
+			if nil != err {
+				return nil, err
+			}
+		}
+		if "" == path {
+			privateKey, err = account.PrivateKeyFromBase58Seed(seed)
+		} else {
+			privateKey, err = account.DeriveChild(seed, path)
+		}
+	} else if strings.HasPrefix(s, taggedPrivate) {
+		if "" != path {
+			return nil, fault.ErrInvalidDerivationPath
+		}
+		b, decodeErr := hex.DecodeString(s[len(taggedPrivate):])
+		if nil != decodeErr {
+			return nil, decodeErr
+		}
+		privateKey, err = account.PrivateKeyFromBytes(b)
+	} else {
+		return nil, fault.ErrInvalidPrivateKeyFile
+	}
+	if nil != err {
+		return nil, err
+	}
+
+	// a seed carries its own network, so ensure it is the selected one
+	if _, err := account.AccountFromBytes(privateKey.Account().Bytes()); nil != err {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// sign a message with any of the supported key types
+func sign(privateKey *account.PrivateKey, message []byte) (account.Signature, error) {
+	switch key := privateKey.PrivateKeyInterface.(type) {
+	case *account.ED25519PrivateKey:
+		return ed25519.Sign(key.PrivateKey, message), nil
+	case *account.ECDSAP256PrivateKey:
+		return key.Sign(message)
+	default:
+		return nil, fault.ErrInvalidKeyType
+	}
+}
+
+// sign a record and return its packed form
+//
+// packing without a signature gives the message to be signed, the
+// record is then packed again to verify the new signature
+func signRecord(record packer, signature *account.Signature, privateKey *account.PrivateKey) (transactionrecord.Packed, error) {
+
+	signer := privateKey.Account()
+
+	*signature = nil
+	message, err := record.Pack(signer)
+	if nil == message {
+		return nil, err
+	}
+
+	*signature, err = sign(privateKey, message)
+	if nil != err {
+		return nil, err
+	}
+	return record.Pack(signer)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/bitmark-inc/bitmarkd/chain"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/version"
+	"github.com/bitmark-inc/exitwithstatus"
+	"github.com/bitmark-inc/getoptions"
+	"io/ioutil"
+	"os"
+)
+
+// bitmark-cli main program
+func main() {
+	// ensure exit handler is first
+	defer exitwithstatus.Handler()
+
+	flags := []getoptions.Option{
+		{Long: "help", HasArg: getoptions.NO_ARGUMENT, Short: 'h'},
+		{Long: "version", HasArg: getoptions.NO_ARGUMENT, Short: 'V'},
+		{Long: "network", HasArg: getoptions.REQUIRED_ARGUMENT, Short: 'n'},
+		{Long: "key-file", HasArg: getoptions.REQUIRED_ARGUMENT, Short: 'k'},
+		{Long: "derivation-path", HasArg: getoptions.REQUIRED_ARGUMENT, Short: 'd'},
+		{Long: "json", HasArg: getoptions.REQUIRED_ARGUMENT, Short: 'j'},
+		{Long: "connect", HasArg: getoptions.REQUIRED_ARGUMENT, Short: 'c'},
+		{Long: "rpc-fingerprint", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "ca-file", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "insecure", HasArg: getoptions.NO_ARGUMENT},
+		{Long: "name", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "fingerprint", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "metadata", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "asset", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "quantity", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "nonce", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "link", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "owner", HasArg: getoptions.REQUIRED_ARGUMENT},
+		{Long: "payment", HasArg: getoptions.REQUIRED_ARGUMENT},
+	}
+
+	program, options, arguments, err := getoptions.GetOS(flags)
+	if nil != err {
+		exitwithstatus.Message("%s: getoptions error: %v", program, err)
+	}
+
+	if len(options["version"]) > 0 {
+		exitwithstatus.Message("%s: version: %s", program, version.Version)
+	}
+
+	if len(options["help"]) > 0 || 1 != len(arguments) {
+		exitwithstatus.Message("usage: %s [--help] [--network=NET] --key-file=FILE [--derivation-path=PATH] [--json=FILE] [--connect=HOST:PORT [TLS options]] [asset|issue|transfer] options...\n"+
+			"  asset     --name=NAME --fingerprint=TEXT [--metadata=KEY=VALUE...]\n"+
+			"  issue     (--asset=INDEX | asset options) [--quantity=N | --nonce=N]\n"+
+			"  transfer  --link=TXID --owner=ACCOUNT [--payment=CURRENCY:ADDRESS:AMOUNT]\n"+
+			"  --json reads the record from a file (- for stdin), options override its fields\n"+
+			"  TLS options: --rpc-fingerprint=HEX | --ca-file=FILE | --insecure", program)
+	}
+
+	network := chain.Bitmark
+	if s, ok := lastOption(options, "network"); ok {
+		network = s
+	}
+	if err := mode.Initialise(network); nil != err {
+		exitwithstatus.Message("%s: network: %q  error: %v", program, network, err)
+	}
+
+	keyFile, ok := lastOption(options, "key-file")
+	if !ok {
+		exitwithstatus.Message("%s: a key-file option is required", program)
+	}
+	path, _ := lastOption(options, "derivation-path")
+	privateKey, err := readPrivateKey(keyFile, path)
+	if nil != err {
+		exitwithstatus.Message("%s: key file: %q  error: %v", program, keyFile, err)
+	}
+
+	var input []byte
+	if s, ok := lastOption(options, "json"); ok {
+		if "-" == s {
+			input, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			input, err = ioutil.ReadFile(s)
+		}
+		if nil != err {
+			exitwithstatus.Message("%s: json file: %q  error: %v", program, s, err)
+		}
+	}
+
+	var tx *transaction
+	command := arguments[0]
+	switch command {
+	case "asset":
+		tx, err = makeAssetTransaction(privateKey, options, input)
+	case "issue":
+		tx, err = makeIssues(privateKey, options, input)
+	case "transfer":
+		tx, err = makeTransfer(privateKey, options, input)
+	default:
+		exitwithstatus.Message("%s: no such command: %q", program, command)
+	}
+	if nil != err {
+		exitwithstatus.Message("%s: %s error: %v", program, command, err)
+	}
+
+	if hostPort, ok := lastOption(options, "connect"); ok {
+		config, err := tlsConfig(options)
+		if nil != err {
+			exitwithstatus.Message("%s: TLS options error: %v", program, err)
+		}
+		client, err := connect(hostPort, config)
+		if nil != err {
+			exitwithstatus.Message("%s: connect: %q  error: %v", program, hostPort, err)
+		}
+		defer client.Close()
+
+		if err := submit(client, tx); nil != err {
+			exitwithstatus.Message("%s: %s error: %v", program, tx.Method, err)
+		}
+	}
+
+	b, err := json.MarshalIndent(tx, "", "  ")
+	if nil != err {
+		exitwithstatus.Message("%s: json error: %v", program, err)
+	}
+	fmt.Printf("%s\n", b)
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"strconv"
+	"strings"
+)
+
+// RPC methods used to submit the records
+const (
+	createMethod   = "Bitmarks.Create"
+	proofMethod    = "Bitmarks.Proof"
+	transferMethod = "Bitmark.Transfer"
+)
+
+// the same JSON as the arguments of Bitmarks.Create
+type createArguments struct {
+	Assets []*transactionrecord.AssetData    `json:"assets"`
+	Issues []*transactionrecord.BitmarkIssue `json:"issues"`
+}
+
+// the result of building and signing
+type transaction struct {
+	Method    string                     `json:"method"`
+	Arguments interface{}                `json:"arguments"`
+	Packed    []transactionrecord.Packed `json:"packed"`
+	Reply     interface{}                `json:"reply,omitempty"`
+	Proof     interface{}                `json:"proof,omitempty"`
+}
+
+// build and sign an asset record
+//
+// the JSON input is an asset record and any options replace its fields
+func makeAsset(privateKey *account.PrivateKey, options map[string][]string, input []byte) (*transactionrecord.AssetData, transactionrecord.Packed, error) {
+
+	asset := &transactionrecord.AssetData{}
+	if nil != input {
+		if err := json.Unmarshal(input, asset); nil != err {
+			return nil, nil, err
+		}
+	}
+
+	if s, ok := lastOption(options, "name"); ok {
+		asset.Name = s
+	}
+	if s, ok := lastOption(options, "fingerprint"); ok {
+		asset.Fingerprint = s
+	}
+	if 0 != len(options["metadata"]) {
+		metadata := make([]string, 0, 2*len(options["metadata"]))
+		for _, item := range options["metadata"] {
+			kv := strings.SplitN(item, "=", 2)
+			if 2 != len(kv) {
+				return nil, nil, fault.ErrMetadataIsNotMap
+			}
+			metadata = append(metadata, kv[0], kv[1])
+		}
+		asset.Metadata = strings.Join(metadata, "\u0000")
+	}
+	asset.Registrant = privateKey.Account()
+
+	packed, err := signRecord(asset, &asset.Signature, privateKey)
+	if nil != err {
+		return nil, nil, err
+	}
+	return asset, packed, nil
+}
+
+// a single asset for the Bitmarks.Create RPC
+func makeAssetTransaction(privateKey *account.PrivateKey, options map[string][]string, input []byte) (*transaction, error) {
+
+	asset, packed, err := makeAsset(privateKey, options, input)
+	if nil != err {
+		return nil, err
+	}
+
+	result := &transaction{
+		Method: createMethod,
+		Arguments: createArguments{
+			Assets: []*transactionrecord.AssetData{asset},
+		},
+		Packed: []transactionrecord.Packed{packed},
+	}
+	return result, nil
+}
+
+// build and sign a set of issues for the Bitmarks.Create RPC
+//
+// the asset is either the index of an existing asset or the fields
+// for a new asset, which is then registered in the same call
+func makeIssues(privateKey *account.PrivateKey, options map[string][]string, input []byte) (*transaction, error) {
+
+	arguments := createArguments{}
+	packed := []transactionrecord.Packed{}
+
+	template := transactionrecord.BitmarkIssue{}
+	if nil != input {
+		if err := json.Unmarshal(input, &template); nil != err {
+			return nil, err
+		}
+	}
+
+	if s, ok := lastOption(options, "asset"); ok {
+		if err := template.AssetIndex.UnmarshalText([]byte(s)); nil != err {
+			return nil, err
+		}
+	} else if _, ok := lastOption(options, "fingerprint"); ok {
+		asset, packedAsset, err := makeAsset(privateKey, options, nil)
+		if nil != err {
+			return nil, err
+		}
+		template.AssetIndex = asset.AssetIndex()
+		arguments.Assets = append(arguments.Assets, asset)
+		packed = append(packed, packedAsset)
+	} else if nil == input {
+		return nil, fault.ErrMissingParameters
+	}
+
+	quantity := 1
+	if s, ok := lastOption(options, "quantity"); ok {
+		n, err := strconv.Atoi(s)
+		if nil != err || n < 1 {
+			return nil, fault.ErrInvalidCount
+		}
+		quantity = n
+	}
+
+	// an explicit nonce is only possible for a single issue
+	useNonce := 0 != template.Nonce
+	if s, ok := lastOption(options, "nonce"); ok {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if nil != err {
+			return nil, fault.ErrInvalidNonce
+		}
+		template.Nonce = n
+		useNonce = true
+	}
+	if useNonce && 1 != quantity {
+		return nil, fault.ErrInvalidCount
+	}
+
+	for i := 0; i < quantity; i += 1 {
+		issue := &transactionrecord.BitmarkIssue{
+			AssetIndex: template.AssetIndex,
+			Owner:      privateKey.Account(),
+			Nonce:      template.Nonce,
+		}
+		if !useNonce {
+			nonce, err := randomNonce()
+			if nil != err {
+				return nil, err
+			}
+			issue.Nonce = nonce
+		}
+		packedIssue, err := signRecord(issue, &issue.Signature, privateKey)
+		if nil != err {
+			return nil, err
+		}
+		arguments.Issues = append(arguments.Issues, issue)
+		packed = append(packed, packedIssue)
+	}
+
+	result := &transaction{
+		Method:    createMethod,
+		Arguments: arguments,
+		Packed:    packed,
+	}
+	return result, nil
+}
+
+// build and sign a transfer from the key's account to a new owner
+func makeTransfer(privateKey *account.PrivateKey, options map[string][]string, input []byte) (*transaction, error) {
+
+	transfer := &transactionrecord.BitmarkTransfer{}
+	if nil != input {
+		if err := json.Unmarshal(input, transfer); nil != err {
+			return nil, err
+		}
+	}
+
+	if s, ok := lastOption(options, "link"); ok {
+		if err := transfer.Link.UnmarshalText([]byte(s)); nil != err {
+			return nil, err
+		}
+	}
+	if s, ok := lastOption(options, "owner"); ok {
+		owner, err := account.AccountFromBase58(s)
+		if nil != err {
+			return nil, err
+		}
+		transfer.Owner = owner
+	}
+	if s, ok := lastOption(options, "payment"); ok {
+		payment, err := parsePayment(s)
+		if nil != err {
+			return nil, err
+		}
+		transfer.Payment = payment
+	}
+
+	packed, err := signRecord(transfer, &transfer.Signature, privateKey)
+	if nil != err {
+		return nil, err
+	}
+
+	result := &transaction{
+		Method:    transferMethod,
+		Arguments: transfer,
+		Packed:    []transactionrecord.Packed{packed},
+	}
+	return result, nil
+}
+
+// parse: CURRENCY:ADDRESS:AMOUNT
+//
+// the amount is in the smallest unit of the currency
+func parsePayment(s string) (*transactionrecord.Payment, error) {
+	fields := strings.Split(s, ":")
+	if 3 != len(fields) || "" == fields[1] {
+		return nil, fault.ErrInvalidCurrency
+	}
+
+	payment := &transactionrecord.Payment{
+		Address: fields[1],
+	}
+	if err := payment.Currency.UnmarshalText([]byte(fields[0])); nil != err {
+		return nil, err
+	}
+	if currency.Nothing == payment.Currency {
+		return nil, fault.ErrInvalidCurrency
+	}
+	amount, err := strconv.ParseUint(fields[2], 10, 64)
+	if nil != err {
+		return nil, err
+	}
+	payment.Amount = amount
+	return payment, nil
+}
+
+// a random issue nonce so that repeated issues of an asset are distinct
+func randomNonce() (uint64, error) {
+	buffer := make([]byte, 8)
+	if _, err := rand.Read(buffer); nil != err {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buffer), nil
+}
+
+// the value of an option if it was given, the last one wins
+func lastOption(options map[string][]string, name string) (string, bool) {
+	values := options[name]
+	if 0 == len(values) {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"reflect"
+	"testing"
+)
+
+// live network seed
+const testSeed = "5XEECqhR7QBkJezUJiUJBmHaSmffDfVN5atuLnQBHnvfxbsWHuBfQLw"
+
+func TestParsePayment(t *testing.T) {
+
+	payment, err := parsePayment("BTC:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn:25000")
+	if nil != err {
+		t.Fatalf("parse error: %v", err)
+	}
+	expected := &transactionrecord.Payment{
+		Currency: currency.Bitcoin,
+		Address:  "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+		Amount:   25000,
+	}
+	if !reflect.DeepEqual(expected, payment) {
+		t.Errorf("payment: %+v  expected: %+v", payment, expected)
+	}
+
+	invalid := []string{
+		"",
+		"BTC:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+		"BTC::25000",
+		"XYZ:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn:25000",
+		":mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn:25000",
+		"BTC:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn:-1",
+		"BTC:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn:ten",
+		"BTC:mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn:25000:1",
+	}
+	for i, s := range invalid {
+		if payment, err := parsePayment(s); nil == err {
+			t.Errorf("%d: %q  gave: %+v", i, s, payment)
+		}
+	}
+}
+
+// the packed record carries a signature that the node accepts
+func TestSignRecord(t *testing.T) {
+
+	privateKey, err := account.PrivateKeyFromBase58Seed(testSeed)
+	if nil != err {
+		t.Fatalf("seed error: %v", err)
+	}
+
+	asset := &transactionrecord.AssetData{
+		Name:        "signed asset",
+		Fingerprint: "01020304",
+		Metadata:    "source\u0000test",
+		Registrant:  privateKey.Account(),
+	}
+	packed, err := signRecord(asset, &asset.Signature, privateKey)
+	if nil != err {
+		t.Fatalf("sign error: %v", err)
+	}
+
+	unpacked, n, err := packed.Unpack()
+	if nil != err {
+		t.Fatalf("unpack error: %v", err)
+	}
+	if len(packed) != n || !reflect.DeepEqual(asset, unpacked) {
+		t.Errorf("unpacked: %+v  expected: %+v", unpacked, asset)
+	}
+
+	// signing again replaces the old signature
+	repacked, err := signRecord(asset, &asset.Signature, privateKey)
+	if nil != err {
+		t.Fatalf("sign again error: %v", err)
+	}
+	if !bytes.Equal(packed, repacked) {
+		t.Errorf("signing again changed the record")
+	}
+
+	// the signature must match the registrant
+	other, err := account.DeriveChild(testSeed, "m/44'/731'/0'")
+	if nil != err {
+		t.Fatalf("derive error: %v", err)
+	}
+	asset.Signature = nil
+	message, _ := asset.Pack(asset.Registrant)
+	asset.Signature, err = sign(other, message)
+	if nil != err {
+		t.Fatalf("sign error: %v", err)
+	}
+	if _, err := asset.Pack(asset.Registrant); nil == err {
+		t.Errorf("record signed by another key was packed")
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/pay"
+	"golang.org/x/crypto/sha3"
+	"io/ioutil"
+	"math/big"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+)
+
+// the parts of the Bitmarks.Create reply needed for the proof
+type createReply struct {
+	Assets     json.RawMessage `json:"assets"`
+	Issues     json.RawMessage `json:"issues"`
+	PayId      pay.PayId       `json:"payId"`
+	PayNonce   string          `json:"payNonce"`
+	Difficulty string          `json:"difficulty"`
+}
+
+type proofArguments struct {
+	PayId pay.PayId `json:"payId"`
+	Nonce string    `json:"nonce"`
+}
+
+type proofReply struct {
+	Status string `json:"status"`
+}
+
+// connect to the JSON RPC port of a bitmarkd
+func connect(hostPort string, config *tls.Config) (*rpc.Client, error) {
+	conn, err := tls.Dial("tcp", hostPort, config)
+	if nil != err {
+		return nil, err
+	}
+	return jsonrpc.NewClient(conn), nil
+}
+
+// select how the certificate of the node is checked
+//
+// the node normally has a self-signed certificate, so either its
+// fingerprint (the SHA3-256 of the certificate as published in the
+// node's DNS TXT record) or the file of the certificate authority that
+// signed it is given; otherwise the system roots are used and checking
+// is only skipped if explicitly requested
+func tlsConfig(options map[string][]string) (*tls.Config, error) {
+
+	config := &tls.Config{}
+
+	if s, ok := lastOption(options, "rpc-fingerprint"); ok {
+		fingerprint, err := hex.DecodeString(s)
+		if nil != err || 32 != len(fingerprint) { // SHA3-256
+			return nil, fault.ErrInvalidFingerprint
+		}
+
+		// the chain is not checked, only the pinned certificate
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = func(certificates [][]byte, _ [][]*x509.Certificate) error {
+			if 0 == len(certificates) {
+				return fault.ErrInvalidFingerprint
+			}
+			digest := sha3.Sum256(certificates[0])
+			if !bytes.Equal(fingerprint, digest[:]) {
+				return fault.ErrInvalidFingerprint
+			}
+			return nil
+		}
+		return config, nil
+	}
+
+	if s, ok := lastOption(options, "ca-file"); ok {
+		data, err := ioutil.ReadFile(s)
+		if nil != err {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fault.ErrInvalidCertificate
+		}
+		config.RootCAs = pool
+		return config, nil
+	}
+
+	if 0 != len(options["insecure"]) {
+		config.InsecureSkipVerify = true
+	}
+	return config, nil
+}
+
+// send the signed records to the node
+//
+// issues without payment need a proof, which is solved here and sent
+// as a second call
+func submit(client *rpc.Client, tx *transaction) error {
+
+	if createMethod != tx.Method {
+		var reply json.RawMessage
+		if err := client.Call(tx.Method, tx.Arguments, &reply); nil != err {
+			return err
+		}
+		tx.Reply = reply
+		return nil
+	}
+
+	var reply createReply
+	if err := client.Call(tx.Method, tx.Arguments, &reply); nil != err {
+		return err
+	}
+	tx.Reply = reply
+
+	// assets only, so nothing to pay for
+	if "" == reply.Difficulty {
+		return nil
+	}
+
+	nonce, err := solveProof(reply.PayId, reply.PayNonce, reply.Difficulty)
+	if nil != err {
+		return err
+	}
+
+	arguments := proofArguments{
+		PayId: reply.PayId,
+		Nonce: hex.EncodeToString(nonce),
+	}
+	var status proofReply
+	if err := client.Call(proofMethod, arguments, &status); nil != err {
+		return err
+	}
+	tx.Proof = status
+	return nil
+}
+
+// find a client nonce such that:
+//
+//	SHA3-256(payId ++ payNonce ++ nonce) <= difficulty
+//
+// the pay nonce and difficulty are the hex values from the reply
+func solveProof(payId pay.PayId, payNonceHex string, difficultyHex string) ([]byte, error) {
+
+	payNonce, err := hex.DecodeString(payNonceHex)
+	if nil != err {
+		return nil, err
+	}
+	difficulty, ok := new(big.Int).SetString(difficultyHex, 16)
+	if !ok || 0 == difficulty.Sign() {
+		return nil, fault.ErrInvalidNonce
+	}
+
+	start, err := randomNonce()
+	if nil != err {
+		return nil, err
+	}
+
+	nonce := make([]byte, 8)
+	hash := new(big.Int)
+	for n := start; ; n += 1 {
+		binary.BigEndian.PutUint64(nonce, n)
+
+		h := sha3.New256()
+		h.Write(payId[:])
+		h.Write(payNonce)
+		h.Write(nonce)
+		digest := h.Sum(nil)
+
+		if hash.SetBytes(digest).Cmp(difficulty) <= 0 {
+			return nonce, nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/pay"
+	"golang.org/x/crypto/sha3"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSolveProof(t *testing.T) {
+
+	payId := pay.NewPayId([][]byte{[]byte("a packed issue")})
+	payNonce := "0102030405060708"
+	difficulty := "0fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+
+	nonce, err := solveProof(payId, payNonce, difficulty)
+	if nil != err {
+		t.Fatalf("solve error: %v", err)
+	}
+
+	h := sha3.New256()
+	h.Write(payId[:])
+	b, _ := hex.DecodeString(payNonce)
+	h.Write(b)
+	h.Write(nonce)
+	digest := new(big.Int).SetBytes(h.Sum(nil))
+	limit, _ := new(big.Int).SetString(difficulty, 16)
+	if digest.Cmp(limit) > 0 {
+		t.Errorf("digest: %x  above difficulty: %s", digest, difficulty)
+	}
+
+	invalid := []struct {
+		payNonce   string
+		difficulty string
+	}{
+		{"not hex", difficulty},
+		{payNonce, "not hex"},
+		{payNonce, "0"},
+		{payNonce, ""},
+	}
+	for i, test := range invalid {
+		if _, err := solveProof(payId, test.payNonce, test.difficulty); nil == err {
+			t.Errorf("%d: pay nonce: %q  difficulty: %q  did not fail", i, test.payNonce, test.difficulty)
+		}
+	}
+}
+
+// certificate checking is only skipped by an explicit option
+func TestTLSConfig(t *testing.T) {
+
+	config, err := tlsConfig(map[string][]string{})
+	if nil != err {
+		t.Fatalf("default error: %v", err)
+	}
+	if config.InsecureSkipVerify {
+		t.Errorf("default skips verification")
+	}
+
+	config, err = tlsConfig(map[string][]string{"insecure": {""}})
+	if nil != err {
+		t.Fatalf("insecure error: %v", err)
+	}
+	if !config.InsecureSkipVerify {
+		t.Errorf("insecure verifies")
+	}
+
+	certificate := []byte("the DER bytes of a certificate")
+	fingerprint := sha3.Sum256(certificate)
+	config, err = tlsConfig(map[string][]string{"rpc-fingerprint": {hex.EncodeToString(fingerprint[:])}})
+	if nil != err {
+		t.Fatalf("fingerprint error: %v", err)
+	}
+	if err := config.VerifyPeerCertificate([][]byte{certificate}, nil); nil != err {
+		t.Errorf("pinned certificate error: %v", err)
+	}
+	if err := config.VerifyPeerCertificate([][]byte{[]byte("another certificate")}, nil); fault.ErrInvalidFingerprint != err {
+		t.Errorf("other certificate: error: %v  expected: %v", err, fault.ErrInvalidFingerprint)
+	}
+	if err := config.VerifyPeerCertificate(nil, nil); nil == err {
+		t.Errorf("no certificate was accepted")
+	}
+
+	for _, s := range []string{"not hex", hex.EncodeToString(fingerprint[:31])} {
+		if _, err := tlsConfig(map[string][]string{"rpc-fingerprint": {s}}); fault.ErrInvalidFingerprint != err {
+			t.Errorf("fingerprint: %q  error: %v  expected: %v", s, err, fault.ErrInvalidFingerprint)
+		}
+	}
+
+	directory, err := ioutil.TempDir("", "bitmark-cli")
+	if nil != err {
+		t.Fatalf("temporary directory error: %v", err)
+	}
+	defer os.RemoveAll(directory)
+
+	filename := filepath.Join(directory, "ca.crt")
+	if _, err := tlsConfig(map[string][]string{"ca-file": {filename}}); nil == err {
+		t.Errorf("missing CA file was accepted")
+	}
+	err = ioutil.WriteFile(filename, []byte("not a certificate\n"), 0600)
+	if nil != err {
+		t.Fatalf("write error: %v", err)
+	}
+	if _, err := tlsConfig(map[string][]string{"ca-file": {filename}}); fault.ErrInvalidCertificate != err {
+		t.Errorf("invalid CA file: error: %v  expected: %v", err, fault.ErrInvalidCertificate)
+	}
+}
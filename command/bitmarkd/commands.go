@@ -5,16 +5,20 @@
 package main
 
 import (
+	"bufio"
 	"crypto/rand"
 	"crypto/tls"
 	"fmt"
+	"github.com/bitmark-inc/bitmarkd/account"
 	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/keyfile"
 	"github.com/bitmark-inc/bitmarkd/mode"
 	"github.com/bitmark-inc/bitmarkd/util"
 	"github.com/bitmark-inc/bitmarkd/zmqutil"
 	"github.com/bitmark-inc/exitwithstatus"
 	"github.com/bitmark-inc/logger"
 	"golang.org/x/crypto/sha3"
+	"golang.org/x/crypto/ssh/terminal"
 	"io/ioutil"
 	"net"
 	"os"
@@ -125,6 +129,97 @@ func processSetupCommand(log *logger.L, arguments []string, options *Configurati
 		fmt.Printf("generated signing key: %q\n", signingKeyFilename)
 		log.Infof("generated signing key: %q", signingKeyFilename)
 
+	case "seed-phrase", "phrase":
+		signingKeyFilename := options.Proofing.SigningKey
+		if len(arguments) >= 1 && "" != arguments[0] {
+			signingKeyFilename = arguments[0] + ".sign"
+		}
+
+		data, err := keyfile.Read(signingKeyFilename)
+		if nil != err {
+			fmt.Printf("error reading signing key file: %q  error: %v\n", signingKeyFilename, err)
+			exitwithstatus.Exit(1)
+		}
+		seed := strings.TrimSpace(string(data))
+		if !strings.HasPrefix(seed, "SEED:") {
+			fmt.Printf("signing key file: %q  does not contain a seed\n", signingKeyFilename)
+			exitwithstatus.Exit(1)
+		}
+		seed = seed[len("SEED:"):]
+		if !strings.ContainsAny(seed, " \t\n") {
+			seed, err = account.MnemonicFromBase58Seed(seed)
+			if nil != err {
+				fmt.Printf("error converting seed: %v\n", err)
+				exitwithstatus.Exit(1)
+			}
+		}
+		fmt.Printf("%s\n", seed)
+
+	case "restore-seed-phrase", "restore":
+		signingKeyFilename := options.Proofing.SigningKey
+		if len(arguments) > 1 {
+			fmt.Printf("the recovery phrase is read from standard input, not the command line\n")
+			exitwithstatus.Exit(1)
+		}
+		if len(arguments) >= 1 && "" != arguments[0] {
+			signingKeyFilename = arguments[0] + ".sign"
+		}
+
+		phrase, err := readRecoveryPhrase()
+		if nil != err {
+			fmt.Printf("cannot read recovery phrase: %v\n", err)
+			exitwithstatus.Exit(1)
+		}
+		seed, err := account.Base58SeedFromMnemonic(phrase)
+		if nil != err {
+			fmt.Printf("error in recovery phrase: %v\n", err)
+			exitwithstatus.Exit(1)
+		}
+
+		if _, err := os.Stat(signingKeyFilename); nil == err {
+			fmt.Printf("signing key file: %q  already exists\n", signingKeyFilename)
+			exitwithstatus.Exit(1)
+		}
+		data := "SEED:" + seed + "\n"
+		if err = ioutil.WriteFile(signingKeyFilename, []byte(data), 0600); err != nil {
+			fmt.Printf("error writing signing key file error: %v\n", err)
+			log.Criticalf("error writing signing key file error: %v", err)
+			exitwithstatus.Exit(1)
+		}
+		fmt.Printf("restored signing key: %q\n", signingKeyFilename)
+		log.Infof("restored signing key: %q", signingKeyFilename)
+
+	case "encrypt-key-files", "encrypt":
+		filenames := arguments
+		if 0 == len(filenames) {
+			filenames = []string{
+				options.Peering.PrivateKey,
+				options.Proofing.PrivateKey,
+				options.Proofing.SigningKey,
+			}
+		}
+
+		passphrase, err := keyfile.NewPassphrase()
+		if nil != err {
+			fmt.Printf("cannot get passphrase: %v\n", err)
+			exitwithstatus.Exit(1)
+		}
+
+		for _, filename := range filenames {
+			err := keyfile.EncryptFile(filename, passphrase)
+			if fault.ErrKeyFileAlreadyEncrypted == err {
+				fmt.Printf("already encrypted: %q\n", filename)
+				continue
+			}
+			if nil != err {
+				fmt.Printf("cannot encrypt: %q  error: %v\n", filename, err)
+				log.Criticalf("cannot encrypt: %q  error: %v", filename, err)
+				exitwithstatus.Exit(1)
+			}
+			fmt.Printf("encrypted: %q\n", filename)
+			log.Infof("encrypted: %q", filename)
+		}
+
 	case "dns-txt", "txt":
 		dnsTXT(log, options)
 
@@ -163,6 +258,22 @@ func processSetupCommand(log *logger.L, arguments []string, options *Configurati
 		fmt.Printf("                                     and signing key in:    %q\n", options.Proofing.SigningKey)
 		fmt.Printf("\n")
 
+		fmt.Printf("  seed-phrase            (phrase)  - display the recovery phrase of the seed in: %q\n", options.Proofing.SigningKey)
+		fmt.Printf("  seed-phrase PREFIX               - display the recovery phrase of the seed in: '<PREFIX>.sign'\n")
+		fmt.Printf("\n")
+
+		fmt.Printf("  restore-seed-phrase    (restore) - create signing key: %q\n", options.Proofing.SigningKey)
+		fmt.Printf("                                     from the 25 words of a recovery phrase\n")
+		fmt.Printf("                                     read from a prompt or standard input\n")
+		fmt.Printf("  restore-seed-phrase PREFIX       - create signing key: '<PREFIX>.sign'\n")
+		fmt.Printf("\n")
+
+		fmt.Printf("  encrypt-key-files      (encrypt) - encrypt the private keys in: %q\n", options.Peering.PrivateKey)
+		fmt.Printf("                                     %q and signing key in: %q\n", options.Proofing.PrivateKey, options.Proofing.SigningKey)
+		fmt.Printf("  encrypt-key-files FILES...       - encrypt the named key files\n")
+		fmt.Printf("                                     passphrase from: $%s, fd in $%s or prompt\n", keyfile.PassphraseVariable, keyfile.PassphraseFdVariable)
+		fmt.Printf("\n")
+
 		fmt.Printf("  dns-txt                (txt)     - display the data to put in a dbs TXT record\n")
 		fmt.Printf("\n")
 
@@ -178,6 +289,25 @@ func processSetupCommand(log *logger.L, arguments []string, options *Configurati
 	return true
 }
 
+// read a recovery phrase from a terminal prompt without echo or from
+// one line of standard input, so that it is not visible in the
+// process list or the shell history
+func readRecoveryPhrase() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "recovery phrase: ")
+		phrase, err := terminal.ReadPassword(fd)
+		fmt.Fprintf(os.Stderr, "\n")
+		return string(phrase), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if 0 == len(line) && nil != err {
+		return "", err
+	}
+	return line, nil
+}
+
 // data command handler
 // the internal block and transaction pools are enabled so these commands can
 // access and/or change these databases
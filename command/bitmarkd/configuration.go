@@ -84,6 +84,7 @@ type Configuration struct {
 	PidFile       string       `libucl:"pidfile"`
 	Chain         string       `libucl:"chain"`
 	Nodes         string       `libucl:"nodes"`
+	Light         bool         `libucl:"light"`
 	Database      DatabaseType `libucl:"database"`
 
 	ClientRPC RPCType               `libucl:"client_rpc"`
@@ -133,6 +133,9 @@ func main() {
 	}
 	defer mode.Finalise()
 
+	// light mode only keeps block headers
+	mode.SetLight(masterConfiguration.Light)
+
 	// command processing - need lock so do not affect an already running process
 	// these commands process data needed for initial setup
 	if len(arguments) > 0 && processSetupCommand(log, arguments, masterConfiguration) {
@@ -153,6 +156,7 @@ func main() {
 
 	// general info
 	log.Infof("test mode: %v", mode.IsTesting())
+	log.Infof("light mode: %v", mode.IsLight())
 	log.Infof("database: %q", masterConfiguration.Database)
 
 	// connection info
@@ -169,14 +173,12 @@ func main() {
 	}
 	defer storage.Finalise()
 
-	// start the reservoir (verified transaction data cache)
-	log.Info("initialise reservoir")
-	err = reservoir.Initialise()
+	// a light database cannot be used by a full node or the reverse
+	err = storage.CheckMode(mode.IsLight())
 	if nil != err {
-		log.Criticalf("reservoir initialise error: %v", err)
-		exitwithstatus.Message("reservoir initialise error: %v", err)
+		log.Criticalf("storage mode error: %v", err)
+		exitwithstatus.Message("storage mode error: %v", err)
 	}
-	defer reservoir.Finalise()
 
 	// start asset cache
 	err = asset.Initialise()
@@ -204,6 +206,16 @@ func main() {
 	}
 	defer block.Finalise()
 
+	// start the reservoir (verified transaction data cache)
+	// - depends on storage, asset and block to restore its journal
+	log.Info("initialise reservoir")
+	err = reservoir.Initialise()
+	if nil != err {
+		log.Criticalf("reservoir initialise error: %v", err)
+		exitwithstatus.Message("reservoir initialise error: %v", err)
+	}
+	defer reservoir.Finalise()
+
 	// these commands are allowed to access the internal database
 	if len(arguments) > 0 && processDataCommand(log, arguments, masterConfiguration) {
 		return
@@ -307,16 +319,19 @@ func main() {
 		}
 	}
 
-	// start payment services
-	paymentConfiguration := &payment.Configuration{
-		Bitcoin: &masterConfiguration.Bitcoin,
-	}
-	err = payment.Initialise(paymentConfiguration)
-	if nil != err {
-		log.Criticalf("payment initialise  error: %v", err)
-		exitwithstatus.Message("payment initialise error: %v", err)
+	// start payment services - not needed in light mode as
+	// transactions are not accepted
+	if !mode.IsLight() {
+		paymentConfiguration := &payment.Configuration{
+			Bitcoin: &masterConfiguration.Bitcoin,
+		}
+		err = payment.Initialise(paymentConfiguration)
+		if nil != err {
+			log.Criticalf("payment initialise  error: %v", err)
+			exitwithstatus.Message("payment initialise error: %v", err)
+		}
+		defer payment.Finalise()
 	}
-	defer payment.Finalise()
 
 	// initialise encryption
 	err = zmqutil.StartAuthentication()
@@ -348,13 +363,15 @@ func main() {
 		exitwithstatus.Message("no RPC servers started")
 	}
 
-	// start proof background processes
-	err = proof.Initialise(&masterConfiguration.Proofing)
-	if nil != err {
-		log.Criticalf("proof initialise error: %v", err)
-		exitwithstatus.Message("proof initialise error: %v", err)
+	// start proof background processes - light mode does not mine
+	if !mode.IsLight() {
+		err = proof.Initialise(&masterConfiguration.Proofing)
+		if nil != err {
+			log.Criticalf("proof initialise error: %v", err)
+			exitwithstatus.Message("proof initialise error: %v", err)
+		}
+		defer proof.Finalise()
 	}
-	defer proof.Finalise()
 
 	// wait for CTRL-C before shutting down to allow manual testing
 	if 0 == len(options["quiet"]) {
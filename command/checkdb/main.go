@@ -0,0 +1,58 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"github.com/bitmark-inc/bitmarkd/block"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"os"
+)
+
+// check the indexes of a stopped node's database against its blocks
+//
+// exit status: 0 = consistent (or repaired), 1 = divergences found, 2 = error
+func main() {
+	if len(os.Args) < 2 || len(os.Args) > 3 || (3 == len(os.Args) && "repair" != os.Args[2]) {
+		fmt.Printf("usage: checkdb database [repair]\n")
+		fmt.Printf(" recompute all indexes from the stored blocks and report any differences\n")
+		fmt.Printf(" with \"repair\" the stored indexes are replaced by the recomputed values\n")
+		fmt.Printf(" the node must not be running\n")
+		os.Exit(2)
+	}
+
+	filename := os.Args[1]
+	repair := 3 == len(os.Args)
+
+	err := storage.Initialise(filename)
+	if nil != err {
+		fmt.Printf("cannot open database: %q  error: %v\n", filename, err)
+		os.Exit(2)
+	}
+	defer storage.Finalise()
+
+	divergences, err := block.CheckIndexes(repair)
+	if nil != err {
+		fmt.Printf("check failed: %v\n", err)
+		fmt.Printf("block data is damaged: the database must be resynchronised\n")
+		storage.Finalise()
+		os.Exit(2)
+	}
+
+	for i, d := range divergences {
+		fmt.Printf("%d: %s\n", i, d)
+	}
+
+	switch {
+	case 0 == len(divergences):
+		fmt.Printf("database is consistent\n")
+	case repair:
+		fmt.Printf("repaired: %d divergences\n", len(divergences))
+	default:
+		fmt.Printf("found: %d divergences\n", len(divergences))
+		storage.Finalise()
+		os.Exit(1)
+	}
+}
@@ -13,6 +13,11 @@ const (
 	ReservoirTimeout = 24 * time.Hour
 )
 
+// the time for a countersigned transfer to be accepted
+const (
+	OfferTimeout = 3 * ReservoirTimeout
+)
+
 // the maximum time before unverified asset is expired
 const (
 	AssetTimeout = ReservoirTimeout + time.Hour
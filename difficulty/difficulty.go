@@ -64,6 +64,9 @@ var constOne = []byte{
 // 	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
 // }
 
+// numerator for work calculation
+var twoTo256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
 var one big.Int        // for reciprocal calculation
 var floatOne big.Float // for reciprocal calculation
 
@@ -81,6 +84,15 @@ func New() *Difficulty {
 	return d.internalReset()
 }
 
+// create a difficulty with the largest possible value
+// that is auto-adjusted through a specific filter
+func NewFiltered(filter filters.Filter) *Difficulty {
+	d := &Difficulty{
+		filter: filter,
+	}
+	return d.internalReset()
+}
+
 // Get 1/difficulty as normal floating-point value
 // this is the Pdiff value
 func (difficulty *Difficulty) Reciprocal() float64 {
@@ -116,6 +128,14 @@ func (difficulty *Difficulty) BigInt() *big.Int {
 	return d.Set(&difficulty.big)
 }
 
+// the expected number of hashes needed to meet this difficulty
+// i.e. 2^256 / (target + 1), used to compare the total work of chains
+func (difficulty *Difficulty) Work() *big.Int {
+	target := difficulty.BigInt()
+	target.Add(target, big.NewInt(1))
+	return target.Quo(twoTo256, target)
+}
+
 // reset difficulty to minimum
 // ensure write locked before calling this
 func (difficulty *Difficulty) internalReset() *Difficulty {
@@ -195,10 +215,21 @@ func (difficulty *Difficulty) internalSetReciprocal(f float64) float64 {
 	// fmt.Printf("%f\n big: %064x\n", f, d)
 	// fmt.Printf("acc: %s\n", accuracy.String())
 
+	if bits, ok := encodeBits(d); ok {
+		difficulty.bits = bits
+	}
+
+	return difficulty.reciprocal
+}
+
+// convert a 256 bit value to the nearest bits value
+// returns false for zero
+func encodeBits(d *big.Int) (uint64, bool) {
+
 	buffer := d.Bytes() // no more than 32 bytes (256 bits)
 
 	if len(buffer) > 32 {
-		fault.Criticalf("difficulty.internalSetReciprocal(%g) invalid value", f)
+		fault.Criticalf("difficulty.encodeBits(%x) invalid value", d)
 		fault.Panic("difficulty.SetBits: failed - needs more than 256 bits")
 	}
 
@@ -250,12 +281,25 @@ func (difficulty *Difficulty) internalSetReciprocal(f float64) float64 {
 			u = u&0x00ffffffffffffff | e<<56
 			//fmt.Printf("bits: %016x\n", u)
 
-			difficulty.bits = u
-			break
+			return u, true
 		}
 	}
+	return 0, false
+}
 
-	return difficulty.reciprocal
+// set from a 256 bit value rounded to the nearest bits value
+// values from the "One" value upwards give the minimum difficulty
+func (difficulty *Difficulty) SetBigInt(d *big.Int) *Difficulty {
+	if d.Cmp(&one) >= 0 {
+		difficulty.m.Lock()
+		defer difficulty.m.Unlock()
+		return difficulty.internalReset()
+	}
+	bits, ok := encodeBits(d)
+	if !ok {
+		fault.Panic("difficulty.SetBigInt: zero value")
+	}
+	return difficulty.SetBits(bits)
 }
 
 // set the difficulty from little endian bytes
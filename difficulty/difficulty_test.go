@@ -167,6 +167,29 @@ func TestUint64(t *testing.T) {
 
 }
 
+// test setting from a 256 bit value
+func TestSetBigInt(t *testing.T) {
+
+	d := difficulty.New()
+	b := difficulty.New()
+
+	for i, item := range tests {
+		b.SetBits(item.bits)
+		d.SetBigInt(b.BigInt())
+
+		if item.bits != d.Bits() {
+			t.Errorf("%d: bits: actual: %016x  expected: %016x", i, d.Bits(), item.bits)
+		}
+	}
+
+	// values easier than the minimum difficulty
+	b.SetBits(difficulty.OneUint64)
+	d.SetBigInt(b.BigInt().Lsh(b.BigInt(), 1))
+	if difficulty.OneUint64 != d.Bits() {
+		t.Errorf("above one: bits: actual: %016x  expected: %016x", d.Bits(), difficulty.OneUint64)
+	}
+}
+
 // test bytes
 func TestBytes(t *testing.T) {
 
@@ -286,3 +309,25 @@ func TestDecay(t *testing.T) {
 		}
 	}
 }
+
+// test work calculation
+func TestWork(t *testing.T) {
+
+	d := difficulty.New()
+
+	// difficulty one has a target just below 2^248
+	if actual := d.Work().Int64(); 256 != actual {
+		t.Errorf("one: actual: %d  expected: 256", actual)
+	}
+
+	// double the reciprocal roughly doubles the work
+	previous := d.Work().Int64()
+	for i := 0; i < 8; i += 1 {
+		d.SetReciprocal(d.Reciprocal() * 2)
+		actual := d.Work().Int64()
+		if actual < 2*previous-1 || actual > 2*previous+1 {
+			t.Errorf("%d: reciprocal: %g  work: %d  previous: %d", i, d.Reciprocal(), actual, previous)
+		}
+		previous = actual
+	}
+}
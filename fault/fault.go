@@ -20,33 +20,47 @@ var (
 	ErrAlreadyInitialised                    = ExistsError("already initialised")
 	ErrAssetNotFound                         = NotFoundError("asset not found")
 	ErrAssetsAlreadyRegistered               = InvalidError("assets already registered")
+	ErrBatchOwnersDiffer                     = InvalidError("batch owners differ")
 	ErrBlockNotFound                         = NotFoundError("block not found")
+	ErrBlockNumberDoesNotMatch               = InvalidError("block number does not match")
+	ErrBranchNotHeavier                      = InvalidError("branch not heavier")
 	ErrCannotDecodeAccount                   = RecordError("cannot decode account")
 	ErrCannotDecodePrivateKey                = RecordError("cannot decode private key")
 	ErrCannotDecodeSeed                      = RecordError("cannot decode seed")
 	ErrCertificateFileAlreadyExists          = ExistsError("certificate file already exists")
 	ErrChecksumMismatch                      = ProcessError("checksum mismatch")
 	ErrConnectingToSelfForbidden             = ProcessError("connecting to self forbidden")
+	ErrDifficultyDoesNotMatch                = InvalidError("difficulty does not match")
+	ErrDifficultyNotMet                      = InvalidError("difficulty not met")
 	ErrDoubleTransferAttempt                 = InvalidError("double transfer attempt")
+	ErrDuplicatePaymentCurrency              = InvalidError("duplicate payment currency")
 	ErrFingerprintTooLong                    = LengthError("fingerprint too long")
 	ErrFingerprintTooShort                   = LengthError("fingerprint too short")
 	ErrIncorrectChain                        = InvalidError("incorrect chain")
 	ErrInitialisationFailed                  = InvalidError("initialisation failed")
+	ErrInsufficientPayment                   = InvalidError("insufficient payment")
 	ErrInvalidBlockHeader                    = InvalidError("invalid block header")
+	ErrInvalidBlockVersion                   = InvalidError("invalid block version")
+	ErrInvalidCertificate                    = InvalidError("invalid certificate")
 	ErrInvalidChain                          = InvalidError("invalid chain")
 	ErrInvalidCount                          = InvalidError("invalid count")
+	ErrInvalidCountersignature               = InvalidError("invalid countersignature")
 	ErrInvalidCurrency                       = InvalidError("invalid currency")
 	ErrInvalidCursor                         = InvalidError("invalid cursor")
+	ErrInvalidDerivationPath                 = InvalidError("invalid derivation path")
 	ErrInvalidDnsTxtRecord                   = InvalidError("invalid dns txt record")
 	ErrInvalidFingerprint                    = InvalidError("invalid fingerprint")
 	ErrInvalidIPAddress                      = InvalidError("invalid IP Address")
+	ErrInvalidInclusionProof                 = InvalidError("invalid inclusion proof")
 	ErrInvalidKeyLength                      = InvalidError("invalid key length")
 	ErrInvalidKeyType                        = InvalidError("invalid key type")
 	ErrInvalidLength                         = InvalidError("invalid length")
 	ErrInvalidLoggerChannel                  = InvalidError("invalid logger channel")
 	ErrInvalidMixedCurrencyPayment           = InvalidError("invalid mixed currency payment")
+	ErrInvalidMnemonicWord                   = InvalidError("invalid mnemonic word")
 	ErrInvalidNonce                          = InvalidError("invalid nonce")
 	ErrInvalidOwnerOrRegistrant              = InvalidError("invalid owner or registrant")
+	ErrInvalidPassphrase                     = InvalidError("invalid passphrase")
 	ErrInvalidPeerResponse                   = InvalidError("invalid peer response")
 	ErrInvalidPortNumber                     = InvalidError("invalid port number")
 	ErrInvalidPrivateKey                     = InvalidError("invalid private key")
@@ -54,44 +68,63 @@ var (
 	ErrInvalidProofSigningKey                = InvalidError("invalid proof signing key")
 	ErrInvalidPublicKey                      = InvalidError("invalid public key")
 	ErrInvalidPublicKeyFile                  = InvalidError("invalid public key file")
+	ErrInvalidSearchTerms                    = InvalidError("invalid search terms")
 	ErrInvalidSeedHeader                     = InvalidError("invalid seed header")
 	ErrInvalidSeedLength                     = InvalidError("invalid seed length")
+	ErrInvalidSeedPrefix                     = InvalidError("invalid seed prefix")
 	ErrInvalidSignature                      = InvalidError("invalid signature")
 	ErrInvalidStructPointer                  = InvalidError("invalid struct pointer")
 	ErrInvalidVersion                        = InvalidError("invalid version")
+	ErrKeyFileAlreadyEncrypted               = ExistsError("key file already encrypted")
 	ErrKeyFileAlreadyExists                  = ExistsError("key file already exists")
 	ErrKeyFileNotFound                       = NotFoundError("key file not found")
+	ErrKeyTypeNotActive                      = InvalidError("key type not active")
 	ErrLinkToInvalidOrUnconfirmedTransaction = InvalidError("link to invalid or unconfirmed transaction")
 	ErrMerkleRootDoesNotMatch                = InvalidError("Merkle Root Does Not Match")
 	ErrMetadataIsNotMap                      = InvalidError("metadata is not map")
 	ErrMetadataTooLong                       = LengthError("metadata too long")
+	ErrMissingBaseRecord                     = InvalidError("missing base record")
 	ErrMissingParameters                     = LengthError("missing parameters")
 	ErrNameTooLong                           = LengthError("name too long")
 	ErrNameTooShort                          = LengthError("name too short")
+	ErrNoCommonCurrency                      = InvalidError("no common currency")
 	ErrNoConnectionsAvailable                = InvalidError("no connections available")
 	ErrNoNewTransactions                     = InvalidError("no new transactions")
+	ErrNoPassphrase                          = InvalidError("no passphrase")
 	ErrNotAPayId                             = InvalidError("not a pay id")
 	ErrNotAPayNonce                          = InvalidError("not a pay nonce")
 	ErrNotAssetIndex                         = RecordError("not asset index")
 	ErrNotAvailableDuringSynchronise         = InvalidError("not available during synchronise")
+	ErrNotAvailableInLightMode               = InvalidError("not available in light mode")
 	ErrNotConnected                          = NotFoundError("not connected")
 	ErrNotInitialised                        = NotFoundError("not initialised")
 	ErrNotLink                               = RecordError("not link")
 	ErrNotPrivateKey                         = RecordError("not private key")
 	ErrNotPublicKey                          = RecordError("not public key")
 	ErrNotTransactionPack                    = RecordError("not transaction pack")
+	ErrOfferNotFound                         = NotFoundError("offer not found")
+	ErrPassphrasesDiffer                     = InvalidError("passphrases differ")
 	ErrPayIdAlreadyUsed                      = InvalidError("payId already used")
+	ErrPaymentAddressNotFound                = NotFoundError("payment address not found")
 	ErrPaymentAddressTooLong                 = LengthError("payment address too long")
 	ErrPreviousBlockDigestDoesNotMatch       = InvalidError("previous block digest does not match")
 	ErrReceiptTooLong                        = LengthError("receipt too long")
 	ErrSignatureTooLong                      = LengthError("signature too long")
+	ErrTimestampTooEarly                     = InvalidError("timestamp too early")
+	ErrTimestampTooFarInFuture               = InvalidError("timestamp too far in future")
 	ErrTooManyItemsToProcess                 = LengthError("too many items to process")
+	ErrTooManyOffers                         = LengthError("too many offers")
 	ErrTransactionAlreadyExists              = ExistsError("transaction already exists")
+	ErrTransactionCountOutOfRange            = LengthError("transaction count out of range")
+	ErrTransactionHasExpired                 = InvalidError("transaction has expired")
 	ErrTransactionIsNotATransfer             = InvalidError("transaction is not a transfer")
 	ErrTransactionIsNotAnAsset               = InvalidError("transaction is not an asset")
 	ErrTransactionIsNotAnIssue               = InvalidError("transaction is not an issue")
 	ErrTransactionIsNotAnIssueOrATransfer    = InvalidError("transaction is not an issue or a transfer")
 	ErrTransactionLinksToSelf                = RecordError("transaction links to self")
+	ErrTransactionNotFound                   = NotFoundError("transaction not found")
+	ErrTransactionTypeNotActive              = InvalidError("transaction type not active")
+	ErrUnexpectedBaseRecord                  = InvalidError("unexpected base record")
 	ErrUnexpectedNilPointer                  = ProcessError("unexpected nil pointer")
 	ErrWrongNetworkForPrivateKey             = InvalidError("wrong network for private key")
 	ErrWrongNetworkForPublicKey              = InvalidError("wrong network for public key")
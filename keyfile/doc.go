@@ -0,0 +1,14 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// reading and writing of key files that may be encrypted with a
+// passphrase
+//
+// an encrypted file holds a single line:
+//
+//   ENCRYPTED:hex(version ++ salt ++ nonce ++ secretbox(original file))
+//
+// the secretbox key is Argon2id(passphrase, salt) so any of the
+// existing key file formats can be encrypted without change
+package keyfile
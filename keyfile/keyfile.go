@@ -0,0 +1,175 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package keyfile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/go-argon2"
+	"golang.org/x/crypto/nacl/secretbox"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	taggedEncrypted = "ENCRYPTED:"
+
+	formatVersion = 0x01
+	saltLength    = 16
+	nonceLength   = 24
+	keyLength     = 32
+	headerLength  = 1 + saltLength + nonceLength
+)
+
+// key derivation parameters for formatVersion
+const (
+	kdfMode        = argon2.ModeArgon2id
+	kdfMemory      = 1 << 16 // 64 MiB
+	kdfParallelism = 1
+	kdfIterations  = 3
+	kdfVersion     = argon2.Version13
+)
+
+// read a key file decrypting it if necessary
+//
+// the result is the original file contents so the caller can parse
+// the plain and decrypted forms the same way
+func Read(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if nil != err {
+		return nil, err
+	}
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	passphrase, err := Passphrase()
+	if nil != err {
+		return nil, err
+	}
+	return Decrypt(data, passphrase)
+}
+
+// encrypt an existing key file in place
+func EncryptFile(filename string, passphrase []byte) error {
+	data, err := ioutil.ReadFile(filename)
+	if nil != err {
+		return err
+	}
+	if IsEncrypted(data) {
+		return fault.ErrKeyFileAlreadyEncrypted
+	}
+
+	encrypted, err := Encrypt(data, passphrase)
+	if nil != err {
+		return err
+	}
+
+	info, err := os.Stat(filename)
+	if nil != err {
+		return err
+	}
+
+	// write alongside then rename so the key is never lost part way
+	temporary := filename + ".new"
+	err = ioutil.WriteFile(temporary, encrypted, info.Mode().Perm()&0600)
+	if nil != err {
+		return err
+	}
+	err = os.Rename(temporary, filename)
+	if nil != err {
+		os.Remove(temporary)
+	}
+	return err
+}
+
+// true if the data is in the encrypted key file format
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(taggedEncrypted))
+}
+
+// encrypt the data with a new random salt and nonce
+func Encrypt(data []byte, passphrase []byte) ([]byte, error) {
+	if 0 == len(passphrase) {
+		return nil, fault.ErrNoPassphrase
+	}
+
+	header := make([]byte, headerLength)
+	header[0] = formatVersion
+	if _, err := rand.Read(header[1:]); nil != err {
+		return nil, err
+	}
+	salt := header[1 : 1+saltLength]
+
+	var nonce [nonceLength]byte
+	copy(nonce[:], header[1+saltLength:])
+
+	key, err := deriveKey(passphrase, salt)
+	if nil != err {
+		return nil, err
+	}
+
+	sealed := secretbox.Seal(header, data, &nonce, key)
+
+	return []byte(taggedEncrypted + hex.EncodeToString(sealed) + "\n"), nil
+}
+
+// decrypt data produced by Encrypt
+func Decrypt(data []byte, passphrase []byte) ([]byte, error) {
+	s := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(s, []byte(taggedEncrypted)) {
+		return nil, fault.ErrInvalidPrivateKeyFile
+	}
+
+	sealed, err := hex.DecodeString(string(s[len(taggedEncrypted):]))
+	if nil != err {
+		return nil, err
+	}
+	if len(sealed) < headerLength+secretbox.Overhead {
+		return nil, fault.ErrInvalidPrivateKeyFile
+	}
+	if formatVersion != sealed[0] {
+		return nil, fault.ErrInvalidVersion
+	}
+	salt := sealed[1 : 1+saltLength]
+
+	var nonce [nonceLength]byte
+	copy(nonce[:], sealed[1+saltLength:headerLength])
+
+	key, err := deriveKey(passphrase, salt)
+	if nil != err {
+		return nil, err
+	}
+
+	plain, ok := secretbox.Open(nil, sealed[headerLength:], &nonce, key)
+	if !ok {
+		return nil, fault.ErrInvalidPassphrase
+	}
+	return plain, nil
+}
+
+// the secretbox key for a passphrase
+func deriveKey(passphrase []byte, salt []byte) (*[keyLength]byte, error) {
+
+	context := &argon2.Context{
+		Iterations:  kdfIterations,
+		Memory:      kdfMemory,
+		Parallelism: kdfParallelism,
+		HashLen:     keyLength,
+		Mode:        kdfMode,
+		Version:     kdfVersion,
+	}
+
+	hash, err := argon2.Hash(context, passphrase, salt)
+	if nil != err {
+		return nil, err
+	}
+
+	var key [keyLength]byte
+	copy(key[:], hash)
+	return &key, nil
+}
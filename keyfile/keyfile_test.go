@@ -0,0 +1,179 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package keyfile
+
+import (
+	"bytes"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+const testKeyData = "PRIVATE:2f9a4c0e6f0c4fe1b0e3a7a1d3f5c1e8b4a2d6f7c9e1a3b5d7f9e2c4a6b8d0f1\n"
+
+func TestEncryptDecrypt(t *testing.T) {
+
+	passphrase := []byte("correct horse battery staple")
+
+	encrypted, err := Encrypt([]byte(testKeyData), passphrase)
+	if nil != err {
+		t.Fatalf("encrypt error: %s", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Errorf("not detected as encrypted: %q", encrypted)
+	}
+	if IsEncrypted([]byte(testKeyData)) {
+		t.Errorf("plain data detected as encrypted")
+	}
+	if bytes.Contains(encrypted, []byte(testKeyData[len("PRIVATE:"):len(testKeyData)-1])) {
+		t.Errorf("key is visible in: %q", encrypted)
+	}
+
+	again, err := Encrypt([]byte(testKeyData), passphrase)
+	if nil != err {
+		t.Fatalf("encrypt error: %s", err)
+	}
+	if bytes.Equal(encrypted, again) {
+		t.Errorf("salt and nonce are not random")
+	}
+
+	plain, err := Decrypt(encrypted, passphrase)
+	if nil != err {
+		t.Fatalf("decrypt error: %s", err)
+	}
+	if testKeyData != string(plain) {
+		t.Errorf("decrypted: %q  expected: %q", plain, testKeyData)
+	}
+
+	if _, err := Decrypt(encrypted, []byte("wrong passphrase")); fault.ErrInvalidPassphrase != err {
+		t.Errorf("wrong passphrase: error: %v  expected: %v", err, fault.ErrInvalidPassphrase)
+	}
+
+	corrupt := append([]byte{}, encrypted...)
+	corrupt[len(corrupt)-2] ^= 0x01 // last hex digit, before the newline
+	if _, err := Decrypt(corrupt, passphrase); fault.ErrInvalidPassphrase != err {
+		t.Errorf("corrupt data: error: %v  expected: %v", err, fault.ErrInvalidPassphrase)
+	}
+
+	if _, err := Decrypt([]byte(taggedEncrypted+"01"), passphrase); fault.ErrInvalidPrivateKeyFile != err {
+		t.Errorf("truncated data: error: %v  expected: %v", err, fault.ErrInvalidPrivateKeyFile)
+	}
+
+	if _, err := Encrypt([]byte(testKeyData), nil); fault.ErrNoPassphrase != err {
+		t.Errorf("empty passphrase: error: %v  expected: %v", err, fault.ErrNoPassphrase)
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+
+	directory, err := ioutil.TempDir("", "keyfile")
+	if nil != err {
+		t.Fatalf("temporary directory error: %s", err)
+	}
+	defer os.RemoveAll(directory)
+
+	filename := filepath.Join(directory, "test.private")
+	err = ioutil.WriteFile(filename, []byte(testKeyData), 0600)
+	if nil != err {
+		t.Fatalf("write error: %s", err)
+	}
+
+	passphrase := []byte("file passphrase")
+	err = EncryptFile(filename, passphrase)
+	if nil != err {
+		t.Fatalf("encrypt file error: %s", err)
+	}
+	if err := EncryptFile(filename, passphrase); fault.ErrKeyFileAlreadyEncrypted != err {
+		t.Errorf("encrypt twice: error: %v  expected: %v", err, fault.ErrKeyFileAlreadyEncrypted)
+	}
+
+	info, err := os.Stat(filename)
+	if nil != err {
+		t.Fatalf("stat error: %s", err)
+	}
+	if 0600 != info.Mode().Perm() {
+		t.Errorf("mode: %o  expected: %o", info.Mode().Perm(), 0600)
+	}
+
+	defer resetPassphrase()
+	resetPassphrase()
+	os.Setenv(PassphraseVariable, string(passphrase))
+	defer os.Unsetenv(PassphraseVariable)
+
+	data, err := Read(filename)
+	if nil != err {
+		t.Fatalf("read error: %s", err)
+	}
+	if testKeyData != string(data) {
+		t.Errorf("read: %q  expected: %q", data, testKeyData)
+	}
+
+	// a plain file is returned unchanged
+	plainFilename := filepath.Join(directory, "test.public")
+	err = ioutil.WriteFile(plainFilename, []byte("PUBLIC:00\n"), 0600)
+	if nil != err {
+		t.Fatalf("write error: %s", err)
+	}
+	data, err = Read(plainFilename)
+	if nil != err {
+		t.Fatalf("read plain error: %s", err)
+	}
+	if "PUBLIC:00\n" != string(data) {
+		t.Errorf("read plain: %q", data)
+	}
+}
+
+func TestPassphraseSources(t *testing.T) {
+
+	defer resetPassphrase()
+	defer os.Unsetenv(PassphraseVariable)
+	defer os.Unsetenv(PassphraseFdVariable)
+
+	os.Setenv(PassphraseVariable, "from environment")
+	os.Setenv(PassphraseFdVariable, "1000")
+	resetPassphrase()
+	passphrase, err := Passphrase()
+	if nil != err || "from environment" != string(passphrase) {
+		t.Errorf("environment: %q  error: %v", passphrase, err)
+	}
+
+	// later changes are not seen once the passphrase is known
+	os.Setenv(PassphraseVariable, "changed")
+	passphrase, err = Passphrase()
+	if nil != err || "from environment" != string(passphrase) {
+		t.Errorf("cached: %q  error: %v", passphrase, err)
+	}
+
+	r, w, err := os.Pipe()
+	if nil != err {
+		t.Fatalf("pipe error: %s", err)
+	}
+	defer r.Close()
+	w.Write([]byte("from descriptor\nnext line\n"))
+	w.Close()
+
+	os.Unsetenv(PassphraseVariable)
+	os.Setenv(PassphraseFdVariable, strconv.Itoa(int(r.Fd())))
+	resetPassphrase()
+	passphrase, err = Passphrase()
+	if nil != err || "from descriptor" != string(passphrase) {
+		t.Errorf("file descriptor: %q  error: %v", passphrase, err)
+	}
+
+	os.Setenv(PassphraseFdVariable, "not a number")
+	resetPassphrase()
+	if _, err := Passphrase(); nil == err {
+		t.Errorf("invalid file descriptor did not fail")
+	}
+}
+
+func resetPassphrase() {
+	cached.Lock()
+	cached.passphrase = nil
+	cached.Unlock()
+}
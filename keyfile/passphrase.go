@@ -0,0 +1,106 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package keyfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"golang.org/x/crypto/ssh/terminal"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// environment variables that supply the passphrase
+const (
+	PassphraseVariable   = "BITMARK_PASSPHRASE"    // the passphrase itself
+	PassphraseFdVariable = "BITMARK_PASSPHRASE_FD" // an open file descriptor to read one line from
+)
+
+// the passphrase is only obtained once and used for all key files
+var cached struct {
+	sync.Mutex
+	passphrase []byte
+}
+
+// the passphrase for decrypting key files
+//
+// taken from the first available of: the environment variable, a
+// line read from the file descriptor or a prompt on the terminal
+func Passphrase() ([]byte, error) {
+	cached.Lock()
+	defer cached.Unlock()
+
+	if nil != cached.passphrase {
+		return cached.passphrase, nil
+	}
+
+	passphrase, err := getPassphrase(false)
+	if nil != err {
+		return nil, err
+	}
+	cached.passphrase = passphrase
+	return passphrase, nil
+}
+
+// a passphrase for encrypting key files
+//
+// from the same sources as Passphrase, but a terminal prompt is
+// repeated to confirm it
+func NewPassphrase() ([]byte, error) {
+	return getPassphrase(true)
+}
+
+func getPassphrase(confirm bool) ([]byte, error) {
+
+	if s := os.Getenv(PassphraseVariable); "" != s {
+		return []byte(s), nil
+	}
+
+	if s := os.Getenv(PassphraseFdVariable); "" != s {
+		fd, err := strconv.ParseUint(s, 10, 31)
+		if nil != err {
+			return nil, err
+		}
+		line, err := bufio.NewReader(os.NewFile(uintptr(fd), PassphraseFdVariable)).ReadBytes('\n')
+		if 0 == len(line) && nil != err {
+			return nil, err
+		}
+		return nonEmpty(bytes.TrimRight(line, "\r\n"))
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return nil, fault.ErrNoPassphrase
+	}
+
+	fmt.Fprintf(os.Stderr, "key file passphrase: ")
+	passphrase, err := terminal.ReadPassword(fd)
+	fmt.Fprintf(os.Stderr, "\n")
+	if nil != err {
+		return nil, err
+	}
+	if confirm {
+		fmt.Fprintf(os.Stderr, "repeat passphrase: ")
+		again, err := terminal.ReadPassword(fd)
+		fmt.Fprintf(os.Stderr, "\n")
+		if nil != err {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, fault.ErrPassphrasesDiffer
+		}
+	}
+	return nonEmpty(passphrase)
+}
+
+func nonEmpty(passphrase []byte) ([]byte, error) {
+	if 0 == len(passphrase) {
+		return nil, fault.ErrNoPassphrase
+	}
+	return passphrase, nil
+}
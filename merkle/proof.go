@@ -0,0 +1,79 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package merkle
+
+import (
+	"github.com/bitmark-inc/bitmarkd/fault"
+)
+
+// compute the sibling digests needed to recompute the merkle root
+// from the id at position index
+//
+// the siblings are in order from the level of the ids up to the one
+// below the root; when a level has an odd number of digests the
+// last one is paired with itself so it is its own sibling
+func InclusionProof(ids []Digest, index int) ([]Digest, error) {
+
+	count := len(ids)
+	if index < 0 || index >= count {
+		return nil, fault.ErrInvalidCount
+	}
+
+	tree := FullMerkleTree(ids)
+
+	siblings := []Digest{}
+	start := 0
+	for width := count; width > 1; width = (width + 1) / 2 {
+		siblings = append(siblings, tree[start+siblingIndex(index, width)])
+		start += width
+		index /= 2
+	}
+	return siblings, nil
+}
+
+// check that an id is at position index of a tree of count ids
+// that has the given merkle root
+//
+// this only needs the values from a block header and an inclusion
+// proof so it can be used without access to the full block
+func VerifyInclusion(root Digest, id Digest, index int, count int, siblings []Digest) bool {
+
+	if index < 0 || index >= count {
+		return false
+	}
+
+	digest := id
+	level := 0
+	for width := count; width > 1; width = (width + 1) / 2 {
+		if level >= len(siblings) {
+			return false
+		}
+		sibling := siblings[level]
+		level += 1
+
+		if siblingIndex(index, width) == index && sibling != digest {
+			return false // odd digest at end of level must be paired with itself
+		}
+		if 0 == index%2 {
+			digest = NewDigest(append(digest[:], sibling[:]...))
+		} else {
+			digest = NewDigest(append(sibling[:], digest[:]...))
+		}
+		index /= 2
+	}
+	return len(siblings) == level && root == digest
+}
+
+// position of the digest that is hashed with the one at index
+// in a level of the tree containing width digests
+func siblingIndex(index int, width int) int {
+	if 1 == index%2 {
+		return index - 1
+	}
+	if index+1 == width {
+		return index
+	}
+	return index + 1
+}
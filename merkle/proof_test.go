@@ -0,0 +1,87 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package merkle_test
+
+import (
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"testing"
+)
+
+// create some distinct ids
+func makeIds(count int) []merkle.Digest {
+	ids := make([]merkle.Digest, count)
+	for i := range ids {
+		ids[i] = merkle.NewDigest([]byte{byte(i), byte(i >> 8)})
+	}
+	return ids
+}
+
+// every id in trees of various sizes can be proved
+func TestInclusionProof(t *testing.T) {
+
+	for count := 1; count <= 33; count += 1 {
+		ids := makeIds(count)
+		tree := merkle.FullMerkleTree(ids)
+		root := tree[len(tree)-1]
+
+		for index, id := range ids {
+			siblings, err := merkle.InclusionProof(ids, index)
+			if nil != err {
+				t.Fatalf("count: %d  index: %d  error: %v", count, index, err)
+			}
+			if !merkle.VerifyInclusion(root, id, index, count, siblings) {
+				t.Errorf("count: %d  index: %d  proof failed", count, index)
+			}
+		}
+	}
+}
+
+// a proof only works for its own id, position and tree
+func TestInclusionProofFails(t *testing.T) {
+
+	ids := makeIds(10)
+	tree := merkle.FullMerkleTree(ids)
+	root := tree[len(tree)-1]
+
+	siblings, err := merkle.InclusionProof(ids, 9)
+	if nil != err {
+		t.Fatalf("inclusion proof error: %v", err)
+	}
+	if 4 != len(siblings) {
+		t.Fatalf("siblings: %d  expected: 4", len(siblings))
+	}
+
+	altered := append([]merkle.Digest{}, siblings...)
+	altered[1][0] ^= 0x01
+
+	tests := []struct {
+		title    string
+		root     merkle.Digest
+		id       merkle.Digest
+		index    int
+		count    int
+		siblings []merkle.Digest
+	}{
+		{"wrong id", root, ids[8], 9, 10, siblings},
+		{"wrong index", root, ids[9], 8, 10, siblings},
+		{"wrong count", root, ids[9], 9, 20, siblings},
+		{"wrong root", ids[0], ids[9], 9, 10, siblings},
+		{"altered sibling", root, ids[9], 9, 10, altered},
+		{"missing sibling", root, ids[9], 9, 10, siblings[:3]},
+		{"extra sibling", root, ids[9], 9, 10, append(siblings, root)},
+		{"index out of range", root, ids[9], 10, 10, siblings},
+		{"negative index", root, ids[9], -1, 10, siblings},
+	}
+
+	for _, test := range tests {
+		if merkle.VerifyInclusion(test.root, test.id, test.index, test.count, test.siblings) {
+			t.Errorf("%s: proof was accepted", test.title)
+		}
+	}
+
+	if _, err := merkle.InclusionProof(ids, 10); nil == err {
+		t.Errorf("proof for index out of range did not fail")
+	}
+}
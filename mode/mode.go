@@ -27,6 +27,7 @@ var globals struct {
 	log     *logger.L
 	mode    Mode
 	testing bool
+	light   bool
 	chain   string
 }
 
@@ -99,6 +100,21 @@ func IsTesting() bool {
 	return globals.testing
 }
 
+// select header only operation
+// call before any blocks are stored
+func SetLight(light bool) {
+	globals.Lock()
+	globals.light = light
+	globals.Unlock()
+}
+
+// only block headers are stored and transactions are fetched from peers
+func IsLight() bool {
+	globals.RLock()
+	defer globals.RUnlock()
+	return globals.light
+}
+
 // name of the current chain
 func ChainName() string {
 	globals.RLock()
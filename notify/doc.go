@@ -0,0 +1,6 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// deliver ownership change events to subscribers watching accounts
+package notify
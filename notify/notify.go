@@ -0,0 +1,182 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"sync"
+	"time"
+)
+
+// limits for each subscription
+const (
+	MaximumOwners = 100  // accounts that can be watched
+	maximumQueued = 1000 // undelivered events before the oldest are dropped
+)
+
+// kinds of event
+const (
+	Issued   = "issued"   // a confirmed issue to the owner
+	Received = "received" // a confirmed transfer to the owner
+	Sent     = "sent"     // a confirmed transfer away from the owner
+	Pending  = "pending"  // a transfer to the owner is waiting in the reservoir
+	Burned   = "burned"   // a confirmed burn of a bitmark held by the owner
+	Offered  = "offered"  // a transfer to the owner is waiting for its acceptance
+)
+
+// an ownership change affecting a watched account
+type Event struct {
+	Kind        string           `json:"kind"`
+	Owner       *account.Account `json:"owner"`
+	TxId        merkle.Digest    `json:"txId"`
+	BlockNumber uint64           `json:"blockNumber,string,omitempty"` // zero for pending
+	Reversed    bool             `json:"reversed"`                     // the block was removed from the chain
+}
+
+// the events waiting for one subscriber
+type Subscription struct {
+	sync.Mutex
+	owners map[string]struct{}
+	events []Event
+	lost   int           // events dropped since the last delivery
+	ready  chan struct{} // signalled when events are queued
+}
+
+// all subscriptions by watched account
+var globalData = struct {
+	sync.Mutex
+	watched map[string]map[*Subscription]struct{}
+}{
+	watched: make(map[string]map[*Subscription]struct{}),
+}
+
+// create an empty subscription
+func NewSubscription() *Subscription {
+	return &Subscription{
+		owners: make(map[string]struct{}),
+		events: make([]Event, 0, 10),
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// start receiving events for an account
+func (s *Subscription) Watch(owner *account.Account) error {
+	globalData.Lock()
+	defer globalData.Unlock()
+	s.Lock()
+	defer s.Unlock()
+
+	key := string(owner.Bytes())
+	if _, ok := s.owners[key]; ok {
+		return nil
+	}
+	if len(s.owners) >= MaximumOwners {
+		return fault.ErrTooManyItemsToProcess
+	}
+	s.owners[key] = struct{}{}
+
+	subscribers, ok := globalData.watched[key]
+	if !ok {
+		subscribers = make(map[*Subscription]struct{})
+		globalData.watched[key] = subscribers
+	}
+	subscribers[s] = struct{}{}
+	return nil
+}
+
+// number of accounts being watched
+func (s *Subscription) Count() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.owners)
+}
+
+// stop all watches, any queued events are discarded
+func (s *Subscription) Close() {
+	globalData.Lock()
+	defer globalData.Unlock()
+	s.Lock()
+	defer s.Unlock()
+
+	for key := range s.owners {
+		subscribers := globalData.watched[key]
+		delete(subscribers, s)
+		if 0 == len(subscribers) {
+			delete(globalData.watched, key)
+		}
+	}
+	s.owners = make(map[string]struct{})
+	s.events = s.events[:0]
+}
+
+// wait for events up to the timeout
+// returns: up to count events and the number of events that were
+// dropped because they were not collected in time
+func (s *Subscription) Wait(count int, timeout time.Duration) ([]Event, int) {
+
+	s.Lock()
+	if 0 == len(s.events) {
+		s.Unlock()
+		select {
+		case <-s.ready:
+		case <-time.After(timeout):
+		}
+		s.Lock()
+	}
+	defer s.Unlock()
+
+	if count > len(s.events) {
+		count = len(s.events)
+	}
+	events := make([]Event, count)
+	copy(events, s.events)
+	s.events = append(s.events[:0], s.events[count:]...)
+
+	lost := s.lost
+	s.lost = 0
+
+	// more remain so keep any other waiter going
+	if 0 != len(s.events) {
+		s.signal()
+	}
+	return events, lost
+}
+
+// queue an event, must hold lock
+func (s *Subscription) add(event Event) {
+	if len(s.events) >= maximumQueued {
+		s.events = append(s.events[:0], s.events[1:]...)
+		s.lost += 1
+	}
+	s.events = append(s.events, event)
+	s.signal()
+}
+
+// wake a waiter without blocking
+func (s *Subscription) signal() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// deliver events to every subscription watching the owner of each event
+func Publish(events ...Event) {
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	for _, event := range events {
+		if nil == event.Owner {
+			continue
+		}
+		for s := range globalData.watched[string(event.Owner.Bytes())] {
+			s.Lock()
+			s.add(event)
+			s.Unlock()
+		}
+	}
+}
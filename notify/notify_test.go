@@ -0,0 +1,121 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package notify_test
+
+import (
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/notify"
+	"testing"
+	"time"
+)
+
+func makeAccount(n byte) *account.Account {
+	publicKey := make([]byte, 32)
+	publicKey[0] = n
+	return &account.Account{
+		AccountInterface: &account.ED25519Account{
+			Test:      true,
+			PublicKey: publicKey,
+		},
+	}
+}
+
+// only events for watched owners are delivered
+func TestWatch(t *testing.T) {
+	owner := makeAccount(1)
+	other := makeAccount(2)
+
+	s := notify.NewSubscription()
+	defer s.Close()
+
+	err := s.Watch(owner)
+	if nil != err {
+		t.Fatalf("watch error: %v", err)
+	}
+
+	notify.Publish(
+		notify.Event{Kind: notify.Issued, Owner: owner, TxId: merkle.Digest{1}, BlockNumber: 2},
+		notify.Event{Kind: notify.Pending, Owner: other, TxId: merkle.Digest{2}},
+		notify.Event{Kind: notify.Sent, Owner: owner, TxId: merkle.Digest{3}, BlockNumber: 3, Reversed: true},
+	)
+
+	events, lost := s.Wait(10, time.Second)
+	if 0 != lost {
+		t.Errorf("lost: %d  expected: 0", lost)
+	}
+	if 2 != len(events) {
+		t.Fatalf("events: %v  expected: 2 events", events)
+	}
+	if notify.Issued != events[0].Kind || (merkle.Digest{1}) != events[0].TxId {
+		t.Errorf("event[0]: %+v", events[0])
+	}
+	if notify.Sent != events[1].Kind || !events[1].Reversed {
+		t.Errorf("event[1]: %+v", events[1])
+	}
+}
+
+// a waiting subscriber is woken by a later event
+func TestWait(t *testing.T) {
+	owner := makeAccount(3)
+
+	s := notify.NewSubscription()
+	defer s.Close()
+	s.Watch(owner)
+
+	events, _ := s.Wait(10, 10*time.Millisecond)
+	if 0 != len(events) {
+		t.Errorf("events before publish: %v", events)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		notify.Publish(notify.Event{Kind: notify.Received, Owner: owner})
+	}()
+
+	events, _ = s.Wait(10, 5*time.Second)
+	if 1 != len(events) || notify.Received != events[0].Kind {
+		t.Errorf("events: %v  expected: one received event", events)
+	}
+}
+
+// closed subscriptions receive nothing
+func TestClose(t *testing.T) {
+	owner := makeAccount(4)
+
+	s := notify.NewSubscription()
+	s.Watch(owner)
+	s.Close()
+
+	notify.Publish(notify.Event{Kind: notify.Received, Owner: owner})
+	events, _ := s.Wait(10, 10*time.Millisecond)
+	if 0 != len(events) {
+		t.Errorf("events after close: %v", events)
+	}
+	if 0 != s.Count() {
+		t.Errorf("count after close: %d", s.Count())
+	}
+}
+
+// the oldest events are dropped if they are not collected
+func TestOverflow(t *testing.T) {
+	owner := makeAccount(5)
+
+	s := notify.NewSubscription()
+	defer s.Close()
+	s.Watch(owner)
+
+	for i := 0; i < 1005; i += 1 {
+		notify.Publish(notify.Event{Kind: notify.Pending, Owner: owner, BlockNumber: uint64(i)})
+	}
+
+	events, lost := s.Wait(100, time.Second)
+	if 5 != lost {
+		t.Errorf("lost: %d  expected: 5", lost)
+	}
+	if 100 != len(events) || 5 != events[0].BlockNumber {
+		t.Errorf("first event: %+v  count: %d", events[0], len(events))
+	}
+}
@@ -212,5 +212,7 @@ func scanTx(log *logger.L, payId pay.PayId, payIdIndex int, tx *bitcoinTransacti
 		packed = append(packed, util.ToVarint64(value)...)
 	}
 
-	storage.Pool.Payment.Put(payId[:], packed)
+	// keyed by transaction so that top ups for the same pay id are kept
+	// separately and rescanning a block does not count a payment twice
+	storage.Pool.Payment.Put(append(payId[:], txId...), packed)
 }
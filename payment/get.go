@@ -6,7 +6,6 @@ package payment
 
 import (
 	"bytes"
-	"encoding/binary"
 	"github.com/bitmark-inc/bitmarkd/currency"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/merkle"
@@ -14,8 +13,12 @@ import (
 	"github.com/bitmark-inc/bitmarkd/transactionrecord"
 )
 
-// get payment record from a specific block given the blocks 8 byte big endian key
-func GetPayments(ownerData []byte, previousTransfer *transactionrecord.BitmarkTransfer) []*transactionrecord.Payment {
+// get the payments needed to transfer a bitmark
+//
+// there is one alternative for each currency that all of the block
+// owners involved can be paid in, a client picks one alternative and
+// pays everything in it using a single currency transaction
+func GetPayments(ownerData []byte, previousPayment *transactionrecord.Payment) []transactionrecord.PaymentAlternative {
 
 	// get block number of transfer and issue; see: storage/doc.go to determine offsets
 	const transferBlockNumberOffset = merkle.DigestLength
@@ -24,41 +27,140 @@ func GetPayments(ownerData []byte, previousTransfer *transactionrecord.BitmarkTr
 	tKey := ownerData[transferBlockNumberOffset : transferBlockNumberOffset+8]
 	iKey := ownerData[issueBlockNumberOffset : issueBlockNumberOffset+8]
 
-	// block owner (from issue) payment
-	// 0: issue block owner
-	// 1: last transfer block owner (could be merged to 1 if same address)
-	// 2: transfer payment (optional)
-	payments := make([]*transactionrecord.Payment, 1, 3)
-	payments[0] = getPayment(iKey) // should never be nil
-
-	// last transfer payment if there is one otherwise add it issuer
-	p := getPayment(tKey)
-	if nil == p {
-		// no transfer payment so issuer get double
-		payments[0].Amount *= 2
-	} else if p.Currency == payments[0].Currency && p.Address == payments[0].Address {
-		// transfer and issuer are the same so accumulate amount
-		payments[0].Amount += p.Amount
-	} else {
-		// separate transfer payment
-		payments = append(payments, p)
+	issuePayments := getPayments(iKey)    // should never be nil
+	transferPayments := getPayments(tKey) // nil for an issue
+
+	alternatives := make([]transactionrecord.PaymentAlternative, 0, len(issuePayments))
+
+nextCurrency:
+	for _, issuePayment := range issuePayments {
+
+		// block owner (from issue) payment
+		// 0: issue block owner
+		// 1: last transfer block owner (could be merged to 1 if same address)
+		// 2: transfer payment (optional)
+		payments := make(transactionrecord.PaymentAlternative, 1, 3)
+		payments[0] = issuePayment
+
+		// last transfer payment if there is one otherwise add it issuer
+		if nil == transferPayments {
+			// no transfer payment so issuer get double
+			payments[0].Amount *= 2
+		} else {
+			p := findCurrency(transferPayments, issuePayment.Currency)
+			if nil == p {
+				// the transfer block owner cannot be paid in this currency
+				continue nextCurrency
+			} else if p.Address == payments[0].Address {
+				// transfer and issuer are the same so accumulate amount
+				payments[0].Amount += p.Amount
+			} else {
+				// separate transfer payment
+				payments = append(payments, p)
+			}
+		}
+
+		// optional payment record (if previous record was transfer and contains such)
+		if nil != previousPayment {
+			if previousPayment.Currency != issuePayment.Currency {
+				continue nextCurrency
+			}
+			// always keep this as a separate amount even if address is the same
+			// so it shows up separately in currency transaction
+			payments = append(payments, previousPayment)
+		}
+
+		alternatives = append(alternatives, payments)
 	}
 
-	// optional payment record (if previous record was transfer and contains such)
-	if nil != previousTransfer && nil != previousTransfer.Payment {
-		// always keep this as a separate amount even if address is the same
-		// so it shows up separately in currency transaction
-		payments = append(payments, previousTransfer.Payment)
+	return alternatives
+}
+
+// get the payments needed for a set of issues
+//
+// the fee for each issue is paid to the owner of a specific block
+// given the blocks 8 byte big endian key, there is one alternative for
+// each currency that the block owner accepts
+func GetIssuePayments(blockNumberKey []byte, count int) []transactionrecord.PaymentAlternative {
+
+	payments := getPayments(blockNumberKey)
+
+	alternatives := make([]transactionrecord.PaymentAlternative, 0, len(payments))
+	for _, p := range payments {
+		p.Amount *= uint64(count)
+		alternatives = append(alternatives, transactionrecord.PaymentAlternative{p})
 	}
+	return alternatives
+}
 
-	return payments
+// the payment in a specific currency
+func findCurrency(payments []*transactionrecord.Payment, c currency.Currency) *transactionrecord.Payment {
+	for _, p := range payments {
+		if c == p.Currency {
+			return p
+		}
+	}
+	return nil
 }
 
-// get a payment record from a specific block given the blocks 8 byte big endian key
-func getPayment(blockNumberKey []byte) *transactionrecord.Payment {
+// combine the payment alternatives for several bitmarks into a single quote
+//
+// only the currencies offered for every bitmark remain; within each
+// currency amounts for the same address are added together, the order
+// in which each address first appears is kept
+func MergePayments(lists ...[]transactionrecord.PaymentAlternative) []transactionrecord.PaymentAlternative {
+
+	if 0 == len(lists) {
+		return nil
+	}
+
+	merged := make([]transactionrecord.PaymentAlternative, 0, len(lists[0]))
+
+nextCurrency:
+	for _, first := range lists[0] {
+		c := first[0].Currency
+
+		payments := make(transactionrecord.PaymentAlternative, 0, 3)
+		index := make(map[string]int)
+		for _, alternatives := range lists {
+			alternative := findAlternative(alternatives, c)
+			if nil == alternative {
+				continue nextCurrency
+			}
+			for _, p := range alternative {
+				if i, ok := index[p.Address]; ok {
+					payments[i].Amount += p.Amount
+					continue
+				}
+				index[p.Address] = len(payments)
+				payments = append(payments, &transactionrecord.Payment{
+					Currency: p.Currency,
+					Address:  p.Address,
+					Amount:   p.Amount,
+				})
+			}
+		}
+		merged = append(merged, payments)
+	}
+	return merged
+}
+
+// the alternative in a specific currency
+func findAlternative(alternatives []transactionrecord.PaymentAlternative, c currency.Currency) transactionrecord.PaymentAlternative {
+	for _, a := range alternatives {
+		if 0 != len(a) && c == a[0].Currency {
+			return a
+		}
+	}
+	return nil
+}
+
+// get the payment records for every currency accepted by the owner
+// of a specific block given the blocks 8 byte big endian key
+func getPayments(blockNumberKey []byte) []*transactionrecord.Payment {
 
 	if 8 != len(blockNumberKey) {
-		fault.Panicf("payment.getPayment: block number need 8 bytes: %x", blockNumberKey)
+		fault.Panicf("payment.getPayments: block number need 8 bytes: %x", blockNumberKey)
 	}
 
 	// if all 8 bytes are zero then no transfer payment as this is an issue
@@ -68,22 +170,26 @@ func getPayment(blockNumberKey []byte) *transactionrecord.Payment {
 
 	blockOwnerData := storage.Pool.BlockOwners.Get(blockNumberKey)
 	if nil == blockOwnerData {
-		fault.Panicf("payment.getPayment: no block owner data for block number: %x", blockNumberKey)
+		fault.Panicf("payment.getPayments: no block owner data for block number: %x", blockNumberKey)
 	}
 
-	c, err := currency.FromUint64(binary.BigEndian.Uint64(blockOwnerData[:8]))
+	addresses, _, err := transactionrecord.UnpackPaymentAddresses(blockOwnerData)
 	if nil != err {
-		fault.Panicf("payment.getPayment: block currency invalid error: %v", err)
+		fault.Panicf("payment.getPayments: block owner data invalid error: %v", err)
 	}
 
-	fee, err := c.GetFee()
-	if nil != err {
-		fault.Panicf("payment.getPayment: get fee returned error: %v", err)
-	}
-
-	return &transactionrecord.Payment{
-		Currency: c,
-		Address:  string(blockOwnerData[8:]),
-		Amount:   fee,
+	payments := make([]*transactionrecord.Payment, len(addresses))
+	for i, a := range addresses {
+		fee, err := a.Currency.GetFee()
+		if nil != err {
+			fault.Panicf("payment.getPayments: get fee returned error: %v", err)
+		}
+
+		payments[i] = &transactionrecord.Payment{
+			Currency: a.Currency,
+			Address:  a.Address,
+			Amount:   fee,
+		}
 	}
+	return payments
 }
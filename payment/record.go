@@ -0,0 +1,87 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package payment
+
+import (
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/util"
+)
+
+// a payment found on a currency's chain
+type Record struct {
+	Currency currency.Currency
+	TxId     []byte
+	Amounts  map[string]uint64 // address → total value paid to it
+}
+
+// unpack a payment confirmation record; see: storage/doc.go for the format
+//
+//   currency(varint) ++ txId_bytes(varint) ++ txId ++ [ count(varint) ++ address ++ value(varint) ]
+//
+// each address is preceded by its length as a varint
+func UnpackRecord(packed []byte) (*Record, error) {
+
+	c, n := util.FromVarint64(packed)
+	if 0 == n {
+		return nil, fault.ErrInvalidCount
+	}
+	packed = packed[n:]
+
+	cur, err := currency.FromUint64(c)
+	if nil != err {
+		return nil, err
+	}
+
+	txId, packed, err := nextBytes(packed)
+	if nil != err {
+		return nil, err
+	}
+
+	count, n := util.FromVarint64(packed)
+	if 0 == n {
+		return nil, fault.ErrInvalidCount
+	}
+	packed = packed[n:]
+
+	record := &Record{
+		Currency: cur,
+		TxId:     txId,
+		Amounts:  make(map[string]uint64),
+	}
+
+	for i := uint64(0); i < count; i += 1 {
+		var address []byte
+		address, packed, err = nextBytes(packed)
+		if nil != err {
+			return nil, err
+		}
+		value, n := util.FromVarint64(packed)
+		if 0 == n {
+			return nil, fault.ErrInvalidCount
+		}
+		packed = packed[n:]
+
+		record.Amounts[string(address)] += value
+	}
+
+	if 0 != len(packed) {
+		return nil, fault.ErrInvalidLength
+	}
+	return record, nil
+}
+
+// split a varint length prefixed byte string from the front of a buffer
+func nextBytes(packed []byte) ([]byte, []byte, error) {
+	length, n := util.FromVarint64(packed)
+	if 0 == n {
+		return nil, nil, fault.ErrInvalidCount
+	}
+	packed = packed[n:]
+	if uint64(len(packed)) < length {
+		return nil, nil, fault.ErrInvalidLength
+	}
+	return packed[:length], packed[length:], nil
+}
@@ -4,10 +4,13 @@
 package peer
 
 import (
+	"bytes"
+	"encoding/hex"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/util"
 	"github.com/bitmark-inc/bitmarkd/zmqutil"
 	"github.com/bitmark-inc/logger"
+	zmq "github.com/pebbe/zmq4"
 )
 
 // priority offsets
@@ -103,3 +106,72 @@ dynamicScan:
 	}
 	return err
 }
+
+// allocate request sockets: the static connections are connected
+// first, followed by unconnected sockets for the dynamic connections
+func newClients(log *logger.L, privateKey []byte, publicKey []byte, connect []Connection) ([]*zmqutil.Client, error) {
+
+	clients := make([]*zmqutil.Client, len(connect)+offsetCount)
+
+	// error code for goto fail
+	errX := error(nil)
+
+	// initially connect all static sockets
+	for i, c := range connect {
+		address, err := util.NewConnection(c.Address)
+		if nil != err {
+			log.Errorf("client[%d]=address: %q  error: %v", i, c.Address, err)
+			errX = err
+			goto fail
+		}
+		serverPublicKey, err := hex.DecodeString(c.PublicKey)
+		if nil != err {
+			log.Errorf("client[%d]=public: %q  error: %v", i, c.PublicKey, err)
+			errX = err
+			goto fail
+		}
+
+		// prevent connection to self
+		if bytes.Equal(publicKey, serverPublicKey) {
+			errX = fault.ErrConnectingToSelfForbidden
+			log.Errorf("client[%d]=public: %q  error: %v", i, c.PublicKey, errX)
+			goto fail
+		}
+
+		client, err := zmqutil.NewClient(zmq.REQ, privateKey, publicKey, connectorTimeout)
+		if nil != err {
+			log.Errorf("client[%d]=%q  error: %v", i, address, err)
+			errX = err
+			goto fail
+		}
+
+		clients[i] = client
+
+		err = client.Connect(address, serverPublicKey)
+		if nil != err {
+			log.Errorf("connect[%d]=%q  error: %v", i, address, err)
+			errX = err
+			goto fail
+		}
+		log.Infof("public key: %x  at: %q", serverPublicKey, c.Address)
+	}
+
+	// just create sockets for dynamic clients
+	for i := len(connect); i < len(clients); i += 1 {
+		client, err := zmqutil.NewClient(zmq.REQ, privateKey, publicKey, connectorTimeout)
+		if nil != err {
+			log.Errorf("client[%d]  error: %v", i, err)
+			errX = err
+			goto fail
+		}
+
+		clients[i] = client
+	}
+
+	return clients, nil
+
+	// error handling
+fail:
+	zmqutil.CloseClients(clients)
+	return nil, errX
+}
@@ -5,9 +5,7 @@
 package peer
 
 import (
-	"bytes"
 	"encoding/binary"
-	"encoding/hex"
 	"github.com/bitmark-inc/bitmarkd/announce"
 	"github.com/bitmark-inc/bitmarkd/block"
 	"github.com/bitmark-inc/bitmarkd/blockdigest"
@@ -15,10 +13,9 @@ import (
 	"github.com/bitmark-inc/bitmarkd/genesis"
 	"github.com/bitmark-inc/bitmarkd/messagebus"
 	"github.com/bitmark-inc/bitmarkd/mode"
-	"github.com/bitmark-inc/bitmarkd/util"
 	"github.com/bitmark-inc/bitmarkd/zmqutil"
 	"github.com/bitmark-inc/logger"
-	zmq "github.com/pebbe/zmq4"
+	"math/big"
 	"time"
 )
 
@@ -28,6 +25,7 @@ const (
 	connectorTimeout    = 60 * time.Second // time out for connections
 	samplelingLimit     = 10               // number of cycles to be 1 block out of sync before resync
 	fetchBlocksPerCycle = 100              // number of blocks to fetch in one set
+	maximumBranchLength = 1000             // most blocks to fetch for a competing branch
 )
 
 // a state type for the thread
@@ -39,6 +37,7 @@ const (
 	cStateHighestBlock connectorState = iota // locate node(s) with highest block number
 	cStateForkDetect   connectorState = iota // read block hashes to check for possible fork
 	cStateFetchBlocks  connectorState = iota // fetch blocks from current or fork point
+	cStateReorganise   connectorState = iota // fetch a heavier competing branch and switch to it
 	cStateRebuild      connectorState = iota // rebuild database from fork point (config setting to force total rebuild)
 	cStateSampling     connectorState = iota // signal resync complete and sample nodes to see if out of sync occurs
 )
@@ -49,10 +48,12 @@ type connector struct {
 	dynamicStart int
 	state        connectorState
 
-	theClient          *zmqutil.Client // client to fetch blocak data from
-	startBlockNumber   uint64          // block number wher local chain forks
-	highestBlockNumber uint64          // block number on best node
-	samples            int             // counter to detect missed block broadcast
+	theClient          *zmqutil.Client         // client to fetch blocak data from
+	startBlockNumber   uint64                  // block number wher local chain forks
+	highestBlockNumber uint64                  // block number on best node
+	highestWork        *big.Int                // total chain work on best node (nil if not supported)
+	penalties          map[*zmqutil.Client]int // bad block responses from each peer
+	samples            int                     // counter to detect missed block broadcast
 }
 
 // initialise the connector
@@ -72,74 +73,20 @@ func (conn *connector) initialise(privateKey []byte, publicKey []byte, connect [
 		log.Error("zero static connections and dynamic is disabled")
 		return fault.ErrNoConnectionsAvailable
 	}
-	conn.clients = make([]*zmqutil.Client, staticCount+offsetCount)
+	clients, err := newClients(log, privateKey, publicKey, connect)
+	if nil != err {
+		return err
+	}
+	conn.clients = clients
 	conn.dynamicStart = staticCount // index of first dynamic socket
 	globalData.connectorClients = conn.clients
 
-	// error code for goto fail
-	errX := error(nil)
-
-	// initially connect all static sockets
-	for i, c := range connect {
-		address, err := util.NewConnection(c.Address)
-		if nil != err {
-			log.Errorf("client[%d]=address: %q  error: %v", i, c.Address, err)
-			errX = err
-			goto fail
-		}
-		serverPublicKey, err := hex.DecodeString(c.PublicKey)
-		if nil != err {
-			log.Errorf("client[%d]=public: %q  error: %v", i, c.PublicKey, err)
-			errX = err
-			goto fail
-		}
-
-		// prevent connection to self
-		if bytes.Equal(publicKey, serverPublicKey) {
-			errX = fault.ErrConnectingToSelfForbidden
-			log.Errorf("client[%d]=public: %q  error: %v", i, c.PublicKey, errX)
-			goto fail
-		}
-
-		client, err := zmqutil.NewClient(zmq.REQ, privateKey, publicKey, connectorTimeout)
-		if nil != err {
-			log.Errorf("client[%d]=%q  error: %v", i, address, err)
-			errX = err
-			goto fail
-		}
-
-		conn.clients[i] = client
-
-		err = client.Connect(address, serverPublicKey)
-		if nil != err {
-			log.Errorf("connect[%d]=%q  error: %v", i, address, err)
-			errX = err
-			goto fail
-		}
-		log.Infof("public key: %x  at: %q", serverPublicKey, c.Address)
-	}
-
-	// just create sockets for dynamic clients
-	for i := conn.dynamicStart; i < len(conn.clients); i += 1 {
-		client, err := zmqutil.NewClient(zmq.REQ, privateKey, publicKey, connectorTimeout)
-		if nil != err {
-			log.Errorf("client[%d]  error: %v", i, err)
-			errX = err
-			goto fail
-		}
-
-		conn.clients[i] = client
-	}
+	conn.penalties = make(map[*zmqutil.Client]int)
 
 	// start state machine
 	conn.state = cStateConnecting
 
 	return nil
-
-	// error handling
-fail:
-	zmqutil.CloseClients(conn.clients)
-	return errX
 }
 
 // various RPC calls to upstream connections
@@ -199,94 +146,159 @@ func (conn *connector) runStateMachine() bool {
 		continueLooping = false
 
 	case cStateHighestBlock:
-		conn.highestBlockNumber, conn.theClient = highestBlock(log, conn.clients)
+		conn.highestBlockNumber, conn.highestWork, conn.theClient = heaviestChain(log, conn.fullPeers())
 		if conn.highestBlockNumber > 0 && nil != conn.theClient {
 			conn.state += 1
 		} else {
 			continueLooping = false
 		}
-		log.Infof("highest block number: %d", conn.highestBlockNumber)
+		log.Infof("highest block number: %d  work: %v", conn.highestBlockNumber, conn.highestWork)
 
 	case cStateForkDetect:
 		h := block.GetHeight()
-		if conn.highestBlockNumber <= h {
+		if !conn.isHeavier(h) {
 			conn.state = cStateRebuild
-		} else {
-			// first block number
-			conn.startBlockNumber = genesis.BlockNumber + 1
-			conn.state += 1 // assume success
-			log.Infof("block number: %d", h)
-
-			// check digests of descending blocks (to detect a fork)
-			for ; h > genesis.BlockNumber; h -= 1 {
-				digest, err := block.DigestForBlock(h)
-				if nil != err {
-					log.Infof("block number: %d  local digest error: %v", h, err)
-					conn.state = cStateHighestBlock // retry
-					break
-				}
-				d, err := blockDigest(conn.theClient, h)
-				if nil != err {
-					log.Infof("block number: %d  fetch digest error: %v", h, err)
-					conn.state = cStateHighestBlock // retry
-					break
-				} else if d == digest {
-					conn.startBlockNumber = h + 1
-					log.Infof("fork from block number: %d", conn.startBlockNumber)
-
-					// remove old blocks
-					err := block.DeleteDownToBlock(conn.startBlockNumber)
-					if nil != err {
-						log.Errorf("delete down to block number: %d  error: %v", conn.startBlockNumber, err)
-						conn.state = cStateHighestBlock // retry
-					}
-					break
-				}
+			break
+		}
+
+		// first block number
+		conn.startBlockNumber = genesis.BlockNumber + 1
+		conn.state = cStateFetchBlocks // assume success
+		log.Infof("block number: %d", h)
+
+		// compare digests of descending blocks (to detect a fork)
+		n := h
+		if n > conn.highestBlockNumber {
+			n = conn.highestBlockNumber
+		}
+		for ; n > genesis.BlockNumber; n -= 1 {
+			digest, err := block.DigestForBlock(n)
+			if nil != err {
+				log.Infof("block number: %d  local digest error: %v", n, err)
+				conn.state = cStateHighestBlock // retry
+				break
+			}
+			d, err := blockDigest(conn.theClient, n)
+			if nil != err {
+				log.Infof("block number: %d  fetch digest error: %v", n, err)
+				conn.state = cStateHighestBlock // retry
+				break
+			} else if d == digest {
+				conn.startBlockNumber = n + 1
+				break
 			}
 		}
 
+		// local blocks above the common block must be replaced
+		if cStateFetchBlocks == conn.state && conn.startBlockNumber <= h {
+			log.Infof("fork from block number: %d", conn.startBlockNumber)
+			conn.state = cStateReorganise
+		}
+
 	case cStateFetchBlocks:
 
 		continueLooping = false
 
-		for n := 0; n < fetchBlocksPerCycle; n += 1 {
+		if conn.startBlockNumber > conn.highestBlockNumber {
+			conn.state = cStateHighestBlock // just in case block height has changed
+			continueLooping = true
+			break
+		}
 
-			if conn.startBlockNumber > conn.highestBlockNumber {
-				conn.state = cStateHighestBlock // just in case block height has changed
-				continueLooping = true
-				break
+		// fetch from every connected full peer at once
+		sources := conn.fullPeers()
+		penalties := make([]int, len(sources))
+		for i, client := range sources {
+			penalties[i] = conn.penalties[client]
+		}
+
+		last := conn.highestBlockNumber
+		if n := uint64(fetchBlocksPerCycle * len(sources)); last-conn.startBlockNumber >= n {
+			last = conn.startBlockNumber + n - 1
+		}
+
+		log.Infof("fetch block numbers: %d..%d  from: %d peers", conn.startBlockNumber, last, len(sources))
+		d := downloader{
+			log: log,
+			fetch: func(source int, number uint64) ([]byte, error) {
+				return blockData(sources[source], number)
+			},
+			store:     block.StoreIncoming,
+			height:    block.GetHeight,
+			penalties: penalties,
+		}
+		if mode.IsLight() {
+			d.fetchRange = func(source int, first uint64, last uint64) ([][]byte, error) {
+				return headerData(sources[source], first, last)
 			}
+			d.store = block.StoreHeader
+		}
+		next, err := d.download(conn.startBlockNumber, last)
+
+		for i, client := range sources {
+			conn.penalties[client] = penalties[i]
+		}
+		conn.startBlockNumber = next
+
+		if nil != err {
+			log.Errorf("fetch block number: %d  error: %v", next, err)
+			conn.state = cStateHighestBlock // retry
+		}
+
+	case cStateReorganise:
+
+		continueLooping = false
+		conn.state = cStateHighestBlock // rescan after any outcome
+
+		// a partial branch is still accepted if it is already heavier
+		last := conn.highestBlockNumber
+		if last-conn.startBlockNumber+1 > maximumBranchLength {
+			last = conn.startBlockNumber + maximumBranchLength - 1
+		}
 
-			log.Infof("fetch block number: %d", conn.startBlockNumber)
-			packedBlock, err := blockData(conn.theClient, conn.startBlockNumber)
+		branch := make([][]byte, 0, last-conn.startBlockNumber+1)
+		if mode.IsLight() {
+			log.Infof("fetch branch headers: %d..%d", conn.startBlockNumber, last)
+			headers, err := headerData(conn.theClient, conn.startBlockNumber, last)
 			if nil != err {
-				log.Errorf("fetch block number: %d  error: %v", conn.startBlockNumber, err)
-				conn.state = cStateHighestBlock // retry
+				log.Errorf("fetch branch headers: %d..%d  error: %v", conn.startBlockNumber, last, err)
 				break
 			}
-			log.Debugf("store block number: %d", conn.startBlockNumber)
-			err = block.StoreIncoming(packedBlock)
-			if nil != err {
-				log.Errorf("store block number: %d  error: %v", conn.startBlockNumber, err)
-				conn.state = cStateHighestBlock // retry
-				break
+			branch = append(branch, headers...)
+		} else {
+			for n := conn.startBlockNumber; n <= last; n += 1 {
+				log.Infof("fetch branch block number: %d", n)
+				packedBlock, err := blockData(conn.theClient, n)
+				if nil != err {
+					log.Errorf("fetch branch block number: %d  error: %v", n, err)
+					break
+				}
+				branch = append(branch, packedBlock)
 			}
+		}
+		if len(branch) != cap(branch) {
+			break
+		}
 
-			// next block
-			conn.startBlockNumber += 1
-
+		err := block.Reorganise(branch)
+		if nil != err {
+			log.Errorf("reorganise from block number: %d  error: %v", conn.startBlockNumber, err)
+			break
 		}
+		log.Infof("reorganised from block number: %d  to: %d", conn.startBlockNumber, last)
+		continueLooping = true
 
 	case cStateRebuild:
 		// return to normal operations
 		conn.state += 1  // next state
 		conn.samples = 0 // zero out the counter
+		conn.penalties = make(map[*zmqutil.Client]int)
 		mode.Set(mode.Normal)
 		continueLooping = false
 
 	case cStateSampling:
 		// check peers
-		conn.highestBlockNumber, conn.theClient = highestBlock(log, conn.clients)
+		conn.highestBlockNumber, conn.highestWork, conn.theClient = heaviestChain(log, conn.fullPeers())
 		height := block.GetHeight()
 
 		log.Infof("remote height: %d", conn.highestBlockNumber)
@@ -294,8 +306,8 @@ func (conn *connector) runStateMachine() bool {
 
 		continueLooping = false
 
-		if conn.highestBlockNumber > height {
-			if conn.highestBlockNumber-height >= 2 {
+		if nil != conn.theClient && conn.isHeavier(height) {
+			if conn.highestBlockNumber >= height+2 || conn.highestBlockNumber < height {
 				conn.state = cStateForkDetect
 				continueLooping = true
 			} else {
@@ -310,6 +322,27 @@ func (conn *connector) runStateMachine() bool {
 	return continueLooping
 }
 
+// connected peers that have full blocks, light peers only have
+// headers so cannot supply blocks
+func (conn *connector) fullPeers() []*zmqutil.Client {
+	peers := []*zmqutil.Client{}
+	for _, client := range conn.clients {
+		if client.IsConnected() && isFullPeer(conn.log, client) {
+			peers = append(peers, client)
+		}
+	}
+	return peers
+}
+
+// check if the best remote chain has more work than the local chain
+// peers that cannot report work are compared by height
+func (conn *connector) isHeavier(height uint64) bool {
+	if nil == conn.highestWork {
+		return conn.highestBlockNumber > height
+	}
+	return conn.highestWork.Cmp(block.GetWork()) > 0
+}
+
 // ***** FIX THIS: is this needed
 // func CheckServer(client *zmqutil.Client) error {
 
@@ -396,84 +429,120 @@ func register(log *logger.L, clients []*zmqutil.Client) bool {
 	return n > 0 // if registration occured
 }
 
-// determine client with highest block
-func highestBlock(log *logger.L, clients []*zmqutil.Client) (uint64, *zmqutil.Client) {
+// determine client with the chain that has the most work
+//
+// peers that do not support the work request are asked for their
+// height and are only chosen if no peer reports its work
+func heaviestChain(log *logger.L, clients []*zmqutil.Client) (uint64, *big.Int, *zmqutil.Client) {
 
 	h := uint64(0)
+	w := (*big.Int)(nil)
 	c := (*zmqutil.Client)(nil)
 
-client_loop:
 	for _, client := range clients {
 		if !client.IsConnected() {
-			continue client_loop
+			continue
 		}
 
-		log.Infof("highestBlock: fetch from: %s", client)
-
-		ok := false
-		data := [][]byte{}
+		log.Infof("heaviestChain: fetch from: %s", client)
 
-	retrying:
-		for retry := 1; retry <= 3; retry += 1 {
-			log.Infof("highestBlock: retry: %d", retry)
-
-			err := client.Send("N")
-			if nil != err {
-				log.Errorf("highestBlock: send error: %v", err)
-				client.Reconnect()
-				if nil != err {
-					log.Errorf("reconnect error: %v", err)
-				}
-				time.Sleep(100 * time.Millisecond)
-				continue retrying
-			}
+		n, work, ok := chainWork(log, client)
+		if !ok {
+			continue
+		}
 
-			data, err = client.Receive(0)
-			if nil != err {
-				log.Errorf("highestBlock: receive error: %v", err)
-				log.Error("highestBlock: reconnecting…")
-				err := client.Reconnect()
-				if nil != err {
-					log.Errorf("highestBlock: reconnect error: %v", err)
-					time.Sleep(500 * time.Millisecond)
-					err := client.Reconnect()
-					if nil != err {
-						log.Errorf("highestBlock: retry reconnect error: %v", err)
-					}
-				}
-				time.Sleep(100 * time.Millisecond)
-				continue retrying
-			}
-			if 2 != len(data) {
-				log.Errorf("highestBlock: received: %d  expected: 2", len(data))
-				continue retrying
+		if nil == work {
+			if nil == w && n > h {
+				h = n
+				c = client
 			}
-			ok = true
-			break retrying // success
+		} else if nil == w || work.Cmp(w) > 0 {
+			h = n
+			w = work
+			c = client
 		}
+	}
+	return h, w, c
+}
+
+// fetch the height and total work of a peer's chain
+//
+// an older peer that rejects the work request is asked for its
+// height only and the work is returned as nil
+func chainWork(log *logger.L, client *zmqutil.Client) (uint64, *big.Int, bool) {
+
+	for _, command := range []string{"W", "N"} {
+
+		data, ok := queryPeer(log, client, command)
 		if !ok {
-			log.Error("highestBlock: all retries failed")
-			continue client_loop
+			return 0, nil, false
 		}
 
 		switch string(data[0]) {
 		case "E":
-			log.Errorf("highestBlock: rpc error response: %q", data[1])
-			continue client_loop
+			log.Errorf("heaviestChain: rpc error response: %q", data[1])
+			continue // older peer: retry for height only
 		case "N":
 			if 8 != len(data[1]) {
-				continue client_loop
+				return 0, nil, false
 			}
-			n := binary.BigEndian.Uint64(data[1])
-
-			if n > h {
-				h = n
-				c = client
+			return binary.BigEndian.Uint64(data[1]), nil, true
+		case "W":
+			if len(data[1]) < 8 {
+				return 0, nil, false
 			}
+			n := binary.BigEndian.Uint64(data[1][:8])
+			work := new(big.Int).SetBytes(data[1][8:])
+			return n, work, true
 		default:
+			return 0, nil, false
+		}
+	}
+	return 0, nil, false
+}
+
+// send a request to a peer, reconnecting and retrying on failure
+func queryPeer(log *logger.L, client *zmqutil.Client, command string) ([][]byte, bool) {
+
+retrying:
+	for retry := 1; retry <= 3; retry += 1 {
+		log.Infof("heaviestChain: retry: %d", retry)
+
+		err := client.Send(command)
+		if nil != err {
+			log.Errorf("heaviestChain: send error: %v", err)
+			client.Reconnect()
+			if nil != err {
+				log.Errorf("reconnect error: %v", err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			continue retrying
+		}
+
+		data, err := client.Receive(0)
+		if nil != err {
+			log.Errorf("heaviestChain: receive error: %v", err)
+			log.Error("heaviestChain: reconnecting…")
+			err := client.Reconnect()
+			if nil != err {
+				log.Errorf("heaviestChain: reconnect error: %v", err)
+				time.Sleep(500 * time.Millisecond)
+				err := client.Reconnect()
+				if nil != err {
+					log.Errorf("heaviestChain: retry reconnect error: %v", err)
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+			continue retrying
+		}
+		if 2 != len(data) {
+			log.Errorf("heaviestChain: received: %d  expected: 2", len(data))
+			continue retrying
 		}
+		return data, true // success
 	}
-	return h, c
+	log.Error("heaviestChain: all retries failed")
+	return nil, false
 }
 
 // fetch block digest
@@ -550,6 +619,8 @@ func (state connectorState) String() string {
 		return "ForkDetect"
 	case cStateFetchBlocks:
 		return "FetchBlocks"
+	case cStateReorganise:
+		return "Reorganise"
 	case cStateRebuild:
 		return "Rebuild"
 	case cStateSampling:
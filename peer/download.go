@@ -0,0 +1,277 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/logger"
+	"sort"
+	"sync"
+)
+
+// download limits
+const (
+	downloadRangeSize = 10 // number of blocks requested from a peer at one time
+	downloadAhead     = 8  // ranges that may be outstanding beyond the next block to store
+	maximumPenalty    = 3  // bad responses before a peer is no longer used
+)
+
+// fetch a single block from one of the download sources
+type fetchFunc func(source int, number uint64) ([]byte, error)
+
+// fetch consecutive blocks first..last from one of the download sources
+type fetchRangeFunc func(source int, first uint64, last uint64) ([][]byte, error)
+
+// a set of consecutive blocks to be fetched from one source
+type blockRange struct {
+	first uint64
+	last  uint64
+	tried map[int]bool // sources that already failed to supply this range
+}
+
+// the outcome of fetching a range
+type rangeResult struct {
+	source int
+	r      *blockRange
+	blocks [][]byte
+	err    error
+	bad    bool // the data did not match the request
+}
+
+// schedule block fetches across several sources and store the
+// results strictly in block number order
+type downloader struct {
+	log        *logger.L
+	fetch      fetchFunc
+	fetchRange fetchRangeFunc // used instead of fetch if set
+	store      func(packedBlock []byte) error
+	height     func() uint64 // current local block height
+	penalties  []int         // bad responses for each source
+}
+
+// fetch and store the blocks first..last
+//
+// ranges are requested from all usable sources concurrently; a range
+// that fails is retried on a source that has not yet tried it and a
+// source that returns bad data is penalised; the download stops when
+// all blocks are stored or a range can no longer be obtained
+//
+// returns the number of the next block that still needs to be stored
+func (d *downloader) download(first uint64, last uint64) (uint64, error) {
+
+	log := d.log
+
+	results := make(chan rangeResult, len(d.penalties))
+	requests := make([]chan *blockRange, len(d.penalties))
+	wg := sync.WaitGroup{}
+
+	idle := []int{}
+	for source := range d.penalties {
+		requests[source] = make(chan *blockRange)
+		if d.penalties[source] >= maximumPenalty {
+			continue
+		}
+		idle = append(idle, source)
+		wg.Add(1)
+		go d.worker(source, requests[source], results, &wg)
+	}
+	defer func() {
+		for _, c := range requests {
+			close(c)
+		}
+		wg.Wait()
+	}()
+
+	queue := []*blockRange{}                  // ranges waiting to be retried
+	nextRange := first                        // first block not yet in any range
+	next := first                             // next block to store
+	received := make(map[uint64]*rangeResult) // completed ranges by first block
+	busy := 0
+
+	errX := error(nil)
+
+download_loop:
+	for next <= last {
+
+		// hand out work to idle sources without getting too far ahead
+		limit := next + downloadAhead*downloadRangeSize
+		stillIdle := []int{}
+	assign_loop:
+		for _, source := range idle {
+			if d.penalties[source] >= maximumPenalty {
+				continue assign_loop
+			}
+			for i, r := range queue {
+				if !r.tried[source] {
+					queue = append(queue[:i], queue[i+1:]...)
+					requests[source] <- r
+					busy += 1
+					continue assign_loop
+				}
+			}
+			if nextRange <= last && nextRange < limit {
+				r := &blockRange{
+					first: nextRange,
+					last:  nextRange + downloadRangeSize - 1,
+					tried: make(map[int]bool),
+				}
+				if r.last > last {
+					r.last = last
+				}
+				nextRange = r.last + 1
+				requests[source] <- r
+				busy += 1
+				continue assign_loop
+			}
+			stillIdle = append(stillIdle, source)
+		}
+		idle = stillIdle
+
+		if 0 == busy {
+			log.Errorf("download: no source available for block: %d", next)
+			errX = fault.ErrNoConnectionsAvailable
+			break download_loop
+		}
+
+		result := <-results
+		busy -= 1
+
+		if nil != result.err {
+			log.Warnf("download: source: %d  blocks: %d..%d  error: %v", result.source, result.r.first, result.r.last, result.err)
+			result.r.tried[result.source] = true
+			queue = insertRange(queue, result.r)
+			if result.bad {
+				d.penalise(result.source)
+			}
+		} else {
+			received[result.r.first] = &result
+		}
+		idle = append(idle, result.source)
+
+		// store all ranges that are now in sequence
+	store_loop:
+		for {
+			result, ok := received[next]
+			if !ok {
+				break store_loop
+			}
+			delete(received, next)
+
+			for _, packedBlock := range result.blocks {
+				if d.height()+1 != next {
+					log.Warnf("download: local height changed while storing block: %d", next)
+					errX = fault.ErrBlockNumberDoesNotMatch
+					break download_loop
+				}
+				err := d.store(packedBlock)
+				if nil != err {
+					log.Errorf("download: source: %d  store block: %d  error: %v", result.source, next, err)
+					d.penalise(result.source)
+					r := &blockRange{
+						first: next,
+						last:  result.r.last,
+						tried: map[int]bool{result.source: true},
+					}
+					queue = insertRange(queue, r)
+					continue store_loop
+				}
+				next += 1
+			}
+		}
+	}
+
+	// wait for outstanding requests so that no source is still in use
+	for ; busy > 0; busy -= 1 {
+		<-results
+	}
+
+	return next, errX
+}
+
+// fetch each range sent to a source until the request channel is closed
+func (d *downloader) worker(source int, requests <-chan *blockRange, results chan<- rangeResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for r := range requests {
+		result := rangeResult{
+			source: source,
+			r:      r,
+			blocks: make([][]byte, 0, r.last-r.first+1),
+		}
+		packedBlocks, err := d.fetchBlocks(source, r)
+		if nil != err {
+			result.err = err
+		}
+		for i, packedBlock := range packedBlocks {
+			n := r.first + uint64(i)
+
+			// a block with the wrong number is bad data
+			if len(packedBlock) < blockrecord.TotalBlockSize {
+				result.err = fault.ErrInvalidBlockHeader
+				result.bad = true
+				break
+			}
+			header, err := blockrecord.PackedHeader(packedBlock[:blockrecord.TotalBlockSize]).Unpack()
+			if nil != err {
+				result.err = err
+				result.bad = true
+				break
+			}
+			if n != header.Number {
+				result.err = fault.ErrBlockNumberDoesNotMatch
+				result.bad = true
+				break
+			}
+			result.blocks = append(result.blocks, packedBlock)
+		}
+		results <- result
+	}
+}
+
+// fetch all the blocks of a range from one source
+// any blocks before an error are returned with it
+func (d *downloader) fetchBlocks(source int, r *blockRange) ([][]byte, error) {
+
+	if nil != d.fetchRange {
+		packedBlocks, err := d.fetchRange(source, r.first, r.last)
+		if nil != err {
+			return nil, err
+		}
+		if uint64(len(packedBlocks)) != r.last-r.first+1 {
+			return packedBlocks, fault.ErrBlockNotFound
+		}
+		return packedBlocks, nil
+	}
+
+	packedBlocks := make([][]byte, 0, r.last-r.first+1)
+	for n := r.first; n <= r.last; n += 1 {
+		packedBlock, err := d.fetch(source, n)
+		if nil != err {
+			return packedBlocks, err
+		}
+		packedBlocks = append(packedBlocks, packedBlock)
+	}
+	return packedBlocks, nil
+}
+
+// record a bad response from a source
+func (d *downloader) penalise(source int) {
+	d.penalties[source] += 1
+	if maximumPenalty == d.penalties[source] {
+		d.log.Warnf("download: source: %d  no longer used", source)
+	}
+}
+
+// add a range to the retry queue keeping it in block number order
+func insertRange(queue []*blockRange, r *blockRange) []*blockRange {
+	i := sort.Search(len(queue), func(i int) bool {
+		return queue[i].first > r.first
+	})
+	queue = append(queue, nil)
+	copy(queue[i+1:], queue[i:])
+	queue[i] = r
+	return queue
+}
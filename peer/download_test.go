@@ -0,0 +1,183 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"errors"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/logger"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a block whose header carries the block number and a marker for bad data
+func testBlock(number uint64, bad bool) []byte {
+	header := blockrecord.New()
+	header.Number = number
+	if bad {
+		header.Nonce = 1
+	}
+	return header.Pack()
+}
+
+// a local chain that accepts blocks in order and rejects marked blocks
+type testChain struct {
+	sync.Mutex
+	height uint64
+	stored []uint64
+}
+
+func (c *testChain) store(packedBlock []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	header, err := blockrecord.PackedHeader(packedBlock).Unpack()
+	if nil != err {
+		return err
+	}
+	if 0 != header.Nonce {
+		return fault.ErrInvalidNonce
+	}
+	if c.height+1 != header.Number {
+		return fault.ErrBlockNumberDoesNotMatch
+	}
+	c.height = header.Number
+	c.stored = append(c.stored, header.Number)
+	return nil
+}
+
+func (c *testChain) getHeight() uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.height
+}
+
+func newTestDownloader(chain *testChain, sources int, fetch fetchFunc) *downloader {
+	return &downloader{
+		log:       logger.New("download-test"),
+		fetch:     fetch,
+		store:     chain.store,
+		height:    chain.getHeight,
+		penalties: make([]int, sources),
+	}
+}
+
+// blocks arriving out of order from several sources are stored in order
+func TestDownloadInOrder(t *testing.T) {
+	chain := &testChain{height: 1}
+
+	fetch := func(source int, number uint64) ([]byte, error) {
+		// later sources are faster
+		time.Sleep(time.Duration(3-source) * time.Millisecond)
+		return testBlock(number, false), nil
+	}
+
+	d := newTestDownloader(chain, 3, fetch)
+	next, err := d.download(2, 101)
+	if nil != err {
+		t.Fatalf("download error: %v", err)
+	}
+	if 102 != next {
+		t.Errorf("next: %d  expected: 102", next)
+	}
+	for i, n := range chain.stored {
+		if uint64(i+2) != n {
+			t.Fatalf("stored[%d]: %d  expected: %d", i, n, i+2)
+		}
+	}
+	if 100 != len(chain.stored) {
+		t.Errorf("stored: %d blocks  expected: 100", len(chain.stored))
+	}
+}
+
+// failed and bad ranges are retried on other sources
+func TestDownloadRetry(t *testing.T) {
+	chain := &testChain{height: 1}
+
+	fetch := func(source int, number uint64) ([]byte, error) {
+		switch source {
+		case 0:
+			return nil, errors.New("unavailable")
+		case 1:
+			return testBlock(number, true), nil // fails to store
+		case 2:
+			return testBlock(number+1, false), nil // wrong block
+		default:
+			return testBlock(number, false), nil
+		}
+	}
+
+	d := newTestDownloader(chain, 4, fetch)
+	next, err := d.download(2, 61)
+	if nil != err {
+		t.Fatalf("download error: %v", err)
+	}
+	if 62 != next || 61 != chain.getHeight() {
+		t.Errorf("next: %d  height: %d  expected: 62, 61", next, chain.getHeight())
+	}
+
+	if 0 != d.penalties[0] {
+		t.Errorf("unavailable source penalty: %d  expected: 0", d.penalties[0])
+	}
+	if 0 == d.penalties[1] {
+		t.Errorf("bad store source was not penalised")
+	}
+	if 0 == d.penalties[2] {
+		t.Errorf("wrong block source was not penalised")
+	}
+	if 0 != d.penalties[3] {
+		t.Errorf("good source penalty: %d  expected: 0", d.penalties[3])
+	}
+}
+
+// when no source can supply a block the download stops there
+func TestDownloadStalled(t *testing.T) {
+	chain := &testChain{height: 1}
+
+	fetch := func(source int, number uint64) ([]byte, error) {
+		if number >= 25 {
+			return nil, errors.New("not found")
+		}
+		return testBlock(number, false), nil
+	}
+
+	d := newTestDownloader(chain, 2, fetch)
+	next, err := d.download(2, 50)
+	if fault.ErrNoConnectionsAvailable != err {
+		t.Errorf("error: %v  expected: %v", err, fault.ErrNoConnectionsAvailable)
+	}
+	if 22 != next {
+		t.Errorf("next: %d  expected: 22", next)
+	}
+}
+
+// ranges fetched in one request are checked and stored like single blocks
+func TestDownloadRange(t *testing.T) {
+	chain := &testChain{height: 1}
+
+	d := newTestDownloader(chain, 2, nil)
+	d.fetchRange = func(source int, first uint64, last uint64) ([][]byte, error) {
+		if 0 == source {
+			return [][]byte{testBlock(first, false)}, nil // short response
+		}
+		blocks := [][]byte{}
+		for n := first; n <= last; n += 1 {
+			blocks = append(blocks, testBlock(n, false))
+		}
+		return blocks, nil
+	}
+
+	next, err := d.download(2, 41)
+	if nil != err {
+		t.Fatalf("download error: %v", err)
+	}
+	if 42 != next || 41 != chain.getHeight() {
+		t.Errorf("next: %d  height: %d  expected: 42, 41", next, chain.getHeight())
+	}
+	if 0 != d.penalties[0] || 0 != d.penalties[1] {
+		t.Errorf("penalties: %v  expected: none", d.penalties)
+	}
+}
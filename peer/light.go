@@ -0,0 +1,196 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/bitmark-inc/bitmarkd/block"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/zmqutil"
+	"github.com/bitmark-inc/logger"
+	"time"
+)
+
+// time to wait for the prover to obtain a proof
+const proofTimeout = 2 * connectorTimeout
+
+// a request for the inclusion proof of a transaction
+// the prover owns its client sockets so it makes the requests
+type proofRequest struct {
+	txId  merkle.Digest
+	reply chan proofResult
+}
+
+type proofResult struct {
+	proof   *block.TransactionProof
+	current bool
+	err     error
+}
+
+// fetch the inclusion proof of a confirmed transaction from the full
+// peers and check it against the locally stored block header
+//
+// returns: the proof and whether several peers agree that the
+// transaction has not been transferred, which cannot be proved and
+// so depends on the peers being honest
+func TransactionProof(txId merkle.Digest) (*block.TransactionProof, bool, error) {
+
+	globalData.RLock()
+	initialised := globalData.initialised
+	globalData.RUnlock()
+	if !initialised {
+		return nil, false, fault.ErrNotInitialised
+	}
+
+	request := proofRequest{
+		txId:  txId,
+		reply: make(chan proofResult, 1),
+	}
+
+	timeout := time.After(proofTimeout)
+	select {
+	case globalData.prv.requests <- request:
+	case <-timeout:
+		return nil, false, fault.ErrNoConnectionsAvailable
+	}
+
+	select {
+	case result := <-request.reply:
+		return result.proof, result.current, result.err
+	case <-timeout:
+		return nil, false, fault.ErrNoConnectionsAvailable
+	}
+}
+
+// check that a peer has full blocks so that it can supply blocks and
+// proofs; a peer that does not answer is not used
+func isFullPeer(log *logger.L, client *zmqutil.Client) bool {
+	info, err := serverInformation(client)
+	if nil != err {
+		log.Warnf("server information from: %s  error: %v", client, err)
+		return false
+	}
+	return !info.Light
+}
+
+// fetch the server information
+func serverInformation(client *zmqutil.Client) (*serverInfo, error) {
+	err := client.Send("I")
+	if nil != err {
+		client.Reconnect()
+		return nil, err
+	}
+
+	data, err := client.Receive(0)
+	if nil != err {
+		client.Reconnect()
+		return nil, err
+	}
+
+	if 2 != len(data) {
+		return nil, fault.ErrInvalidPeerResponse
+	}
+
+	switch string(data[0]) {
+	case "E":
+		return nil, fault.InvalidError(string(data[1]))
+	case "I":
+		var info serverInfo
+		err = json.Unmarshal(data[1], &info)
+		if nil != err {
+			return nil, err
+		}
+		return &info, nil
+	default:
+	}
+	return nil, fault.ErrInvalidPeerResponse
+}
+
+// fetch a transaction inclusion proof
+func inclusionProof(client *zmqutil.Client, txId merkle.Digest) (*block.TransactionProof, bool, error) {
+	err := client.Send("P", txId[:])
+	if nil != err {
+		client.Reconnect()
+		return nil, false, err
+	}
+
+	data, err := client.Receive(0)
+	if nil != err {
+		client.Reconnect()
+		return nil, false, err
+	}
+
+	if 2 != len(data) {
+		return nil, false, fault.ErrInvalidPeerResponse
+	}
+
+	switch string(data[0]) {
+	case "E":
+		return nil, false, fault.InvalidError(string(data[1]))
+	case "P":
+		if len(data[1]) < 1 {
+			break
+		}
+		proof, err := block.UnpackTransactionProof(data[1][1:])
+		if nil != err {
+			return nil, false, err
+		}
+		return proof, 1 == data[1][0], nil
+	default:
+	}
+	return nil, false, fault.ErrInvalidPeerResponse
+}
+
+// fetch the packed headers of the blocks first..last
+func headerData(client *zmqutil.Client, first uint64, last uint64) ([][]byte, error) {
+
+	headers := make([][]byte, 0, last-first+1)
+	for first <= last {
+		count := last - first + 1
+		if count > block.MaximumHeaders {
+			count = block.MaximumHeaders
+		}
+		parameter1 := make([]byte, 8)
+		binary.BigEndian.PutUint64(parameter1, first)
+		parameter2 := make([]byte, 8)
+		binary.BigEndian.PutUint64(parameter2, count)
+
+		err := client.Send("G", parameter1, parameter2)
+		if nil != err {
+			client.Reconnect()
+			return headers, err
+		}
+
+		data, err := client.Receive(0)
+		if nil != err {
+			client.Reconnect()
+			return headers, err
+		}
+
+		if 2 != len(data) {
+			return headers, fault.ErrInvalidPeerResponse
+		}
+
+		switch string(data[0]) {
+		case "E":
+			return headers, fault.InvalidError(string(data[1]))
+		case "G":
+			packed := data[1]
+			if 0 == len(packed) || 0 != len(packed)%blockrecord.TotalBlockSize || uint64(len(packed)) > count*blockrecord.TotalBlockSize {
+				return headers, fault.ErrInvalidPeerResponse
+			}
+			for ; 0 != len(packed); packed = packed[blockrecord.TotalBlockSize:] {
+				headers = append(headers, packed[:blockrecord.TotalBlockSize])
+				first += 1
+			}
+		default:
+			return headers, fault.ErrInvalidPeerResponse
+		}
+	}
+	return headers, nil
+}
@@ -10,8 +10,10 @@ import (
 	"github.com/bitmark-inc/bitmarkd/announce"
 	"github.com/bitmark-inc/bitmarkd/block"
 	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
 	"github.com/bitmark-inc/bitmarkd/mode"
 	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
 	"github.com/bitmark-inc/bitmarkd/util"
 	"github.com/bitmark-inc/bitmarkd/version"
 	"github.com/bitmark-inc/bitmarkd/zmqutil"
@@ -37,6 +39,7 @@ type serverInfo struct {
 	Version string `json:"version"`
 	Chain   string `json:"chain"`
 	Normal  bool   `json:"normal"`
+	Light   bool   `json:"light"`
 	Height  uint64 `json:"height"`
 }
 
@@ -153,9 +156,17 @@ func (lstn *listener) process(socket *zmq.Socket) {
 		result = make([]byte, 8)
 		binary.BigEndian.PutUint64(result, blockNumber)
 
+	case "W": // get block number and total chain work
+		blockNumber, work := block.GetHeightAndWork()
+		result = make([]byte, 8)
+		binary.BigEndian.PutUint64(result, blockNumber)
+		result = append(result, work.Bytes()...)
+
 	case "B": // get packed block
 		if 1 != len(parameters) {
 			err = fault.ErrMissingParameters
+		} else if mode.IsLight() {
+			err = fault.ErrNotAvailableInLightMode
 		} else if 8 == len(parameters[0]) {
 			result = storage.Pool.Blocks.Get(parameters[0])
 			if nil == result {
@@ -170,6 +181,7 @@ func (lstn *listener) process(socket *zmq.Socket) {
 			Version: version.Version,
 			Chain:   mode.ChainName(),
 			Normal:  mode.Is(mode.Normal),
+			Light:   mode.IsLight(),
 			Height:  block.GetHeight(),
 		}
 		result, err = json.Marshal(info)
@@ -190,6 +202,29 @@ func (lstn *listener) process(socket *zmq.Socket) {
 			err = fault.ErrBlockNotFound
 		}
 
+	case "G": // get packed headers: first block number, count
+		if 2 != len(parameters) {
+			err = fault.ErrMissingParameters
+		} else if 8 == len(parameters[0]) && 8 == len(parameters[1]) {
+			first := binary.BigEndian.Uint64(parameters[0])
+			count := binary.BigEndian.Uint64(parameters[1])
+			if count > block.MaximumHeaders {
+				count = block.MaximumHeaders
+			}
+			result, err = block.GetPackedHeaders(first, int(count))
+		} else {
+			err = fault.ErrBlockNotFound
+		}
+
+	case "P": // get transaction inclusion proof: current owner flag ++ packed proof
+		if 1 != len(parameters) {
+			err = fault.ErrMissingParameters
+		} else if merkle.DigestLength == len(parameters[0]) {
+			result, err = transactionProof(parameters[0])
+		} else {
+			err = fault.ErrTransactionNotFound
+		}
+
 	case "R": // registration: chain, publicKey, broadcasts, listeners
 		if 4 != len(parameters) {
 			listenerSendError(socket, fault.ErrMissingParameters)
@@ -235,6 +270,62 @@ func (lstn *listener) process(socket *zmq.Socket) {
 	log.Infof("sent: %q  result: %x", fn, result)
 }
 
+// proof of a confirmed transaction preceded by a flag that is one
+// if the transaction has not yet been transferred
+//
+// for the batch link id of a bitmark moved by a batch transfer the
+// proof is of the batch transfer
+func transactionProof(id []byte) ([]byte, error) {
+
+	txId := merkle.Digest{}
+	err := merkle.DigestFromBytes(&txId, id)
+	if nil != err {
+		return nil, err
+	}
+
+	provedId := txId
+	if batchTxId, _, ok := block.BatchLink(txId); ok {
+		provedId = batchTxId
+	}
+
+	proof, err := block.ProveTransaction(provedId)
+	if nil != err {
+		return nil, err
+	}
+
+	transaction, _, err := proof.Packed.Unpack()
+	if nil != err {
+		return nil, err
+	}
+
+	current := byte(0)
+	switch tx := transaction.(type) {
+	case *transactionrecord.BitmarkIssue:
+		if storage.Pool.OwnerDigest.Has(append(tx.Owner.Bytes(), txId[:]...)) {
+			current = 1
+		}
+	case *transactionrecord.BitmarkTransfer:
+		if storage.Pool.OwnerDigest.Has(append(tx.Owner.Bytes(), txId[:]...)) {
+			current = 1
+		}
+	case *transactionrecord.BitmarkTransferCountersigned:
+		if storage.Pool.OwnerDigest.Has(append(tx.Owner.Bytes(), txId[:]...)) {
+			current = 1
+		}
+	case *transactionrecord.BitmarkBurn:
+		// never current
+	case *transactionrecord.BitmarkBatchTransfer:
+		// only its batch link ids hold ownership
+		if provedId != txId && storage.Pool.OwnerDigest.Has(append(tx.Owner.Bytes(), txId[:]...)) {
+			current = 1
+		}
+	default:
+		return nil, fault.ErrTransactionIsNotAnIssueOrATransfer
+	}
+
+	return append([]byte{current}, proof.Pack()...), nil
+}
+
 // send an error packet
 func listenerSendError(socket *zmq.Socket, err error) {
 	errorMessage := err.Error()
@@ -0,0 +1,134 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/messagebus"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/zmqutil"
+	"github.com/bitmark-inc/logger"
+)
+
+// number of full peers that must report a transaction as not yet
+// transferred before a light node reports it as current
+const minimumProofAgreement = 2
+
+// fetch inclusion proofs for a light node
+//
+// this has its own sockets to the same peers as the connector so
+// that requests do not wait for block synchronisation
+type prover struct {
+	log          *logger.L
+	clients      []*zmqutil.Client // nil unless in light mode
+	dynamicStart int
+	requests     chan proofRequest
+}
+
+// initialise the prover
+func (prv *prover) initialise(privateKey []byte, publicKey []byte, connect []Connection) error {
+
+	log := logger.New("prover")
+	if nil == log {
+		return fault.ErrInvalidLoggerChannel
+	}
+	prv.log = log
+
+	log.Info("initialising…")
+
+	prv.requests = make(chan proofRequest)
+
+	// a full node has the transactions locally
+	if !mode.IsLight() {
+		return nil
+	}
+
+	clients, err := newClients(log, privateKey, publicKey, connect)
+	if nil != err {
+		return err
+	}
+	prv.clients = clients
+	prv.dynamicStart = len(connect) // index of first dynamic socket
+
+	return nil
+}
+
+// answer proof requests and follow the connector's dynamic connections
+func (prv *prover) Run(args interface{}, shutdown <-chan struct{}) {
+
+	log := prv.log
+
+	log.Info("starting…")
+
+	queue := messagebus.Bus.Prover.Chan()
+
+loop:
+	for {
+		select {
+		case <-shutdown:
+			break loop
+		case item := <-queue:
+			if nil != prv.clients {
+				connectTo(log, prv.clients, prv.dynamicStart, item.Command, item.Parameters[0], item.Parameters[1])
+			}
+		case request := <-prv.requests:
+			request.reply <- prv.fetchProof(request.txId)
+		}
+	}
+	log.Info("shutting down…")
+	zmqutil.CloseClients(prv.clients)
+	log.Info("stopped")
+}
+
+// ask every connected full peer for a proof
+//
+// the proof is checked against the local headers, but whether the
+// transaction is current cannot be proved so it must be reported by
+// several peers with no peer disagreeing
+func (prv *prover) fetchProof(txId merkle.Digest) proofResult {
+
+	log := prv.log
+
+	result := proofResult{
+		err: fault.ErrNoConnectionsAvailable,
+	}
+	current := 0
+	transferred := 0
+
+	for _, client := range prv.clients {
+		if !client.IsConnected() || !isFullPeer(log, client) {
+			continue
+		}
+
+		proof, isCurrent, err := inclusionProof(client, txId)
+		if nil == err {
+			err = proof.Verify(txId)
+		}
+		if nil != err {
+			log.Warnf("proof of: %v  from: %s  error: %v", txId, client, err)
+			if nil == result.proof {
+				result.err = err
+			}
+			continue
+		}
+
+		if nil == result.proof {
+			result.proof = proof
+			result.err = nil
+		}
+		if isCurrent {
+			current += 1
+		} else {
+			transferred += 1
+		}
+	}
+
+	result.current = current >= minimumProofAgreement && 0 == transferred
+	if nil != result.proof && !result.current {
+		log.Infof("proof of: %v  current: %d  transferred: %d", txId, current, transferred)
+	}
+	return result
+}
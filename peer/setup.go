@@ -51,6 +51,7 @@ type proofData struct {
 	brdc broadcaster // for broadcasting blocks, transactions etc.
 	lstn listener    // for RPC responses
 	conn connector   // for RPC requests
+	prv  prover      // for light mode proof requests
 	sbsc subscriber  // for subscriptions
 
 	connectorClients  []*zmqutil.Client
@@ -112,6 +113,9 @@ func Initialise(configuration *Configuration) error {
 	if err := globalData.conn.initialise(privateKey, publicKey, configuration.Connect, configuration.DynamicConnections); nil != err {
 		return err
 	}
+	if err := globalData.prv.initialise(privateKey, publicKey, configuration.Connect); nil != err {
+		return err
+	}
 	if err := globalData.sbsc.initialise(privateKey, publicKey, configuration.Subscribe, configuration.DynamicConnections); nil != err {
 		return err
 	}
@@ -126,6 +130,7 @@ func Initialise(configuration *Configuration) error {
 		&globalData.brdc,
 		&globalData.lstn,
 		&globalData.conn,
+		&globalData.prv,
 		&globalData.sbsc,
 	}
 
@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"github.com/bitmark-inc/bitmarkd/announce"
 	"github.com/bitmark-inc/bitmarkd/asset"
+	"github.com/bitmark-inc/bitmarkd/block"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/messagebus"
 	"github.com/bitmark-inc/bitmarkd/mode"
@@ -338,14 +339,23 @@ func processAssets(packed []byte) error {
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
 
 	ok := false
+	number := block.GetHeight() + 1
 	for 0 != len(packed) {
 		transaction, n, err := transactionrecord.Packed(packed).Unpack()
 		if nil != err {
 			return err
 		}
 
+		err = block.CheckAccounts(transaction, number)
+		if nil != err {
+			return err
+		}
+
 		switch tx := transaction.(type) {
 		case *transactionrecord.AssetData:
 			_, packedAsset, err := asset.Cache(tx)
@@ -378,16 +388,24 @@ func processIssues(packed, verified []byte) error {
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
 
 	packedIssues := transactionrecord.Packed(packed)
 	issueCount := 0 // for payment difficulty
 
 	issues := make([]*transactionrecord.BitmarkIssue, 0, 100)
+	number := block.GetHeight() + 1
 	for 0 != len(packedIssues) {
 		transaction, n, err := packedIssues.Unpack()
 		if nil != err {
 			return err
 		}
+		err = block.CheckAccounts(transaction, number)
+		if nil != err {
+			return err
+		}
 
 		switch tx := transaction.(type) {
 		case *transactionrecord.BitmarkIssue:
@@ -419,7 +437,7 @@ func processIssues(packed, verified []byte) error {
 	return nil
 }
 
-// unpack transfer and process it
+// unpack transfer or burn and process it
 func processTransfer(packed []byte) error {
 
 	if 0 == len(packed) {
@@ -429,11 +447,18 @@ func processTransfer(packed []byte) error {
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
 
 	transaction, _, err := transactionrecord.Packed(packed).Unpack()
 	if nil != err {
 		return err
 	}
+	err = block.CheckAccounts(transaction, block.GetHeight()+1)
+	if nil != err {
+		return err
+	}
 
 	switch tx := transaction.(type) {
 	case *transactionrecord.BitmarkTransfer:
@@ -447,6 +472,39 @@ func processTransfer(packed []byte) error {
 			return fault.ErrTransactionAlreadyExists
 		}
 
+	case *transactionrecord.BitmarkTransferCountersigned:
+
+		_, duplicate, err := reservoir.StoreTransferCountersigned(tx)
+		if nil != err {
+			return err
+		}
+
+		if duplicate {
+			return fault.ErrTransactionAlreadyExists
+		}
+
+	case *transactionrecord.BitmarkBurn:
+
+		_, duplicate, err := reservoir.StoreBurn(tx)
+		if nil != err {
+			return err
+		}
+
+		if duplicate {
+			return fault.ErrTransactionAlreadyExists
+		}
+
+	case *transactionrecord.BitmarkBatchTransfer:
+
+		_, duplicate, err := reservoir.StoreBatchTransfer(tx)
+		if nil != err {
+			return err
+		}
+
+		if duplicate {
+			return fault.ErrTransactionAlreadyExists
+		}
+
 	default:
 		return fault.ErrTransactionIsNotATransfer
 	}
@@ -463,6 +521,9 @@ func processProof(packed []byte) error {
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
 
 	var payId pay.PayId
 	if len(packed) > payment.NonceLength+len(payId) {
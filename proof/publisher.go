@@ -5,7 +5,6 @@
 package proof
 
 import (
-	"bytes"
 	"crypto/rand"
 	"encoding/binary"
 	"encoding/hex"
@@ -16,8 +15,8 @@ import (
 	"github.com/bitmark-inc/bitmarkd/block"
 	"github.com/bitmark-inc/bitmarkd/blockrecord"
 	"github.com/bitmark-inc/bitmarkd/currency"
-	"github.com/bitmark-inc/bitmarkd/difficulty"
 	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/keyfile"
 	"github.com/bitmark-inc/bitmarkd/merkle"
 	"github.com/bitmark-inc/bitmarkd/mode"
 	"github.com/bitmark-inc/bitmarkd/reservoir"
@@ -28,14 +27,13 @@ import (
 	"github.com/bitmark-inc/logger"
 	zmq "github.com/pebbe/zmq4"
 	"golang.org/x/crypto/ed25519"
-	"io/ioutil"
 	"strings"
 	"time"
 )
 
 // tags for the signing key data
 const (
-	taggedSeed    = "SEED:"    // followed by base58 encoded seed as produced by desktop/cli client
+	taggedSeed    = "SEED:"    // followed by base58 encoded seed as produced by desktop/cli client, or its recovery phrase
 	taggedPrivate = "PRIVATE:" // followed by 64 bytes of hex Ed25519 private key
 )
 
@@ -46,13 +44,12 @@ const (
 )
 
 type publisher struct {
-	log             *logger.L
-	socket4         *zmq.Socket
-	socket6         *zmq.Socket
-	paymentCurrency currency.Currency
-	paymentAddress  string
-	owner           *account.Account
-	privateKey      []byte
+	log        *logger.L
+	socket4    *zmq.Socket
+	socket6    *zmq.Socket
+	payments   []transactionrecord.PaymentAddress
+	owner      *account.Account
+	privateKey []byte
 }
 
 // initialise the publisher
@@ -66,52 +63,17 @@ func (pub *publisher) initialise(configuration *Configuration) error {
 
 	log.Info("initialising…")
 
-	_, err := fmt.Sscan(configuration.Currency, &pub.paymentCurrency)
+	payments, err := paymentAddresses(configuration)
 	if nil != err {
-		log.Errorf("currency: %q  error: %v", configuration.Currency, err)
+		log.Errorf("payment addresses error: %v", err)
 		return err
 	}
-	pub.paymentAddress = configuration.Address
+	pub.payments = payments
 
-	if databytes, err := ioutil.ReadFile(configuration.SigningKey); err != nil {
+	err = pub.loadSigningKey(configuration.SigningKey, configuration.SigningPath)
+	if nil != err {
+		log.Errorf("read signing key file: %q  error: %v", configuration.SigningKey, err)
 		return err
-	} else {
-		s := strings.TrimSpace(string(databytes))
-
-		if strings.HasPrefix(s, taggedSeed) {
-			privateKey, err := account.PrivateKeyFromBase58Seed(s[len(taggedSeed):])
-			if nil != err {
-				return err
-			}
-			pub.privateKey = privateKey.PrivateKeyBytes()
-			pub.owner = privateKey.Account()
-		} else if strings.HasPrefix(s, taggedPrivate) {
-			b, err := hex.DecodeString(s[len(taggedPrivate):])
-			if err != nil {
-				return err
-			}
-			privateKey, err := account.PrivateKeyFromBytes(b)
-			if nil != err {
-				return err
-			}
-			pub.privateKey = privateKey.PrivateKeyBytes()
-			pub.owner = privateKey.Account()
-		} else {
-			return fault.ErrInvalidProofSigningKey
-		}
-		rand := bytes.NewBuffer(databytes)
-		publicKey, privateKey, err := ed25519.GenerateKey(rand)
-		if nil != err {
-			log.Errorf("public key generation  error: %v", err)
-			return err
-		}
-		pub.owner = &account.Account{
-			AccountInterface: &account.ED25519Account{
-				Test:      mode.IsTesting(),
-				PublicKey: publicKey,
-			},
-		}
-		pub.privateKey = privateKey
 	}
 
 	// read the keys
@@ -172,6 +134,145 @@ loop:
 	}
 }
 
+// set the block owner and the key that signs the foundation record
+func (pub *publisher) loadSigningKey(filename string, path string) error {
+
+	databytes, err := keyfile.Read(filename)
+	if nil != err {
+		return err
+	}
+	s := strings.TrimSpace(string(databytes))
+
+	var privateKey *account.PrivateKey
+	if strings.HasPrefix(s, taggedSeed) {
+		privateKey, err = signingKeyFromSeed(s[len(taggedSeed):], path)
+		if nil != err {
+			return err
+		}
+		// a seed is not checked against the network when it is decoded
+		key, ok := privateKey.PrivateKeyInterface.(*account.ED25519PrivateKey)
+		if !ok || mode.IsTesting() != key.Test {
+			return fault.ErrWrongNetworkForPrivateKey
+		}
+	} else if strings.HasPrefix(s, taggedPrivate) {
+		b, err := hex.DecodeString(s[len(taggedPrivate):])
+		if nil != err {
+			return err
+		}
+		privateKey, err = account.PrivateKeyFromBytes(b)
+		if nil != err {
+			return err
+		}
+	} else {
+		return fault.ErrInvalidProofSigningKey
+	}
+
+	// the foundation record is signed with ed25519.Sign
+	if account.ED25519 != privateKey.KeyType() {
+		return fault.ErrInvalidKeyType
+	}
+
+	pub.privateKey = privateKey.PrivateKeyBytes()
+	pub.owner = privateKey.Account()
+	return nil
+}
+
+// the seed itself or one of its children if a derivation path is set
+//
+// the seed can also be given as its recovery phrase
+func signingKeyFromSeed(seed string, path string) (*account.PrivateKey, error) {
+	if strings.ContainsAny(seed, " \t\n") {
+		s, err := account.Base58SeedFromMnemonic(seed)
+		if nil != err {
+			return nil, err
+		}
+		seed = s
+	}
+	if "" == path {
+		return account.PrivateKeyFromBase58Seed(seed)
+	}
+	return account.DeriveChild(seed, path)
+}
+
+// collect the legacy currency/address and the payment_address map
+// into a list in currency order
+func paymentAddresses(configuration *Configuration) ([]transactionrecord.PaymentAddress, error) {
+
+	addresses := make(map[currency.Currency]string)
+
+	if "" != configuration.Currency {
+		var c currency.Currency
+		_, err := fmt.Sscan(configuration.Currency, &c)
+		if nil != err {
+			return nil, err
+		}
+		addresses[c] = configuration.Address
+	}
+
+	for name, address := range configuration.PaymentAddress {
+		var c currency.Currency
+		_, err := fmt.Sscan(name, &c)
+		if nil != err {
+			return nil, err
+		}
+		if _, ok := addresses[c]; ok {
+			return nil, fault.ErrDuplicatePaymentCurrency
+		}
+		addresses[c] = address
+	}
+
+	if 0 == len(addresses) {
+		return nil, fault.ErrMissingParameters
+	}
+
+	payments := make([]transactionrecord.PaymentAddress, 0, len(addresses))
+	for c := currency.First; c <= currency.Last; c += 1 {
+		if address, ok := addresses[c]; ok {
+			payments = append(payments, transactionrecord.PaymentAddress{
+				Currency: c,
+				Address:  address,
+			})
+		}
+	}
+	if len(payments) != len(addresses) {
+		return nil, fault.ErrInvalidCurrency
+	}
+	return payments, nil
+}
+
+// the signed base record for a block
+//
+// blocks before the foundation record is activated carry the legacy
+// base record with only the first payment address
+func (pub *publisher) packBase(number uint64) (transactionrecord.Packed, error) {
+
+	if !block.IsTagActive(transactionrecord.BlockFoundationTag, number) {
+		base := &transactionrecord.BaseData{
+			Currency:       pub.payments[0].Currency,
+			PaymentAddress: pub.payments[0].Address,
+			Owner:          pub.owner,
+			Nonce:          1234,
+		}
+		partiallyPackedBase, _ := base.Pack(pub.owner) // ignore error to get packed without signature
+		base.Signature = ed25519.Sign(pub.privateKey[:], partiallyPackedBase)
+		return base.Pack(pub.owner)
+	}
+
+	base := &transactionrecord.BlockFoundation{
+		Payments: pub.payments,
+		Owner:    pub.owner,
+		Nonce:    1234,
+	}
+
+	// sign the record and attach signature
+	partiallyPackedBase, _ := base.Pack(pub.owner) // ignore error to get packed without signature
+	signature := ed25519.Sign(pub.privateKey[:], partiallyPackedBase)
+	base.Signature = signature[:]
+
+	// re-pack to makesure signature is valid
+	return base.Pack(pub.owner)
+}
+
 // process some items into a block and publish it
 func (pub *publisher) process() {
 
@@ -201,20 +302,10 @@ func (pub *publisher) process() {
 	// to accumulate new assets
 	assetIds := make([]transactionrecord.AssetIndex, 0, txCount)
 
-	base := &transactionrecord.BaseData{
-		Currency:       pub.paymentCurrency,
-		PaymentAddress: pub.paymentAddress,
-		Owner:          pub.owner,
-		Nonce:          1234,
-	}
+	// the block is built on the current chain
+	previousBlock, number, difficulty := block.Get()
 
-	// sign the record and attach signature
-	partiallyPackedBase, _ := base.Pack(pub.owner) // ignore error to get packed without signature
-	signature := ed25519.Sign(pub.privateKey[:], partiallyPackedBase)
-	base.Signature = signature[:]
-
-	// re-pack to makesure signature is valid
-	packedBase, err := base.Pack(pub.owner)
+	packedBase, err := pub.packBase(number)
 	if nil != err {
 		pub.log.Criticalf("pack base error: %v", err)
 		fault.PanicWithError("publisher packe base", err)
@@ -224,14 +315,21 @@ func (pub *publisher) process() {
 	txIds := make([]merkle.Digest, 1, len(pooledTxIds)*2) // allow room for inserted assets & allocate base
 	txIds[0] = merkle.NewDigest(packedBase)               // base is first
 
-	n := 0 // index for txIds
-	for _, item := range transactions {
+	for n, item := range transactions {
 		unpacked, _, err := transactionrecord.Packed(item).Unpack()
 		if nil != err {
 			pub.log.Criticalf("unpack error: %v", err)
 			fault.PanicWithError("publisher extraction transactions", err)
 		}
 
+		// new types and key types stay in the reservoir until they are activated
+		if !block.IsTagActive(transactionrecord.Packed(item).Type(), number) {
+			continue
+		}
+		if nil != block.CheckAccounts(unpacked, number) {
+			continue
+		}
+
 		// only issues and transfers are allowed here
 		switch tx := unpacked.(type) {
 		case *transactionrecord.BitmarkIssue:
@@ -253,7 +351,7 @@ func (pub *publisher) process() {
 				seenAsset[tx.AssetIndex] = struct{}{}
 			}
 
-		case *transactionrecord.BitmarkTransfer:
+		case *transactionrecord.BitmarkTransfer, *transactionrecord.BitmarkTransferCountersigned, *transactionrecord.BitmarkBurn, *transactionrecord.BitmarkBatchTransfer:
 			// ok
 
 		default: // all other types cannot occur here
@@ -264,7 +362,11 @@ func (pub *publisher) process() {
 		// concatenate items
 		txIds = append(txIds, pooledTxIds[n])
 		txData = append(txData, item...)
-		n += 1
+	}
+
+	if 1 == len(txIds) {
+		pub.log.Info("no activated transactions in verified pool")
+		return
 	}
 
 	// build the tree of transaction IDs
@@ -282,18 +384,18 @@ func (pub *publisher) process() {
 	_, err = rand.Read(randomBytes)
 	nonce := blockrecord.NonceType(binary.LittleEndian.Uint64(randomBytes))
 
-	bits := difficulty.Current
 	timestamp := uint64(time.Now().Unix())
 
-	// PreviousBlock is all zero
 	message := &PublishedItem{
 		Job: "?", // set by enqueue
 		Header: blockrecord.Header{
 			Version:          blockrecord.Version,
 			TransactionCount: uint16(transactionCount),
+			Number:           number,
+			PreviousBlock:    previousBlock,
 			MerkleRoot:       merkleRoot,
 			Timestamp:        timestamp,
-			Difficulty:       bits,
+			Difficulty:       difficulty,
 			Nonce:            nonce,
 		},
 		Base:     packedBase,
@@ -303,8 +405,6 @@ func (pub *publisher) process() {
 
 	pub.log.Tracef("message: %v", message)
 
-	message.Header.PreviousBlock, message.Header.Number = block.Get()
-
 	// add job to the queue
 	enqueueToJobQueue(message, txData)
 
@@ -0,0 +1,113 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package proof
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// live and testing network seeds
+const (
+	testSeed    = "5XEECqhR7QBkJezUJiUJBmHaSmffDfVN5atuLnQBHnvfxbsWHuBfQLw"
+	testingSeed = "5XEECtzqJYokJbDkLzPMqNEF1Eo5qfGPqhbb4pGeuj2igeEMYraCcJ1"
+)
+
+func TestLoadSigningKey(t *testing.T) {
+
+	directory, err := ioutil.TempDir("", "publisher")
+	if nil != err {
+		t.Fatalf("temporary directory error: %s", err)
+	}
+	defer os.RemoveAll(directory)
+
+	seedKey, err := account.PrivateKeyFromBase58Seed(testSeed)
+	if nil != err {
+		t.Fatalf("seed error: %s", err)
+	}
+	childKey, err := account.DeriveChild(testSeed, "m/44'/731'/0'")
+	if nil != err {
+		t.Fatalf("derive error: %s", err)
+	}
+	phrase, err := account.MnemonicFromBase58Seed(testSeed)
+	if nil != err {
+		t.Fatalf("phrase error: %s", err)
+	}
+
+	tests := []struct {
+		data     string
+		path     string
+		expected *account.PrivateKey
+	}{
+		{"SEED:" + testSeed, "", seedKey},
+		{"SEED:" + testSeed, "m/44'/731'/0'", childKey},
+		{"SEED:" + phrase, "", seedKey},
+		{"SEED:" + phrase, "m/44'/731'/0'", childKey},
+		{"PRIVATE:" + hex.EncodeToString(childKey.Bytes()), "", childKey},
+	}
+
+	filename := filepath.Join(directory, "proof.sign")
+	for i, test := range tests {
+		err := ioutil.WriteFile(filename, []byte(test.data+"\n"), 0600)
+		if nil != err {
+			t.Fatalf("%d: write error: %s", i, err)
+		}
+
+		pub := &publisher{}
+		err = pub.loadSigningKey(filename, test.path)
+		if nil != err {
+			t.Errorf("%d: load error: %s", i, err)
+			continue
+		}
+		if test.expected.Account().String() != pub.owner.String() {
+			t.Errorf("%d: owner: %s  expected: %s", i, pub.owner, test.expected.Account())
+		}
+		if !bytes.Equal(test.expected.PrivateKeyBytes(), pub.privateKey) {
+			t.Errorf("%d: private key does not match the owner", i)
+		}
+	}
+
+	err = ioutil.WriteFile(filename, []byte("not a key\n"), 0600)
+	if nil != err {
+		t.Fatalf("write error: %s", err)
+	}
+	pub := &publisher{}
+	if err := pub.loadSigningKey(filename, ""); nil == err {
+		t.Errorf("invalid key file did not fail")
+	}
+
+	// an ECDSA P-256 key cannot sign the foundation record
+	ecdsaKey, err := account.PrivateKeyFromBase58("26jeSzNAiE28dwr9uEHcAzDoyz9z6SSxHAg2s3YA2nNbhcn9isS")
+	if nil != err {
+		t.Fatalf("ECDSA key error: %s", err)
+	}
+	err = ioutil.WriteFile(filename, []byte("PRIVATE:"+hex.EncodeToString(ecdsaKey.Bytes())+"\n"), 0600)
+	if nil != err {
+		t.Fatalf("write error: %s", err)
+	}
+	pub = &publisher{}
+	if err := pub.loadSigningKey(filename, ""); fault.ErrInvalidKeyType != err {
+		t.Errorf("ECDSA key: error: %v  expected: %v", err, fault.ErrInvalidKeyType)
+	}
+
+	// a testing seed on the live network
+	err = ioutil.WriteFile(filename, []byte("SEED:"+testingSeed+"\n"), 0600)
+	if nil != err {
+		t.Fatalf("write error: %s", err)
+	}
+	for _, path := range []string{"", "m/44'/731'/0'"} {
+		pub := &publisher{}
+		err := pub.loadSigningKey(filename, path)
+		if fault.ErrWrongNetworkForPrivateKey != err {
+			t.Errorf("testing seed: path: %q  error: %v  expected: %v", path, err, fault.ErrWrongNetworkForPrivateKey)
+		}
+	}
+}
@@ -17,13 +17,15 @@ import (
 // this is read from a libucl configuration file
 type Configuration struct {
 	//MaximumConnections int          `libucl:"maximum_connections"`
-	Publish    []string `libucl:"publish"`
-	Submit     []string `libucl:"submit"`
-	PrivateKey string   `libucl:"private_key"`
-	PublicKey  string   `libucl:"public_key"`
-	SigningKey string   `libucl:"signing_key"`
-	Currency   string   `libucl:"currency"`
-	Address    string   `libucl:"address"`
+	Publish        []string          `libucl:"publish"`
+	Submit         []string          `libucl:"submit"`
+	PrivateKey     string            `libucl:"private_key"`
+	PublicKey      string            `libucl:"public_key"`
+	SigningKey     string            `libucl:"signing_key"`
+	SigningPath    string            `libucl:"signing_path"`    // derive the signing key from a SEED: signing key, e.g. "m/44'/731'/0'"
+	Currency       string            `libucl:"currency"`        // single currency, kept for older configurations
+	Address        string            `libucl:"address"`         // address for the single currency
+	PaymentAddress map[string]string `libucl:"payment_address"` // currency name → address
 }
 
 // globals for background proccess
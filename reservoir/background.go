@@ -47,12 +47,26 @@ func (state *verifierData) process(globaldata *globalDataType) {
 	globalData.Lock()
 	defer globalData.Unlock()
 
+	// all journal changes of this cycle are written together
+	batch := storage.NewBatch()
+	defer batch.Commit()
+
 	for payId, item := range globalData.unverified.entries {
-		record := storage.Pool.Payment.Get(payId[:])
-		if nil != record {
-			setVerified(payId)
+		records, err := paymentRecords(payId)
+		if nil != err {
+			log.Errorf("pay id: %s  payment records error: %v", payId, err)
+		}
+		status, err := checkPayment(item, records)
+		if TrackingAccepted == status {
+			setVerified(batch, payId)
 			continue
 		}
+		// leave the entry to expire unless a later payment is sufficient
+		// and only log when the reason changes
+		if TrackingInvalid == status && err != item.invalid {
+			log.Warnf("pay id: %s  status: %s  error: %v", payId, status, err)
+			item.invalid = err
+		}
 
 		if time.Since(item.expires) > 0 {
 			log.Infof("expired: %#v", payId)
@@ -66,8 +80,11 @@ func (state *verifierData) process(globaldata *globalDataType) {
 			}
 
 			delete(globalData.unverified.entries, payId)
+			batch.Delete(storage.Pool.PendingEntries, payId[:])
 		}
 	}
+
+	expireOffers()
 }
 
 // rebroadcasting process
@@ -14,6 +14,7 @@ import (
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/merkle"
 	"github.com/bitmark-inc/bitmarkd/pay"
+	"github.com/bitmark-inc/bitmarkd/payment"
 	"github.com/bitmark-inc/bitmarkd/storage"
 	"github.com/bitmark-inc/bitmarkd/transactionrecord"
 	"golang.org/x/crypto/sha3"
@@ -32,6 +33,7 @@ type IssueInfo struct {
 	Difficulty *difficulty.Difficulty
 	TxIds      []merkle.Digest
 	Packed     []byte
+	Payments   []transactionrecord.PaymentAlternative // empty if only a proof is possible
 }
 
 // store packed record(s) in the Unverified table
@@ -118,6 +120,9 @@ func StoreIssues(issues []*transactionrecord.BitmarkIssue, isVerified bool) (*Is
 				transaction: packedIssue,
 			}
 			globalData.verified[txId] = v
+			batch := storage.NewBatch()
+			journalVerified(batch, txId, v)
+			batch.Commit()
 			return nil, false, nil
 		}
 	}
@@ -136,8 +141,9 @@ func StoreIssues(issues []*transactionrecord.BitmarkIssue, isVerified bool) (*Is
 	}
 
 	// if already seen just return pay id
-	if _, ok := globalData.unverified.entries[payId]; ok {
+	if entry, ok := globalData.unverified.entries[payId]; ok {
 		globalData.log.Debugf("duplicate pay id: %s", payId)
+		result.Payments = entry.payments
 		return result, true, nil
 	}
 
@@ -150,6 +156,13 @@ func StoreIssues(issues []*transactionrecord.BitmarkIssue, isVerified bool) (*Is
 
 	globalData.log.Infof("creating pay id: %s", payId)
 
+	// the fees are paid to the owner of the latest block
+	feeBlock := uint64(0)
+	if last, ok := storage.Pool.BlockOwners.LastElement(); ok {
+		feeBlock = binary.BigEndian.Uint64(last.Key)
+		result.Payments = payment.GetIssuePayments(last.Key, count)
+	}
+
 	expiresAt := time.Now().Add(constants.ReservoirTimeout)
 
 	// create index entries
@@ -167,12 +180,17 @@ func StoreIssues(issues []*transactionrecord.BitmarkIssue, isVerified bool) (*Is
 		},
 		nonce:      nonce, // FIXME: this value seems not used
 		difficulty: difficulty,
+		feeBlock:   feeBlock,
+		payments:   result.Payments,
 		expires:    expiresAt,
 	}
 	//copy(entry.txIds, txIds)
 	//copy(entry.transactions, transactions)
 
 	globalData.unverified.entries[payId] = entry
+	batch := storage.NewBatch()
+	journalUnverified(batch, payId, entry)
+	batch.Commit()
 
 	return result, false, nil
 }
@@ -228,7 +246,9 @@ func TryProof(payId pay.PayId, clientNonce []byte) TrackingStatus {
 		if bigDigest.Cmp(bigDifficulty) <= 0 {
 			globalData.log.Debugf("TryProof: success: pay id: %s", payId)
 			globalData.Lock()
-			setVerified(payId)
+			batch := storage.NewBatch()
+			setVerified(batch, payId)
+			batch.Commit()
 			globalData.Unlock()
 			return TrackingAccepted
 		}
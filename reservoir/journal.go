@@ -0,0 +1,311 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package reservoir
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/asset"
+	"github.com/bitmark-inc/bitmarkd/difficulty"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/pay"
+	"github.com/bitmark-inc/bitmarkd/payment"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"time"
+)
+
+// from storage/doc.go:
+//
+//   U ++ payId  - expiry time ++ pay nonce ++ difficulty bits ++ fee block number ++ packed records
+//   V ++ txId   - packed records
+//
+// the journal is written as the reservoir changes so that its
+// contents can be reloaded after a restart; all the records changed
+// by one reservoir operation are written in a single storage batch
+
+// size of the fixed part of an unverified entry
+const (
+	expiresSize         = 8
+	nonceOffset         = expiresSize
+	difficultyOffset    = nonceOffset + len(PayNonce{})
+	feeBlockOffset      = difficultyOffset + 8
+	unverifiedFixedSize = feeBlockOffset + 8
+)
+
+// number of journal records to read at once
+const journalFetchCount = 100
+
+// queue writing an unverified entry to the journal
+// hold lock before calling this
+func journalUnverified(batch *storage.Batch, payId pay.PayId, entry *unverifiedItem) {
+
+	data := make([]byte, unverifiedFixedSize)
+	binary.BigEndian.PutUint64(data, uint64(entry.expires.Unix()))
+	copy(data[nonceOffset:], entry.nonce[:])
+	if nil != entry.difficulty {
+		binary.BigEndian.PutUint64(data[difficultyOffset:], entry.difficulty.Bits())
+	}
+	binary.BigEndian.PutUint64(data[feeBlockOffset:], entry.feeBlock)
+
+	seen := make(map[string]struct{})
+	for i, transaction := range entry.transactions {
+		if nil != entry.assetIds {
+			data = appendPendingAsset(data, entry.assetIds[i], seen)
+		}
+		data = append(data, transaction...)
+	}
+
+	batch.Put(storage.Pool.PendingEntries, payId[:], data)
+}
+
+// queue writing a verified transaction to the journal
+// hold lock before calling this
+func journalVerified(batch *storage.Batch, txId merkle.Digest, item *verifiedItem) {
+
+	data := []byte{}
+	if nil != item.data.assetIds {
+		data = appendPendingAsset(data, item.data.assetIds[item.index], nil)
+	}
+	data = append(data, item.transaction...)
+
+	batch.Put(storage.Pool.VerifiedEntries, txId[:], data)
+}
+
+// the asset of an issue must be kept with it until the asset is confirmed
+func appendPendingAsset(data []byte, assetId []byte, seen map[string]struct{}) []byte {
+
+	if nil != seen {
+		if _, ok := seen[string(assetId)]; ok {
+			return data
+		}
+		seen[string(assetId)] = struct{}{}
+	}
+
+	assetIndex := transactionrecord.AssetIndex{}
+	copy(assetIndex[:], assetId)
+	packedAsset := asset.Get(assetIndex)
+	if nil == packedAsset {
+		return data // already confirmed
+	}
+	return append(data, packedAsset...)
+}
+
+// reload the journal, dropping anything that is expired or is no
+// longer valid on the current chain
+// hold lock before calling this
+func restoreJournal() error {
+
+	err := forEachJournalEntry(storage.Pool.PendingEntries, func(key []byte, value []byte) error {
+		payId := pay.PayId{}
+		if len(payId) != len(key) || len(value) < unverifiedFixedSize {
+			return fault.ErrInvalidLength
+		}
+		copy(payId[:], key)
+
+		expires := time.Unix(int64(binary.BigEndian.Uint64(value)), 0)
+		if time.Since(expires) > 0 {
+			return fault.ErrTransactionHasExpired
+		}
+
+		entry, err := restoreItem(value[unverifiedFixedSize:])
+		if nil != err {
+			return err
+		}
+		entry.expires = expires
+		copy(entry.nonce[:], value[nonceOffset:])
+		if bits := binary.BigEndian.Uint64(value[difficultyOffset:]); 0 != bits {
+			entry.difficulty = difficulty.New().SetBits(bits)
+		}
+		if nil == entry.links {
+			entry.payments, err = restoreIssuePayments(value[feeBlockOffset:unverifiedFixedSize], len(entry.txIds))
+			if nil != err {
+				return err
+			}
+			entry.feeBlock = binary.BigEndian.Uint64(value[feeBlockOffset:])
+		}
+
+		for _, txId := range entry.txIds {
+			globalData.unverified.index[txId] = payId
+		}
+		for _, link := range entry.links {
+			globalData.pendingTransfer[link] = entry.txIds[0]
+		}
+		globalData.unverified.entries[payId] = entry
+		return nil
+	})
+	if nil != err {
+		return err
+	}
+
+	return forEachJournalEntry(storage.Pool.VerifiedEntries, func(key []byte, value []byte) error {
+		entry, err := restoreItem(value)
+		if nil != err {
+			return err
+		}
+		if 1 != len(entry.txIds) || merkle.DigestLength != len(key) || !bytes.Equal(entry.txIds[0][:], key) {
+			return fault.ErrInvalidLength
+		}
+
+		txId := entry.txIds[0]
+		v := &verifiedItem{
+			data:        entry.itemData,
+			transaction: entry.transactions[0],
+			index:       0,
+		}
+		v.links = entry.links
+		for _, link := range entry.links {
+			globalData.pendingTransfer[link] = txId
+		}
+		globalData.verified[txId] = v
+		return nil
+	})
+}
+
+// call f for every record of a journal pool
+// any record for which f returns an error is removed from the journal
+func forEachJournalEntry(p *storage.PoolHandle, f func(key []byte, value []byte) error) error {
+
+	log := globalData.log
+	batch := storage.NewBatch()
+	cursor := p.NewFetchCursor()
+	for {
+		items, err := cursor.Fetch(journalFetchCount)
+		if nil != err {
+			batch.Discard()
+			return err
+		}
+		for _, item := range items {
+			err := f(item.Key, item.Value)
+			if nil != err {
+				log.Infof("restore: drop: %x  error: %v", item.Key, err)
+				batch.Delete(p, item.Key)
+			}
+		}
+		if len(items) < journalFetchCount {
+			batch.Commit()
+			return nil
+		}
+		cursor.Seek(append(items[len(items)-1].Key, 0))
+	}
+}
+
+// unpack and revalidate the records of one journal entry
+// hold lock before calling this
+func restoreItem(packed []byte) (*unverifiedItem, error) {
+
+	data := &itemData{}
+	var payments []transactionrecord.PaymentAlternative
+
+	for 0 != len(packed) {
+		transaction, n, err := transactionrecord.Packed(packed).Unpack()
+		if nil != err {
+			return nil, err
+		}
+		record := packed[:n]
+		packed = packed[n:]
+
+		switch tx := transaction.(type) {
+
+		case *transactionrecord.AssetData:
+			_, _, err := asset.Cache(tx)
+			if nil != err {
+				return nil, err
+			}
+
+		case *transactionrecord.BitmarkIssue:
+			packedIssue, err := tx.Pack(tx.Owner)
+			if nil != err {
+				return nil, err
+			}
+			if !asset.Exists(tx.AssetIndex) {
+				return nil, fault.ErrAssetNotFound
+			}
+			txId := packedIssue.MakeLink()
+			if _, ok := globalData.unverified.index[txId]; ok {
+				return nil, fault.ErrTransactionAlreadyExists
+			}
+			if _, ok := globalData.verified[txId]; ok {
+				return nil, fault.ErrTransactionAlreadyExists
+			}
+			if storage.Pool.Transactions.Has(txId[:]) {
+				return nil, fault.ErrTransactionAlreadyExists
+			}
+			if nil != data.links {
+				return nil, fault.ErrTransactionIsNotAnIssue
+			}
+			data.txIds = append(data.txIds, txId)
+			data.assetIds = append(data.assetIds, append([]byte{}, tx.AssetIndex[:]...))
+			data.transactions = append(data.transactions, record)
+
+		case *transactionrecord.BitmarkTransfer:
+			payments, err = restoreTransfer(data, []merkle.Digest{tx.Link}, tx, record)
+			if nil != err {
+				return nil, err
+			}
+
+		case *transactionrecord.BitmarkTransferCountersigned:
+			payments, err = restoreTransfer(data, []merkle.Digest{tx.Link}, tx, record)
+			if nil != err {
+				return nil, err
+			}
+
+		case *transactionrecord.BitmarkBurn:
+			payments, err = restoreTransfer(data, []merkle.Digest{tx.Link}, tx, record)
+			if nil != err {
+				return nil, err
+			}
+
+		case *transactionrecord.BitmarkBatchTransfer:
+			payments, err = restoreTransfer(data, tx.Links, tx, record)
+			if nil != err {
+				return nil, err
+			}
+
+		default:
+			return nil, fault.ErrTransactionIsNotAnIssueOrATransfer
+		}
+	}
+
+	if 0 == len(data.txIds) {
+		return nil, fault.ErrMissingParameters
+	}
+
+	entry := &unverifiedItem{
+		itemData: data,
+		payments: payments,
+	}
+	return entry, nil
+}
+
+// the fees of issues must still be paid to the same block owner
+// hold lock before calling this
+func restoreIssuePayments(blockNumberKey []byte, count int) ([]transactionrecord.PaymentAlternative, error) {
+	if bytes.Equal([]byte{0, 0, 0, 0, 0, 0, 0, 0}, blockNumberKey) {
+		return nil, nil // only a proof is possible
+	}
+	if !storage.Pool.BlockOwners.Has(blockNumberKey) {
+		return nil, fault.ErrBlockNotFound
+	}
+	return payment.GetIssuePayments(blockNumberKey, count), nil
+}
+
+// revalidate a transfer, batch transfer or burn, which is always the
+// only record of its entry
+// hold lock before calling this
+func restoreTransfer(data *itemData, links []merkle.Digest, transfer transactionrecord.Transaction, record []byte) ([]transactionrecord.PaymentAlternative, error) {
+	if 0 != len(data.txIds) {
+		return nil, fault.ErrTransactionIsNotATransfer
+	}
+	verifyResult, _, err := verifyTransfer(links, transfer)
+	if nil != err {
+		return nil, err
+	}
+	data.txIds = []merkle.Digest{verifyResult.txId}
+	data.links = links
+	data.transactions = [][]byte{record}
+	return verifyResult.payments, nil
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package reservoir_test
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/asset"
+	"github.com/bitmark-inc/bitmarkd/block"
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/reservoir"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"golang.org/x/crypto/ed25519"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// an account with its signing key
+type testOwner struct {
+	account    *account.Account
+	privateKey ed25519.PrivateKey
+}
+
+func newTestOwner(t *testing.T) testOwner {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if nil != err {
+		t.Fatalf("key pair generation error: %v", err)
+	}
+	return testOwner{
+		account: &account.Account{
+			AccountInterface: &account.ED25519Account{
+				Test:      mode.IsTesting(),
+				PublicKey: publicKey,
+			},
+		},
+		privateKey: privateKey,
+	}
+}
+
+func (owner testOwner) signIssue(t *testing.T, issue *transactionrecord.BitmarkIssue) transactionrecord.Packed {
+	packed, _ := issue.Pack(owner.account)
+	issue.Signature = ed25519.Sign(owner.privateKey, packed)
+	packed, err := issue.Pack(owner.account)
+	if nil != err {
+		t.Fatalf("issue pack error: %v", err)
+	}
+	return packed
+}
+
+func (owner testOwner) signTransfer(t *testing.T, transfer *transactionrecord.BitmarkTransfer) transactionrecord.Packed {
+	packed, _ := transfer.Pack(owner.account)
+	transfer.Signature = ed25519.Sign(owner.privateKey, packed)
+	packed, err := transfer.Pack(owner.account)
+	if nil != err {
+		t.Fatalf("transfer pack error: %v", err)
+	}
+	return packed
+}
+
+func (owner testOwner) signBurn(t *testing.T, burn *transactionrecord.BitmarkBurn) transactionrecord.Packed {
+	packed, _ := burn.Pack(owner.account)
+	burn.Signature = ed25519.Sign(owner.privateKey, packed)
+	packed, err := burn.Pack(owner.account)
+	if nil != err {
+		t.Fatalf("burn pack error: %v", err)
+	}
+	return packed
+}
+
+func (owner testOwner) signBatchTransfer(t *testing.T, batch *transactionrecord.BitmarkBatchTransfer) transactionrecord.Packed {
+	packed, _ := batch.Pack(owner.account)
+	batch.Signature = ed25519.Sign(owner.privateKey, packed)
+	packed, err := batch.Pack(owner.account)
+	if nil != err {
+		t.Fatalf("batch transfer pack error: %v", err)
+	}
+	return packed
+}
+
+// restart the reservoir and the asset cache
+func restart(t *testing.T) {
+	reservoir.Finalise()
+	asset.Finalise()
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+}
+
+// pending and verified transactions survive a restart
+// and stale entries are dropped when they are reloaded
+func TestJournal(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+	defer func() {
+		reservoir.Finalise()
+		asset.Finalise()
+	}()
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+
+	// an asset that is not yet confirmed
+	registration := &transactionrecord.AssetData{
+		Name:        "journal",
+		Fingerprint: "journal fingerprint",
+		Metadata:    "",
+		Registrant:  owner.account,
+	}
+	packed, _ := registration.Pack(owner.account)
+	registration.Signature = ed25519.Sign(owner.privateKey, packed)
+	assetIndex, _, err := asset.Cache(registration)
+	if nil != err {
+		t.Fatalf("asset cache error: %v", err)
+	}
+
+	pendingIssue := &transactionrecord.BitmarkIssue{
+		AssetIndex: *assetIndex,
+		Owner:      owner.account,
+		Nonce:      1,
+	}
+	pendingId := owner.signIssue(t, pendingIssue).MakeLink()
+	_, _, err = reservoir.StoreIssues([]*transactionrecord.BitmarkIssue{pendingIssue}, false)
+	if nil != err {
+		t.Fatalf("store pending issue error: %v", err)
+	}
+
+	verifiedIssue := &transactionrecord.BitmarkIssue{
+		AssetIndex: *assetIndex,
+		Owner:      owner.account,
+		Nonce:      2,
+	}
+	verifiedId := owner.signIssue(t, verifiedIssue).MakeLink()
+	_, _, err = reservoir.StoreIssues([]*transactionrecord.BitmarkIssue{verifiedIssue}, true)
+	if nil != err {
+		t.Fatalf("store verified issue error: %v", err)
+	}
+
+	// a confirmed issue in block 2 that can be transferred
+	confirmedIssue := &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("confirmed")),
+		Owner:      owner.account,
+		Nonce:      3,
+	}
+	packedIssue := owner.signIssue(t, confirmedIssue)
+	issueId := packedIssue.MakeLink()
+
+	blockOwner := transactionrecord.PackPaymentAddresses([]transactionrecord.PaymentAddress{
+		{Currency: currency.Bitcoin, Address: "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"},
+	})
+	blockNumber := []byte{0, 0, 0, 0, 0, 0, 0, 2}
+
+	batch := storage.NewBatch()
+	batch.Put(storage.Pool.BlockOwners, blockNumber, blockOwner)
+	batch.Put(storage.Pool.Transactions, issueId[:], packedIssue)
+	block.CreateOwnership(batch, issueId, 2, confirmedIssue.AssetIndex, owner.account)
+	batch.Commit()
+
+	transfer := &transactionrecord.BitmarkTransfer{
+		Link:  issueId,
+		Owner: newOwner.account,
+	}
+	transferId := owner.signTransfer(t, transfer).MakeLink()
+	_, _, err = reservoir.StoreTransfer(transfer)
+	if nil != err {
+		t.Fatalf("store transfer error: %v", err)
+	}
+
+	// an entry that expired while the node was stopped
+	expired := make([]byte, 32)
+	binary.BigEndian.PutUint64(expired, uint64(time.Now().Add(-time.Minute).Unix()))
+	packedExpired := owner.signIssue(t, &transactionrecord.BitmarkIssue{
+		AssetIndex: *assetIndex,
+		Owner:      owner.account,
+		Nonce:      4,
+	})
+	expiredKey := make([]byte, 48)
+	expiredKey[0] = 0x01
+	storage.Pool.PendingEntries.Put(expiredKey, append(expired, packedExpired...))
+
+	restart(t)
+
+	items := []struct {
+		title string
+		txId  merkle.Digest
+		state reservoir.TransactionState
+	}{
+		{"pending issue", pendingId, reservoir.StatePending},
+		{"verified issue", verifiedId, reservoir.StateVerified},
+		{"pending transfer", transferId, reservoir.StatePending},
+		{"expired issue", packedExpired.MakeLink(), reservoir.StateUnknown},
+	}
+	for _, item := range items {
+		if state := reservoir.TransactionStatus(item.txId); item.state != state {
+			t.Errorf("%s: state: %s  expected: %s", item.title, state, item.state)
+		}
+	}
+	if !asset.Exists(*assetIndex) {
+		t.Errorf("pending asset was not restored")
+	}
+	if storage.Pool.PendingEntries.Has(expiredKey) {
+		t.Errorf("expired entry was not removed")
+	}
+
+	// the transfer is confirmed by another node while this one is stopped
+	storage.Pool.Transactions.Put(transferId[:], []byte{})
+
+	restart(t)
+
+	if state := reservoir.TransactionStatus(transferId); reservoir.StateConfirmed != state {
+		t.Errorf("confirmed transfer: state: %s  expected: %s", state, reservoir.StateConfirmed)
+	}
+	if _, state := reservoir.PendingTransaction(transferId); reservoir.StateUnknown != state {
+		t.Errorf("confirmed transfer still in reservoir: state: %s", state)
+	}
+	if state := reservoir.TransactionStatus(pendingId); reservoir.StatePending != state {
+		t.Errorf("pending issue after second restart: state: %s  expected: %s", state, reservoir.StatePending)
+	}
+}
+
+// the fees for pending issues are still paid to the same block owner
+// after a restart, and are dropped if that block is removed
+func TestJournalIssuePayments(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+	defer func() {
+		reservoir.Finalise()
+		asset.Finalise()
+	}()
+
+	owner := newTestOwner(t)
+
+	registration := &transactionrecord.AssetData{
+		Name:        "fees",
+		Fingerprint: "fees fingerprint",
+		Metadata:    "",
+		Registrant:  owner.account,
+	}
+	packed, _ := registration.Pack(owner.account)
+	registration.Signature = ed25519.Sign(owner.privateKey, packed)
+	assetIndex, _, err := asset.Cache(registration)
+	if nil != err {
+		t.Fatalf("asset cache error: %v", err)
+	}
+
+	const feeAddress = "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"
+	blockNumber := []byte{0, 0, 0, 0, 0, 0, 0, 2}
+	storage.Pool.BlockOwners.Put(blockNumber, transactionrecord.PackPaymentAddresses([]transactionrecord.PaymentAddress{
+		{Currency: currency.Bitcoin, Address: feeAddress},
+	}))
+
+	issues := make([]*transactionrecord.BitmarkIssue, 2)
+	for i := range issues {
+		issues[i] = &transactionrecord.BitmarkIssue{
+			AssetIndex: *assetIndex,
+			Owner:      owner.account,
+			Nonce:      uint64(i + 1),
+		}
+		owner.signIssue(t, issues[i])
+	}
+
+	fee, _ := currency.Bitcoin.GetFee()
+	expected := []transactionrecord.PaymentAlternative{{
+		{Currency: currency.Bitcoin, Address: feeAddress, Amount: 2 * fee},
+	}}
+
+	stored, _, err := reservoir.StoreIssues(issues, false)
+	if nil != err {
+		t.Fatalf("store issues error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, stored.Payments) {
+		t.Errorf("payments: %v  expected: %v", stored.Payments, expected)
+	}
+
+	// a newer block does not change the fee destination
+	storage.Pool.BlockOwners.Put([]byte{0, 0, 0, 0, 0, 0, 0, 3}, transactionrecord.PackPaymentAddresses([]transactionrecord.PaymentAddress{
+		{Currency: currency.Bitcoin, Address: "mnnemVbQECtikaGZPYux4dGHH3YZyCg4sq"},
+	}))
+
+	restart(t)
+
+	restored, duplicate, err := reservoir.StoreIssues(issues, false)
+	if nil != err {
+		t.Fatalf("store issues after restart error: %v", err)
+	}
+	if !duplicate || restored.Id != stored.Id || !reflect.DeepEqual(expected, restored.Payments) {
+		t.Errorf("after restart: duplicate: %t  payments: %v  expected: %v", duplicate, restored.Payments, expected)
+	}
+
+	// the block is removed by a reorganisation while the node is stopped
+	storage.Pool.BlockOwners.Delete(blockNumber)
+
+	restart(t)
+
+	if state := reservoir.TransactionStatus(stored.TxIds[0]); reservoir.StateUnknown != state {
+		t.Errorf("issue paid to a removed block: state: %s  expected: %s", state, reservoir.StateUnknown)
+	}
+}
+
+// removing a confirmed transaction only changes the journal when the
+// batch of the block is committed
+func TestJournalBatch(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+	defer func() {
+		reservoir.Finalise()
+		asset.Finalise()
+	}()
+
+	owner := newTestOwner(t)
+	newOwner := newTestOwner(t)
+
+	issueId := confirmIssue(t, owner, "batch")
+	transfer := &transactionrecord.BitmarkTransfer{
+		Link:  issueId,
+		Owner: newOwner.account,
+	}
+	owner.signTransfer(t, transfer)
+	stored, _, err := reservoir.StoreTransfer(transfer)
+	if nil != err {
+		t.Fatalf("store transfer error: %v", err)
+	}
+	if !storage.Pool.PendingEntries.Has(stored.Id[:]) {
+		t.Fatalf("transfer was not journalled")
+	}
+
+	reservoir.Disable()
+	batch := storage.NewBatch()
+	reservoir.DeleteByTxId(batch, stored.TxId)
+	reservoir.Enable()
+
+	if !storage.Pool.PendingEntries.Has(stored.Id[:]) {
+		t.Errorf("journal changed before the batch was committed")
+	}
+	batch.Commit()
+	if storage.Pool.PendingEntries.Has(stored.Id[:]) {
+		t.Errorf("journal record remains after the batch was committed")
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package reservoir
+
+import (
+	"bytes"
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/constants"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/notify"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"sort"
+	"time"
+)
+
+// maximum offers returned for one account
+const (
+	MaximumOffers = 100
+)
+
+// limits on the offers held so that memory use is bounded by the
+// number of bitmarks owned
+const (
+	maximumAccountOffers = MaximumOffers // made by one account
+	maximumLinkOffers    = 10            // of one bitmark
+)
+
+// a countersigned transfer signed by the current owner that is
+// waiting for the new owner to add the countersignature
+//
+// offers are only held in memory by the node that received them and
+// are not part of the journal, so they are lost on a restart
+type Offer struct {
+	Id       merkle.Digest                                   `json:"id"`
+	From     *account.Account                                `json:"from"`
+	Transfer *transactionrecord.BitmarkTransferCountersigned `json:"transfer"`
+	Expires  time.Time                                       `json:"expires"`
+}
+
+// store an offer, the countersignature is ignored
+//
+// returns true if the same offer was already present
+func StoreOffer(transfer *transactionrecord.BitmarkTransferCountersigned) (*Offer, bool, error) {
+
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	currentOwner, _, err := linkOwner(transfer.Link)
+	if nil != err {
+		return nil, false, err
+	}
+
+	offered := *transfer
+	offered.Countersignature = nil
+
+	packedOffer, err := offered.PackOffer(currentOwner)
+	if nil != err {
+		return nil, false, err
+	}
+
+	id := packedOffer.MakeLink()
+	if offer, ok := globalData.offers[id]; ok {
+		return offer, true, nil
+	}
+
+	// the link must still be held by the current owner
+	if _, ok := globalData.pendingTransfer[transfer.Link]; ok {
+		return nil, false, fault.ErrDoubleTransferAttempt
+	}
+	if !storage.Pool.OwnerDigest.Has(append(currentOwner.Bytes(), transfer.Link[:]...)) {
+		return nil, false, fault.ErrDoubleTransferAttempt
+	}
+
+	// expired offers do not count towards the limits
+	from := string(currentOwner.Bytes())
+	now := time.Now()
+	linkCount := 0
+	for offerId := range globalData.offersFrom[from] {
+		o := globalData.offers[offerId]
+		if now.After(o.Expires) {
+			deleteOffer(offerId)
+		} else if o.Transfer.Link == transfer.Link {
+			linkCount += 1
+		}
+	}
+	if linkCount >= maximumLinkOffers || len(globalData.offersFrom[from]) >= maximumAccountOffers {
+		return nil, false, fault.ErrTooManyOffers
+	}
+
+	offer := &Offer{
+		Id:       id,
+		From:     currentOwner,
+		Transfer: &offered,
+		Expires:  time.Now().Add(constants.OfferTimeout),
+	}
+	globalData.offers[id] = offer
+	if _, ok := globalData.offersFrom[from]; !ok {
+		globalData.offersFrom[from] = make(map[merkle.Digest]struct{})
+	}
+	globalData.offersFrom[from][id] = struct{}{}
+
+	notify.Publish(notify.Event{
+		Kind:  notify.Offered,
+		Owner: offered.Owner,
+		TxId:  id,
+	})
+
+	return offer, false, nil
+}
+
+// list the offers made by or to an account, oldest first
+func ListOffers(owner *account.Account) []Offer {
+
+	globalData.RLock()
+	defer globalData.RUnlock()
+
+	now := time.Now()
+	ownerBytes := owner.Bytes()
+
+	offers := make(offerList, 0, 10)
+	for _, offer := range globalData.offers {
+		if now.After(offer.Expires) {
+			continue
+		}
+		if bytes.Equal(ownerBytes, offer.From.Bytes()) || bytes.Equal(ownerBytes, offer.Transfer.Owner.Bytes()) {
+			offers = append(offers, *offer)
+		}
+	}
+	sort.Sort(offers)
+
+	if len(offers) > MaximumOffers {
+		offers = offers[:MaximumOffers]
+	}
+	return offers
+}
+
+// add the new owner's countersignature and store the completed transfer
+func AcceptOffer(id merkle.Digest, countersignature account.Signature) (*TransferInfo, bool, error) {
+
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	offer, err := currentOffer(id)
+	if nil != err {
+		return nil, false, err
+	}
+
+	transfer := *offer.Transfer
+	transfer.Countersignature = countersignature
+
+	result, duplicate, err := storeTransfer([]merkle.Digest{transfer.Link}, &transfer, transfer.Owner)
+	if nil != err {
+		return nil, false, err
+	}
+
+	deleteOffer(id)
+
+	return result, duplicate, nil
+}
+
+// remove an offer, either party can do this by signing the offer id
+func RejectOffer(id merkle.Digest, signature account.Signature) error {
+
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	offer, err := currentOffer(id)
+	if nil != err {
+		return err
+	}
+
+	if nil != offer.Transfer.Owner.CheckSignature(id[:], signature) && nil != offer.From.CheckSignature(id[:], signature) {
+		return fault.ErrInvalidSignature
+	}
+
+	deleteOffer(id)
+
+	return nil
+}
+
+// find an offer that has not expired
+// hold lock before calling this
+func currentOffer(id merkle.Digest) (*Offer, error) {
+	offer, ok := globalData.offers[id]
+	if !ok {
+		return nil, fault.ErrOfferNotFound
+	}
+	if time.Now().After(offer.Expires) {
+		deleteOffer(id)
+		return nil, fault.ErrOfferNotFound
+	}
+	return offer, nil
+}
+
+// drop offers that were not accepted in time
+// hold lock before calling this
+func expireOffers() {
+	now := time.Now()
+	for id, offer := range globalData.offers {
+		if now.After(offer.Expires) {
+			deleteOffer(id)
+		}
+	}
+}
+
+// remove an offer and its index entry
+// hold lock before calling this
+func deleteOffer(id merkle.Digest) {
+	offer, ok := globalData.offers[id]
+	if !ok {
+		return
+	}
+	delete(globalData.offers, id)
+
+	from := string(offer.From.Bytes())
+	delete(globalData.offersFrom[from], id)
+	if 0 == len(globalData.offersFrom[from]) {
+		delete(globalData.offersFrom, from)
+	}
+}
+
+// to sort offers by expiry time
+type offerList []Offer
+
+func (a offerList) Len() int {
+	return len(a)
+}
+
+func (a offerList) Swap(i, j int) {
+	a[i], a[j] = a[j], a[i]
+}
+
+func (a offerList) Less(i, j int) bool {
+	if a[i].Expires.Equal(a[j].Expires) {
+		return bytes.Compare(a[i].Id[:], a[j].Id[:]) < 0
+	}
+	return a[i].Expires.Before(a[j].Expires)
+}
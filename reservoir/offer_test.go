@@ -0,0 +1,188 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package reservoir_test
+
+import (
+	"fmt"
+	"github.com/bitmark-inc/bitmarkd/asset"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/reservoir"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"golang.org/x/crypto/ed25519"
+	"testing"
+)
+
+// an offer needs the new owner's countersignature before it becomes
+// a pending transfer and either party can remove it
+func TestOffer(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+	defer func() {
+		reservoir.Finalise()
+		asset.Finalise()
+	}()
+
+	owner := newTestOwner(t)
+	recipient := newTestOwner(t)
+	other := newTestOwner(t)
+
+	issueId := confirmIssue(t, owner, "offer")
+
+	newOffer := func(signer testOwner, to testOwner) (*transactionrecord.BitmarkTransferCountersigned, []byte) {
+		transfer := &transactionrecord.BitmarkTransferCountersigned{
+			Link:  issueId,
+			Owner: to.account,
+		}
+		message, _ := transfer.PackOffer(signer.account)
+		transfer.Signature = ed25519.Sign(signer.privateKey, message)
+		return transfer, message
+	}
+
+	stolen, _ := newOffer(other, recipient)
+	if _, _, err := reservoir.StoreOffer(stolen); fault.ErrInvalidSignature != err {
+		t.Errorf("offer by other: error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+
+	transfer, message := newOffer(owner, recipient)
+	offer, duplicate, err := reservoir.StoreOffer(transfer)
+	if nil != err {
+		t.Fatalf("store offer error: %v", err)
+	}
+	if duplicate {
+		t.Errorf("first offer is duplicate")
+	}
+	if _, duplicate, err := reservoir.StoreOffer(transfer); nil != err || !duplicate {
+		t.Errorf("repeated offer: duplicate: %t  error: %v", duplicate, err)
+	}
+
+	for _, item := range []struct {
+		title string
+		owner testOwner
+		count int
+	}{
+		{"owner", owner, 1},
+		{"recipient", recipient, 1},
+		{"other", other, 0},
+	} {
+		offers := reservoir.ListOffers(item.owner.account)
+		if item.count != len(offers) {
+			t.Errorf("%s: offers: %d  expected: %d", item.title, len(offers), item.count)
+		} else if 1 == item.count && offer.Id != offers[0].Id {
+			t.Errorf("%s: offer id: %v  expected: %v", item.title, offers[0].Id, offer.Id)
+		}
+	}
+
+	// only the recipient can countersign
+	if _, _, err := reservoir.AcceptOffer(offer.Id, ed25519.Sign(owner.privateKey, message)); fault.ErrInvalidCountersignature != err {
+		t.Errorf("accept by owner: error: %v  expected: %v", err, fault.ErrInvalidCountersignature)
+	}
+
+	// only the two parties can reject
+	if err := reservoir.RejectOffer(offer.Id, ed25519.Sign(other.privateKey, offer.Id[:])); fault.ErrInvalidSignature != err {
+		t.Errorf("reject by other: error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+
+	stored, _, err := reservoir.AcceptOffer(offer.Id, ed25519.Sign(recipient.privateKey, message))
+	if nil != err {
+		t.Fatalf("accept error: %v", err)
+	}
+	if state := reservoir.TransactionStatus(stored.TxId); reservoir.StatePending != state {
+		t.Errorf("accepted transfer: state: %s  expected: %s", state, reservoir.StatePending)
+	}
+	if _, _, err := reservoir.AcceptOffer(offer.Id, ed25519.Sign(recipient.privateKey, message)); fault.ErrOfferNotFound != err {
+		t.Errorf("second accept: error: %v  expected: %v", err, fault.ErrOfferNotFound)
+	}
+	if offers := reservoir.ListOffers(recipient.account); 0 != len(offers) {
+		t.Errorf("offers after accept: %d", len(offers))
+	}
+
+	// the link is now pending so it cannot be offered again
+	transfer, _ = newOffer(owner, other)
+	if _, _, err := reservoir.StoreOffer(transfer); fault.ErrDoubleTransferAttempt != err {
+		t.Errorf("offer of pending link: error: %v  expected: %v", err, fault.ErrDoubleTransferAttempt)
+	}
+
+	// the recipient rejects an offer of a different bitmark
+	issueId = confirmIssue(t, owner, "rejected offer")
+	transfer, _ = newOffer(owner, recipient)
+	offer, _, err = reservoir.StoreOffer(transfer)
+	if nil != err {
+		t.Fatalf("store offer error: %v", err)
+	}
+	if err := reservoir.RejectOffer(offer.Id, ed25519.Sign(recipient.privateKey, offer.Id[:])); nil != err {
+		t.Errorf("reject error: %v", err)
+	}
+	if offers := reservoir.ListOffers(owner.account); 0 != len(offers) {
+		t.Errorf("offers after reject: %d", len(offers))
+	}
+}
+
+// the offers held for one bitmark and for one account are limited
+func TestOfferLimits(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+	defer func() {
+		reservoir.Finalise()
+		asset.Finalise()
+	}()
+
+	const linkOffers = 10 // offers allowed for one bitmark
+
+	owner := newTestOwner(t)
+
+	newOffer := func(link merkle.Digest) *transactionrecord.BitmarkTransferCountersigned {
+		transfer := &transactionrecord.BitmarkTransferCountersigned{
+			Link:  link,
+			Owner: newTestOwner(t).account,
+		}
+		message, _ := transfer.PackOffer(owner.account)
+		transfer.Signature = ed25519.Sign(owner.privateKey, message)
+		return transfer
+	}
+
+	var last *reservoir.Offer
+	for i := 0; i < reservoir.MaximumOffers/linkOffers; i += 1 {
+		issueId := confirmIssue(t, owner, fmt.Sprintf("limit %d", i))
+		for j := 0; j < linkOffers; j += 1 {
+			offer, _, err := reservoir.StoreOffer(newOffer(issueId))
+			if nil != err {
+				t.Fatalf("link: %d  offer: %d  store offer error: %v", i, j, err)
+			}
+			last = offer
+		}
+		if _, _, err := reservoir.StoreOffer(newOffer(issueId)); fault.ErrTooManyOffers != err {
+			t.Errorf("link: %d  extra offer: error: %v  expected: %v", i, err, fault.ErrTooManyOffers)
+		}
+	}
+
+	// the account is at its limit even for a bitmark with no offers
+	issueId := confirmIssue(t, owner, "limit extra")
+	if _, _, err := reservoir.StoreOffer(newOffer(issueId)); fault.ErrTooManyOffers != err {
+		t.Errorf("account extra offer: error: %v  expected: %v", err, fault.ErrTooManyOffers)
+	}
+
+	// withdrawing an offer makes room for another
+	if err := reservoir.RejectOffer(last.Id, ed25519.Sign(owner.privateKey, last.Id[:])); nil != err {
+		t.Fatalf("reject error: %v", err)
+	}
+	if _, _, err := reservoir.StoreOffer(newOffer(issueId)); nil != err {
+		t.Errorf("offer after reject: error: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package reservoir
+
+import (
+	"bytes"
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/pay"
+	"github.com/bitmark-inc/bitmarkd/payment"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+)
+
+// maximum currency transactions read for one pay id
+const maximumPaymentRecords = 100
+
+// fetch every payment confirmation record stored for a pay id
+func paymentRecords(payId pay.PayId) ([][]byte, error) {
+
+	items, err := storage.Pool.Payment.NewFetchCursor().Seek(payId[:]).Fetch(maximumPaymentRecords)
+	if nil != err {
+		return nil, err
+	}
+
+	records := make([][]byte, 0, len(items))
+	for _, item := range items {
+		if !bytes.HasPrefix(item.Key, payId[:]) {
+			break
+		}
+		records = append(records, item.Value)
+	}
+	return records, nil
+}
+
+// check that the stored payment records cover everything an entry requires
+//
+// records in the same currency are accumulated so that a payment can
+// be topped up by a later currency transaction; the total in one of
+// the currencies must pay each expected (currency, address, amount)
+// of the alternative in that currency with payments to the same
+// address accumulated
+//
+// returns TrackingAccepted if the payment is sufficient, TrackingNotFound
+// if nothing has been paid yet otherwise TrackingInvalid and the reason
+func checkPayment(entry *unverifiedItem, packedPayments [][]byte) (TrackingStatus, error) {
+
+	if 0 == len(packedPayments) {
+		return TrackingNotFound, nil
+	}
+
+	totals := make([]*payment.Record, 0, len(packedPayments))
+	index := make(map[currency.Currency]*payment.Record)
+	for _, packed := range packedPayments {
+		record, err := payment.UnpackRecord(packed)
+		if nil != err {
+			return TrackingInvalid, err
+		}
+		total, ok := index[record.Currency]
+		if !ok {
+			index[record.Currency] = record
+			totals = append(totals, record)
+			continue
+		}
+		for address, value := range record.Amounts {
+			total.Amounts[address] += value
+		}
+	}
+
+	// report why the first currency paid was not sufficient
+	var reason error
+	for _, total := range totals {
+		err := checkAlternatives(entry.payments, total)
+		if nil == err {
+			return TrackingAccepted, nil
+		}
+		if nil == reason {
+			reason = err
+		}
+	}
+	return TrackingInvalid, reason
+}
+
+// every payment of the alternative in the paid currency must be
+// covered by the amount paid to its address
+func checkAlternatives(alternatives []transactionrecord.PaymentAlternative, record *payment.Record) error {
+
+	if 0 == len(alternatives) {
+		return fault.ErrMissingParameters
+	}
+
+	var expected transactionrecord.PaymentAlternative
+	for _, alternative := range alternatives {
+		if 0 != len(alternative) && alternative[0].Currency == record.Currency {
+			expected = alternative
+			break
+		}
+	}
+	if nil == expected {
+		return fault.ErrInvalidMixedCurrencyPayment
+	}
+
+	required := make(map[string]uint64)
+	for _, p := range expected {
+		if p.Currency != record.Currency {
+			return fault.ErrInvalidMixedCurrencyPayment
+		}
+		required[p.Address] += p.Amount
+	}
+
+	for address, amount := range required {
+		paid, ok := record.Amounts[address]
+		if !ok {
+			return fault.ErrPaymentAddressNotFound
+		}
+		if paid < amount {
+			return fault.ErrInsufficientPayment
+		}
+	}
+	return nil
+}
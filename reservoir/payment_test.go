@@ -0,0 +1,267 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package reservoir
+
+import (
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"github.com/bitmark-inc/bitmarkd/util"
+	"testing"
+)
+
+// test addresses
+const (
+	issuerAddress   = "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"
+	transferAddress = "mnnemVbQECtikaGZPYux4dGHH3YZyCg4sq"
+	sellerAddress   = "mjPkDNakVA4w4hJZ6WF7p8yKUV2merhyCM"
+)
+
+// an address and the value paid to it
+type testAmount struct {
+	address string
+	value   uint64
+}
+
+// create a payment record in the same format as the currency scanners
+func packPayment(c currency.Currency, amounts ...testAmount) []byte {
+	packed := util.ToVarint64(c.Uint64())
+	txId := []byte{0x01, 0x02, 0x03, 0x04}
+	packed = append(packed, util.ToVarint64(uint64(len(txId)))...)
+	packed = append(packed, txId...)
+	packed = append(packed, util.ToVarint64(uint64(len(amounts)))...)
+	for _, a := range amounts {
+		packed = append(packed, util.ToVarint64(uint64(len(a.address)))...)
+		packed = append(packed, a.address...)
+		packed = append(packed, util.ToVarint64(a.value)...)
+	}
+	return packed
+}
+
+func TestCheckPayment(t *testing.T) {
+
+	transfer := &unverifiedItem{
+		itemData: &itemData{
+			txIds: []merkle.Digest{{}},
+			links: []merkle.Digest{{}},
+		},
+		payments: []transactionrecord.PaymentAlternative{{
+			{Currency: currency.Bitcoin, Address: issuerAddress, Amount: 20000},
+			{Currency: currency.Bitcoin, Address: transferAddress, Amount: 10000},
+		}},
+	}
+
+	// a separate payment to the same address must be added to it
+	sale := &unverifiedItem{
+		itemData: transfer.itemData,
+		payments: []transactionrecord.PaymentAlternative{{
+			{Currency: currency.Bitcoin, Address: issuerAddress, Amount: 20000},
+			{Currency: currency.Bitcoin, Address: issuerAddress, Amount: 500000},
+		}},
+	}
+
+	// the alternative in the paid currency is the one checked
+	alternatives := &unverifiedItem{
+		itemData: transfer.itemData,
+		payments: []transactionrecord.PaymentAlternative{
+			{
+				{Currency: currency.Nothing, Address: sellerAddress, Amount: 1},
+			},
+			{
+				{Currency: currency.Bitcoin, Address: issuerAddress, Amount: 20000},
+				{Currency: currency.Bitcoin, Address: transferAddress, Amount: 10000},
+			},
+		},
+	}
+
+	// the fees for three issues
+	issues := &unverifiedItem{
+		itemData: &itemData{
+			txIds: []merkle.Digest{{1}, {2}, {3}},
+		},
+		payments: []transactionrecord.PaymentAlternative{{
+			{Currency: currency.Bitcoin, Address: issuerAddress, Amount: 30000},
+		}},
+	}
+
+	// without a block owner issues can only be proved
+	proofOnly := &unverifiedItem{
+		itemData: issues.itemData,
+	}
+
+	truncated := packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000})
+	truncated = truncated[:len(truncated)-5]
+
+	tests := []struct {
+		title   string
+		entry   *unverifiedItem
+		payment [][]byte
+		status  TrackingStatus
+		err     error
+	}{
+		{
+			title:   "exact transfer payment",
+			entry:   transfer,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000}, testAmount{transferAddress, 10000})},
+			status:  TrackingAccepted,
+		},
+		{
+			title:   "transfer overpaid with change",
+			entry:   transfer,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{transferAddress, 10001}, testAmount{sellerAddress, 77}, testAmount{issuerAddress, 30000})},
+			status:  TrackingAccepted,
+		},
+		{
+			title:   "transfer underpaid",
+			entry:   transfer,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000}, testAmount{transferAddress, 9999})},
+			status:  TrackingInvalid,
+			err:     fault.ErrInsufficientPayment,
+		},
+		{
+			title:   "transfer address missing",
+			entry:   transfer,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 30000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrPaymentAddressNotFound,
+		},
+		{
+			title:   "transfer paid to wrong address",
+			entry:   transfer,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000}, testAmount{sellerAddress, 10000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrPaymentAddressNotFound,
+		},
+		{
+			title:   "transfer paid in other currency",
+			entry:   transfer,
+			payment: [][]byte{packPayment(currency.Nothing, testAmount{issuerAddress, 20000}, testAmount{transferAddress, 10000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrInvalidMixedCurrencyPayment,
+		},
+		{
+			title:   "second alternative paid",
+			entry:   alternatives,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000}, testAmount{transferAddress, 10000})},
+			status:  TrackingAccepted,
+		},
+		{
+			title:   "second alternative paid to first alternative address",
+			entry:   alternatives,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{sellerAddress, 30000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrPaymentAddressNotFound,
+		},
+		{
+			title:   "sale paid in full",
+			entry:   sale,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 520000})},
+			status:  TrackingAccepted,
+		},
+		{
+			title:   "sale only paid fee",
+			entry:   sale,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 500000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrInsufficientPayment,
+		},
+		{
+			title:   "issue fees paid",
+			entry:   issues,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{sellerAddress, 12345}, testAmount{issuerAddress, 30000})},
+			status:  TrackingAccepted,
+		},
+		{
+			title:   "issue fees paid to the payer's own address",
+			entry:   issues,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{sellerAddress, 30000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrPaymentAddressNotFound,
+		},
+		{
+			title:   "issue fees split between addresses",
+			entry:   issues,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 15000}, testAmount{transferAddress, 15000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrInsufficientPayment,
+		},
+		{
+			title:   "issue fees not in a currency",
+			entry:   issues,
+			payment: [][]byte{packPayment(currency.Nothing, testAmount{issuerAddress, 30000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrInvalidMixedCurrencyPayment,
+		},
+		{
+			title: "issue fees topped up",
+			entry: issues,
+			payment: [][]byte{
+				packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000}),
+				packPayment(currency.Bitcoin, testAmount{sellerAddress, 500}, testAmount{issuerAddress, 10000}),
+			},
+			status: TrackingAccepted,
+		},
+		{
+			title: "top up in another currency",
+			entry: issues,
+			payment: [][]byte{
+				packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000}),
+				packPayment(currency.Nothing, testAmount{issuerAddress, 10000}),
+			},
+			status: TrackingInvalid,
+			err:    fault.ErrInsufficientPayment,
+		},
+		{
+			title:   "issues that can only be proved",
+			entry:   proofOnly,
+			payment: [][]byte{packPayment(currency.Bitcoin, testAmount{issuerAddress, 30000})},
+			status:  TrackingInvalid,
+			err:     fault.ErrMissingParameters,
+		},
+		{
+			title:  "nothing paid yet",
+			entry:  issues,
+			status: TrackingNotFound,
+		},
+		{
+			title: "transfer topped up",
+			entry: transfer,
+			payment: [][]byte{
+				packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000}, testAmount{transferAddress, 5000}),
+				packPayment(currency.Bitcoin, testAmount{transferAddress, 5000}),
+			},
+			status: TrackingAccepted,
+		},
+		{
+			title:   "truncated record",
+			entry:   transfer,
+			payment: [][]byte{truncated},
+			status:  TrackingInvalid,
+			err:     fault.ErrInvalidLength,
+		},
+		{
+			title:   "unknown currency",
+			entry:   transfer,
+			payment: [][]byte{util.ToVarint64(1000)},
+			status:  TrackingInvalid,
+			err:     fault.ErrInvalidCurrency,
+		},
+		{
+			title:   "trailing data",
+			entry:   transfer,
+			payment: [][]byte{append(packPayment(currency.Bitcoin, testAmount{issuerAddress, 20000}, testAmount{transferAddress, 10000}), 0x00)},
+			status:  TrackingInvalid,
+			err:     fault.ErrInvalidLength,
+		},
+	}
+
+	for i, test := range tests {
+		status, err := checkPayment(test.entry, test.payment)
+		if test.status != status || test.err != err {
+			t.Errorf("%d: %s: status: %s  error: %v  expected: %s  error: %v", i, test.title, status, err, test.status, test.err)
+		}
+	}
+}
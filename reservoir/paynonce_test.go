@@ -53,7 +53,7 @@ func TestNewPayNonceBitmark(t *testing.T) {
 	setup(t)
 	defer teardown(t)
 
-	d, n := block.Get()
+	d, n, _ := block.Get()
 	t.Logf("block: %d  %#v", n, d)
 
 	nonce := reservoir.NewPayNonce()
@@ -81,7 +81,7 @@ func TestNewPayNonceTesting(t *testing.T) {
 	setup(t)
 	defer teardown(t)
 
-	d, n := block.Get()
+	d, n, _ := block.Get()
 	t.Logf("block: %d  %#v", n, d)
 
 	nonce := reservoir.NewPayNonce()
@@ -30,6 +30,13 @@ type globalDataType struct {
 	// from an invalid duplicate transfer
 	pendingTransfer map[merkle.Digest]merkle.Digest
 
+	// countersigned transfers waiting for the new owner
+	offers map[merkle.Digest]*Offer
+
+	// offer ids indexed by the account that made them to limit the
+	// offers that one account can hold
+	offersFrom map[string]map[merkle.Digest]struct{}
+
 	verifier      verifierData
 	rebroadcaster rebroadcaster
 	background    *background.T
@@ -42,21 +49,23 @@ type unverifiedEntry struct {
 
 type itemData struct {
 	txIds        []merkle.Digest
-	links        []merkle.Digest // links[i] corresponds to txIds[i]
+	links        []merkle.Digest // transfers only: every link spent by the single transaction
 	assetIds     [][]byte        // asset[i] index corresponds to txIds[iu]
 	transactions [][]byte        // transactions[i] corresponds to txIds[i]
 }
 
 type unverifiedItem struct {
 	*itemData
-	nonce      PayNonce                     // only for issues
-	difficulty *difficulty.Difficulty       // only for issues
-	payments   []*transactionrecord.Payment // currently only for transfers
+	nonce      PayNonce                               // only for issues
+	difficulty *difficulty.Difficulty                 // only for issues
+	feeBlock   uint64                                 // only for issues: block whose owner is paid the fees
+	payments   []transactionrecord.PaymentAlternative // empty if issues can only be proved
+	invalid    error                                  // why the last payment check failed
 	expires    time.Time
 }
 
 type verifiedItem struct {
-	link        merkle.Digest
+	links       []merkle.Digest
 	transaction []byte
 	data        *itemData // point to the item struct
 	index       int       // index of assetIds and transactions in an item
@@ -74,7 +83,9 @@ type rebroadcaster struct {
 // gobal storage
 var globalData globalDataType
 
-// create the cache
+// create the cache and reload it from the journal
+//
+// storage, asset and block must be initialised before this
 func Initialise() error {
 
 	globalData.Lock()
@@ -90,6 +101,14 @@ func Initialise() error {
 	globalData.unverified.index = make(map[merkle.Digest]pay.PayId)
 	globalData.verified = make(map[merkle.Digest]*verifiedItem)
 	globalData.pendingTransfer = make(map[merkle.Digest]merkle.Digest)
+	globalData.offers = make(map[merkle.Digest]*Offer)
+	globalData.offersFrom = make(map[string]map[merkle.Digest]struct{})
+
+	// reload anything that was waiting before the last shutdown
+	if err := restoreJournal(); nil != err {
+		return err
+	}
+	globalData.log.Infof("restored: unverified: %d  verified: %d", len(globalData.unverified.entries), len(globalData.verified))
 
 	globalData.enabled = true
 
@@ -188,9 +207,50 @@ func TransactionStatus(txId merkle.Digest) TransactionState {
 	return StateUnknown
 }
 
+// get the result of checking the payment of a pending transaction
+//
+// returns TrackingInvalid and the reason if the payments found so far
+// are not sufficient, otherwise TrackingNotFound
+func PaymentStatus(txId merkle.Digest) (TrackingStatus, error) {
+	globalData.RLock()
+	defer globalData.RUnlock()
+
+	payId, ok := globalData.unverified.index[txId]
+	if !ok {
+		return TrackingNotFound, nil
+	}
+	entry := globalData.unverified.entries[payId]
+	if nil == entry.invalid {
+		return TrackingNotFound, nil
+	}
+	return TrackingInvalid, entry.invalid
+}
+
+// get a transaction that is waiting to be confirmed
+func PendingTransaction(txId merkle.Digest) (transactionrecord.Packed, TransactionState) {
+	globalData.RLock()
+	defer globalData.RUnlock()
+
+	if payId, ok := globalData.unverified.index[txId]; ok {
+		entry := globalData.unverified.entries[payId]
+		for i, id := range entry.txIds {
+			if id == txId {
+				return entry.transactions[i], StatePending
+			}
+		}
+	}
+
+	if v, ok := globalData.verified[txId]; ok {
+		return v.transaction, StateVerified
+	}
+
+	return nil, StateUnknown
+}
+
 // move transaction(s) to verified cache
+// the journal changes are queued in the batch
 // must hold lock before calling this
-func setVerified(payId pay.PayId) {
+func setVerified(batch *storage.Batch, payId pay.PayId) {
 	entry, ok := globalData.unverified.entries[payId]
 	if ok {
 		// move the record
@@ -199,14 +259,14 @@ func setVerified(payId pay.PayId) {
 				data:        entry.itemData,
 				transaction: entry.transactions[i],
 				index:       i,
-			}
-			if nil != entry.links {
-				v.link = entry.links[i]
+				links:       entry.links,
 			}
 			globalData.verified[txId] = v
 			delete(globalData.unverified.index, txId)
+			journalVerified(batch, txId, v)
 		}
 		delete(globalData.unverified.entries, payId)
+		batch.Delete(storage.Pool.PendingEntries, payId[:])
 	}
 }
 
@@ -252,36 +312,42 @@ func Enable() {
 }
 
 // remove a record using a transaction id
-func DeleteByTxId(txId merkle.Digest) {
+// the journal change is queued in the batch of the caller
+func DeleteByTxId(batch *storage.Batch, txId merkle.Digest) {
 	globalData.Lock()
 	if globalData.enabled {
 		fault.Panic("reservoir delete tx id when not locked")
 	}
 	if payId, ok := globalData.unverified.index[txId]; ok {
-		internalDelete(payId)
+		internalDelete(batch, payId)
 	}
 	if v, ok := globalData.verified[txId]; ok {
-		link := v.link
 		delete(globalData.verified, txId)
-		delete(globalData.pendingTransfer, link)
+		for _, link := range v.links {
+			delete(globalData.pendingTransfer, link)
+		}
+		batch.Delete(storage.Pool.VerifiedEntries, txId[:])
 	}
 	globalData.Unlock()
 }
 
 // remove a record using a link id
-func DeleteByLink(link merkle.Digest) {
+// the journal change is queued in the batch of the caller
+func DeleteByLink(batch *storage.Batch, link merkle.Digest) {
 	globalData.Lock()
 	if globalData.enabled {
 		fault.Panic("reservoir delete link when not locked")
 	}
 	if txId, ok := globalData.pendingTransfer[link]; ok {
 		if payId, ok := globalData.unverified.index[txId]; ok {
-			internalDelete(payId)
+			internalDelete(batch, payId)
 		}
 		if v, ok := globalData.verified[txId]; ok {
-			link := v.link
 			delete(globalData.verified, txId)
-			delete(globalData.pendingTransfer, link)
+			for _, link := range v.links {
+				delete(globalData.pendingTransfer, link)
+			}
+			batch.Delete(storage.Pool.VerifiedEntries, txId[:])
 		}
 	}
 	globalData.Unlock()
@@ -289,17 +355,18 @@ func DeleteByLink(link merkle.Digest) {
 
 // hold lock before calling
 // delete unverified transactions
-func internalDelete(payId pay.PayId) {
+func internalDelete(batch *storage.Batch, payId pay.PayId) {
 	entry, ok := globalData.unverified.entries[payId]
 	if ok {
-		for i, txId := range entry.txIds {
+		for _, txId := range entry.txIds {
 			delete(globalData.unverified.index, txId)
 			delete(globalData.verified, txId)
-			if nil != entry.links {
-				link := entry.links[i]
-				delete(globalData.pendingTransfer, link)
-			}
+			batch.Delete(storage.Pool.VerifiedEntries, txId[:])
+		}
+		for _, link := range entry.links {
+			delete(globalData.pendingTransfer, link)
 		}
 		delete(globalData.unverified.entries, payId)
+		batch.Delete(storage.Pool.PendingEntries, payId[:])
 	}
 }
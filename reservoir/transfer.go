@@ -5,10 +5,12 @@
 package reservoir
 
 import (
+	"bytes"
 	"github.com/bitmark-inc/bitmarkd/account"
 	"github.com/bitmark-inc/bitmarkd/constants"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/notify"
 	"github.com/bitmark-inc/bitmarkd/pay"
 	"github.com/bitmark-inc/bitmarkd/payment"
 	"github.com/bitmark-inc/bitmarkd/storage"
@@ -21,7 +23,7 @@ type TransferInfo struct {
 	Id       pay.PayId
 	TxId     merkle.Digest
 	Packed   []byte
-	Payments []*transactionrecord.Payment
+	Payments []transactionrecord.PaymentAlternative // at least one
 }
 
 // store a single transfer
@@ -31,7 +33,52 @@ func StoreTransfer(transfer *transactionrecord.BitmarkTransfer) (*TransferInfo,
 	globalData.Lock()
 	defer globalData.Unlock()
 
-	verifyResult, duplicate, err := verifyTransfer(transfer)
+	return storeTransfer([]merkle.Digest{transfer.Link}, transfer, transfer.Owner)
+}
+
+// store a single countersigned transfer
+//
+// normally this is created by accepting an offer, but it can be
+// received complete from a peer
+func StoreTransferCountersigned(transfer *transactionrecord.BitmarkTransferCountersigned) (*TransferInfo, bool, error) {
+
+	// critical code - prevent overlapping blocks of transactions
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	return storeTransfer([]merkle.Digest{transfer.Link}, transfer, transfer.Owner)
+}
+
+// store a single burn
+//
+// this is handled as a transfer to no one, so the same fees apply
+func StoreBurn(burn *transactionrecord.BitmarkBurn) (*TransferInfo, bool, error) {
+
+	// critical code - prevent overlapping blocks of transactions
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	return storeTransfer([]merkle.Digest{burn.Link}, burn, nil)
+}
+
+// store a batch transfer
+//
+// all of the bitmarks are accepted or rejected together and a single
+// payment covers the fees for every one of them
+func StoreBatchTransfer(batch *transactionrecord.BitmarkBatchTransfer) (*TransferInfo, bool, error) {
+
+	// critical code - prevent overlapping blocks of transactions
+	globalData.Lock()
+	defer globalData.Unlock()
+
+	return storeTransfer(batch.Links, batch, batch.Owner)
+}
+
+// common code for all transfers and burn, newOwner is nil for a burn
+// ensure lock is held before calling
+func storeTransfer(links []merkle.Digest, transfer transactionrecord.Transaction, newOwner *account.Account) (*TransferInfo, bool, error) {
+
+	verifyResult, duplicate, err := verifyTransfer(links, transfer)
 	if nil != err {
 		return nil, false, err
 	}
@@ -41,17 +88,15 @@ func StoreTransfer(transfer *transactionrecord.BitmarkTransfer) (*TransferInfo,
 	payId := pay.NewPayId([][]byte{packedTransfer})
 
 	txId := verifyResult.txId
-	link := transfer.Link
-	if txId == link {
-		// reject any transaction that links to itself
-		// this should never occur, but protect agains this situuation
-		return nil, false, fault.ErrTransactionLinksToSelf
+	for _, link := range links {
+		if txId == link {
+			// reject any transaction that links to itself
+			// this should never occur, but protect agains this situuation
+			return nil, false, fault.ErrTransactionLinksToSelf
+		}
 	}
 
-	previousTransfer := verifyResult.previousTransfer
-	ownerData := verifyResult.ownerData
-
-	payments := payment.GetPayments(ownerData, previousTransfer)
+	payments := verifyResult.payments
 
 	result := &TransferInfo{
 		Id:       payId,
@@ -75,13 +120,15 @@ func StoreTransfer(transfer *transactionrecord.BitmarkTransfer) (*TransferInfo,
 
 	// create index and pending entries
 	globalData.unverified.index[txId] = payId
-	globalData.pendingTransfer[link] = txId
+	for _, link := range links {
+		globalData.pendingTransfer[link] = txId
+	}
 
 	// save transactions
 	entry := &unverifiedItem{
 		itemData: &itemData{
 			txIds:        []merkle.Digest{txId},
-			links:        []merkle.Digest{link},
+			links:        links,
 			transactions: [][]byte{packedTransfer},
 		},
 		payments: payments,
@@ -89,50 +136,51 @@ func StoreTransfer(transfer *transactionrecord.BitmarkTransfer) (*TransferInfo,
 	}
 
 	globalData.unverified.entries[payId] = entry
+	batch := storage.NewBatch()
+	journalUnverified(batch, payId, entry)
+	batch.Commit()
+
+	if nil != newOwner {
+		notify.Publish(notify.Event{
+			Kind:  notify.Pending,
+			Owner: newOwner,
+			TxId:  txId,
+		})
+	}
 
 	return result, false, nil
 }
 
 // returned data from veriftyTransfer
 type verifiedInfo struct {
-	txId             merkle.Digest
-	packedTransfer   []byte
-	previousTransfer *transactionrecord.BitmarkTransfer
-	ownerData        []byte
+	txId           merkle.Digest
+	packedTransfer []byte
+	payments       []transactionrecord.PaymentAlternative
 }
 
-// verify that a transfer is ok
+// verify that a transfer, batch transfer or burn of the linked
+// records is ok
 // ensure lock is held before calling
-func verifyTransfer(arguments *transactionrecord.BitmarkTransfer) (*verifiedInfo, bool, error) {
-
-	// find the current owner via the link
-	previousPacked := storage.Pool.Transactions.Get(arguments.Link[:])
-	if nil == previousPacked {
-		return nil, false, fault.ErrLinkToInvalidOrUnconfirmedTransaction
-	}
-
-	previousTransaction, _, err := transactionrecord.Packed(previousPacked).Unpack()
-	if nil != err {
-		return nil, false, err
-	}
+func verifyTransfer(links []merkle.Digest, transfer transactionrecord.Transaction) (*verifiedInfo, bool, error) {
 
+	// all links must belong to the same owner
 	var currentOwner *account.Account
-	var previousTransfer *transactionrecord.BitmarkTransfer
-
-	switch tx := previousTransaction.(type) {
-	case *transactionrecord.BitmarkIssue:
-		currentOwner = tx.Owner
-
-	case *transactionrecord.BitmarkTransfer:
-		currentOwner = tx.Owner
-		previousTransfer = tx
-
-	default:
-		return nil, false, fault.ErrLinkToInvalidOrUnconfirmedTransaction
+	previousPayments := make([]*transactionrecord.Payment, len(links))
+	for i, link := range links {
+		owner, previousPayment, err := linkOwner(link)
+		if nil != err {
+			return nil, false, err
+		}
+		if nil == currentOwner {
+			currentOwner = owner
+		} else if !bytes.Equal(currentOwner.Bytes(), owner.Bytes()) {
+			return nil, false, fault.ErrBatchOwnersDiffer
+		}
+		previousPayments[i] = previousPayment
 	}
 
 	// pack transfer and check signature
-	packedTransfer, err := arguments.Pack(currentOwner)
+	packedTransfer, err := transfer.Pack(currentOwner)
 	if nil != err {
 		return nil, false, err
 	}
@@ -141,16 +189,18 @@ func verifyTransfer(arguments *transactionrecord.BitmarkTransfer) (*verifiedInfo
 	txId := packedTransfer.MakeLink()
 
 	// check if this transfer was already received
-	_, okP := globalData.pendingTransfer[arguments.Link]
 	_, okU := globalData.unverified.index[txId]
 	duplicate := false
-	if okU && okP {
-		// if both then it is a possible duplicate
-		// (depends on later pay id check)
-		duplicate = true
-	} else if okU || okP {
-		// not an exact match - must be a double transfer
-		return nil, false, fault.ErrDoubleTransferAttempt
+	for _, link := range links {
+		_, okP := globalData.pendingTransfer[link]
+		if okU && okP {
+			// if both then it is a possible duplicate
+			// (depends on later pay id check)
+			duplicate = true
+		} else if okU || okP {
+			// not an exact match - must be a double transfer
+			return nil, false, fault.ErrDoubleTransferAttempt
+		}
 	}
 
 	// a single verified transfer fails the whole block
@@ -165,29 +215,106 @@ func verifyTransfer(arguments *transactionrecord.BitmarkTransfer) (*verifiedInfo
 	// log.Infof("packed transfer: %x", packedTransfer)
 	// log.Infof("id: %v", txId)
 
-	// get count for current owner record
-	// to make sure that the record has not already been transferred
-	dKey := append(currentOwner.Bytes(), arguments.Link[:]...)
-	// log.Infof("dKey: %x", dKey)
-	dCount := storage.Pool.OwnerDigest.Get(dKey)
-	if nil == dCount {
-		return nil, false, fault.ErrDoubleTransferAttempt
+	payments := make([][]transactionrecord.PaymentAlternative, len(links))
+	for i, link := range links {
+
+		// get count for current owner record
+		// to make sure that the record has not already been transferred
+		dKey := append(currentOwner.Bytes(), link[:]...)
+		// log.Infof("dKey: %x", dKey)
+		dCount := storage.Pool.OwnerDigest.Get(dKey)
+		if nil == dCount {
+			return nil, false, fault.ErrDoubleTransferAttempt
+		}
+
+		// get ownership data
+		oKey := append(currentOwner.Bytes(), dCount...)
+		// log.Infof("oKey: %x", oKey)
+		ownerData := storage.Pool.Ownership.Get(oKey)
+		if nil == ownerData {
+			return nil, false, fault.ErrDoubleTransferAttempt
+		}
+		// log.Infof("ownerData: %x", ownerData)
+
+		payments[i] = payment.GetPayments(ownerData, previousPayments[i])
+	}
+
+	result := &verifiedInfo{
+		txId:           txId,
+		packedTransfer: packedTransfer,
+		payments:       payments[0],
 	}
 
-	// get ownership data
-	oKey := append(currentOwner.Bytes(), dCount...)
-	// log.Infof("oKey: %x", oKey)
-	ownerData := storage.Pool.Ownership.Get(oKey)
-	if nil == ownerData {
-		return nil, false, fault.ErrDoubleTransferAttempt
+	// a batch is paid for as a whole
+	if len(payments) > 1 {
+		result.payments = payment.MergePayments(payments...)
 	}
-	// log.Infof("ownerData: %x", ownerData)
 
-	result := &verifiedInfo{
-		txId:             txId,
-		packedTransfer:   packedTransfer,
-		previousTransfer: previousTransfer,
-		ownerData:        ownerData,
+	// the block owners must have a currency in common
+	if 0 == len(result.payments) {
+		return nil, false, fault.ErrNoCommonCurrency
 	}
 	return result, duplicate, nil
 }
+
+// the owner of a confirmed link and the payment it requests
+// from the next transfer
+func linkOwner(link merkle.Digest) (*account.Account, *transactionrecord.Payment, error) {
+
+	previousPacked := storage.Pool.Transactions.Get(link[:])
+	if nil == previousPacked {
+		return batchLinkOwner(link)
+	}
+
+	previousTransaction, _, err := transactionrecord.Packed(previousPacked).Unpack()
+	if nil != err {
+		return nil, nil, err
+	}
+
+	switch tx := previousTransaction.(type) {
+	case *transactionrecord.BitmarkIssue:
+		return tx.Owner, nil, nil
+
+	case *transactionrecord.BitmarkTransfer:
+		return tx.Owner, tx.Payment, nil
+
+	case *transactionrecord.BitmarkTransferCountersigned:
+		return tx.Owner, tx.Payment, nil
+
+	default:
+		// includes a burn, which has no owner, and a batch
+		// transfer, whose bitmarks are linked by batch link id
+		return nil, nil, fault.ErrLinkToInvalidOrUnconfirmedTransaction
+	}
+}
+
+// the owner of one of the bitmarks moved by a confirmed batch transfer
+//
+// from storage/doc.go:
+//
+//   S ++ batch link id  - batch txId ++ previous link
+func batchLinkOwner(link merkle.Digest) (*account.Account, *transactionrecord.Payment, error) {
+
+	data := storage.Pool.BatchLinks.Get(link[:])
+	if 2*merkle.DigestLength != len(data) {
+		return nil, nil, fault.ErrLinkToInvalidOrUnconfirmedTransaction
+	}
+
+	batchPacked := storage.Pool.Transactions.Get(data[:merkle.DigestLength])
+	if nil == batchPacked {
+		return nil, nil, fault.ErrLinkToInvalidOrUnconfirmedTransaction
+	}
+
+	transaction, _, err := transactionrecord.Packed(batchPacked).Unpack()
+	if nil != err {
+		return nil, nil, err
+	}
+
+	batch, ok := transaction.(*transactionrecord.BitmarkBatchTransfer)
+	if !ok {
+		return nil, nil, fault.ErrLinkToInvalidOrUnconfirmedTransaction
+	}
+
+	// a batch carries no payment for the next transfer
+	return batch.Owner, nil, nil
+}
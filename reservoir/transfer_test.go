@@ -0,0 +1,250 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package reservoir_test
+
+import (
+	"github.com/bitmark-inc/bitmarkd/asset"
+	"github.com/bitmark-inc/bitmarkd/block"
+	"github.com/bitmark-inc/bitmarkd/currency"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/reservoir"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"testing"
+)
+
+// the payment address of the block containing a confirmed issue
+const testPaymentAddress = "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"
+
+// write an issue as if it was confirmed in block 2
+func confirmIssue(t *testing.T, owner testOwner, fingerprint string) merkle.Digest {
+	issue := &transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte(fingerprint)),
+		Owner:      owner.account,
+		Nonce:      1,
+	}
+	packedIssue := owner.signIssue(t, issue)
+	issueId := packedIssue.MakeLink()
+
+	blockOwner := transactionrecord.PackPaymentAddresses([]transactionrecord.PaymentAddress{
+		{Currency: currency.Bitcoin, Address: testPaymentAddress},
+	})
+
+	batch := storage.NewBatch()
+	batch.Put(storage.Pool.BlockOwners, []byte{0, 0, 0, 0, 0, 0, 0, 2}, blockOwner)
+	batch.Put(storage.Pool.Transactions, issueId[:], packedIssue)
+	block.CreateOwnership(batch, issueId, 2, issue.AssetIndex, owner.account)
+	batch.Commit()
+
+	return issueId
+}
+
+// a burn is paid for like a transfer and spends the link
+func TestStoreBurn(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+	defer func() {
+		reservoir.Finalise()
+		asset.Finalise()
+	}()
+
+	owner := newTestOwner(t)
+	other := newTestOwner(t)
+
+	issueId := confirmIssue(t, owner, "burn")
+
+	stolen := &transactionrecord.BitmarkBurn{
+		Link: issueId,
+	}
+	other.signBurn(t, stolen)
+	if _, _, err := reservoir.StoreBurn(stolen); fault.ErrInvalidSignature != err {
+		t.Errorf("burn by other: error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+
+	burn := &transactionrecord.BitmarkBurn{
+		Link: issueId,
+	}
+	burnId := owner.signBurn(t, burn).MakeLink()
+	stored, duplicate, err := reservoir.StoreBurn(burn)
+	if nil != err {
+		t.Fatalf("store burn error: %v", err)
+	}
+	if duplicate || burnId != stored.TxId {
+		t.Errorf("tx id: %v  duplicate: %t  expected: %v", stored.TxId, duplicate, burnId)
+	}
+
+	fee, _ := currency.Bitcoin.GetFee()
+	if 1 != len(stored.Payments) || 1 != len(stored.Payments[0]) || testPaymentAddress != stored.Payments[0][0].Address || 2*fee != stored.Payments[0][0].Amount {
+		t.Errorf("payments: %v", stored.Payments)
+	}
+
+	if _, duplicate, err := reservoir.StoreBurn(burn); nil != err || !duplicate {
+		t.Errorf("repeated burn: duplicate: %t  error: %v", duplicate, err)
+	}
+
+	transfer := &transactionrecord.BitmarkTransfer{
+		Link:  issueId,
+		Owner: other.account,
+	}
+	owner.signTransfer(t, transfer)
+	if _, _, err := reservoir.StoreTransfer(transfer); fault.ErrDoubleTransferAttempt != err {
+		t.Errorf("transfer after burn: error: %v  expected: %v", err, fault.ErrDoubleTransferAttempt)
+	}
+
+	restart(t)
+
+	if state := reservoir.TransactionStatus(burnId); reservoir.StatePending != state {
+		t.Errorf("burn after restart: state: %s  expected: %s", state, reservoir.StatePending)
+	}
+}
+
+// a batch transfer is accepted as a whole with one combined payment
+func TestStoreBatchTransfer(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+	defer func() {
+		reservoir.Finalise()
+		asset.Finalise()
+	}()
+
+	owner := newTestOwner(t)
+	other := newTestOwner(t)
+
+	links := []merkle.Digest{
+		confirmIssue(t, owner, "batch 1"),
+		confirmIssue(t, owner, "batch 2"),
+		confirmIssue(t, owner, "batch 3"),
+	}
+	otherId := confirmIssue(t, other, "batch other")
+
+	mixed := &transactionrecord.BitmarkBatchTransfer{
+		Links: append([]merkle.Digest{otherId}, links...),
+		Owner: other.account,
+	}
+	owner.signBatchTransfer(t, mixed)
+	if _, _, err := reservoir.StoreBatchTransfer(mixed); fault.ErrBatchOwnersDiffer != err {
+		t.Errorf("mixed owners: error: %v  expected: %v", err, fault.ErrBatchOwnersDiffer)
+	}
+
+	batch := &transactionrecord.BitmarkBatchTransfer{
+		Links: links,
+		Owner: other.account,
+	}
+	batchId := owner.signBatchTransfer(t, batch).MakeLink()
+	stored, duplicate, err := reservoir.StoreBatchTransfer(batch)
+	if nil != err {
+		t.Fatalf("store batch transfer error: %v", err)
+	}
+	if duplicate || batchId != stored.TxId {
+		t.Errorf("tx id: %v  duplicate: %t  expected: %v", stored.TxId, duplicate, batchId)
+	}
+
+	// every issue pays double to the same address
+	fee, _ := currency.Bitcoin.GetFee()
+	if 1 != len(stored.Payments) || 1 != len(stored.Payments[0]) || testPaymentAddress != stored.Payments[0][0].Address || 6*fee != stored.Payments[0][0].Amount {
+		t.Errorf("payments: %v", stored.Payments)
+	}
+
+	if _, duplicate, err := reservoir.StoreBatchTransfer(batch); nil != err || !duplicate {
+		t.Errorf("repeated batch transfer: duplicate: %t  error: %v", duplicate, err)
+	}
+
+	transfer := &transactionrecord.BitmarkTransfer{
+		Link:  links[1],
+		Owner: owner.account,
+	}
+	owner.signTransfer(t, transfer)
+	if _, _, err := reservoir.StoreTransfer(transfer); fault.ErrDoubleTransferAttempt != err {
+		t.Errorf("transfer after batch transfer: error: %v  expected: %v", err, fault.ErrDoubleTransferAttempt)
+	}
+
+	restart(t)
+
+	if state := reservoir.TransactionStatus(batchId); reservoir.StatePending != state {
+		t.Errorf("batch transfer after restart: state: %s  expected: %s", state, reservoir.StatePending)
+	}
+	if _, _, err := reservoir.StoreTransfer(transfer); fault.ErrDoubleTransferAttempt != err {
+		t.Errorf("transfer after restart: error: %v  expected: %v", err, fault.ErrDoubleTransferAttempt)
+	}
+}
+
+// a bitmark moved by a confirmed batch transfer continues from its
+// batch link id
+func TestTransferBatchLink(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := asset.Initialise(); nil != err {
+		t.Fatalf("asset initialise error: %v", err)
+	}
+	if err := reservoir.Initialise(); nil != err {
+		t.Fatalf("reservoir initialise error: %v", err)
+	}
+	defer func() {
+		reservoir.Finalise()
+		asset.Finalise()
+	}()
+
+	owner := newTestOwner(t)
+	other := newTestOwner(t)
+
+	issueId := confirmIssue(t, owner, "batch link")
+	batch := &transactionrecord.BitmarkBatchTransfer{
+		Links: []merkle.Digest{issueId},
+		Owner: other.account,
+	}
+	packedBatch := owner.signBatchTransfer(t, batch)
+	batchId := packedBatch.MakeLink()
+	batchLinkId := transactionrecord.BatchLinkId(batchId, issueId)
+
+	// confirm the batch in block 2
+	b := storage.NewBatch()
+	b.Put(storage.Pool.Transactions, batchId[:], packedBatch)
+	b.Put(storage.Pool.BatchLinks, batchLinkId[:], append(batchId[:], issueId[:]...))
+	block.TransferOwnership(b, issueId, batchLinkId, 2, owner.account, other.account)
+	b.Commit()
+
+	for _, link := range []merkle.Digest{issueId, batchId} {
+		transfer := &transactionrecord.BitmarkTransfer{
+			Link:  link,
+			Owner: owner.account,
+		}
+		other.signTransfer(t, transfer)
+		if _, _, err := reservoir.StoreTransfer(transfer); nil == err {
+			t.Errorf("transfer of: %v  was accepted", link)
+		}
+	}
+
+	transfer := &transactionrecord.BitmarkTransfer{
+		Link:  batchLinkId,
+		Owner: owner.account,
+	}
+	other.signTransfer(t, transfer)
+	stored, _, err := reservoir.StoreTransfer(transfer)
+	if nil != err {
+		t.Fatalf("transfer of batch link error: %v", err)
+	}
+
+	// issue and batch are in the same block so one payment
+	fee, _ := currency.Bitcoin.GetFee()
+	if 1 != len(stored.Payments) || 1 != len(stored.Payments[0]) || testPaymentAddress != stored.Payments[0][0].Address || 2*fee != stored.Payments[0][0].Amount {
+		t.Errorf("payments: %v", stored.Payments)
+	}
+}
@@ -5,7 +5,10 @@
 package rpc
 
 import (
+	"encoding/hex"
+	"github.com/bitmark-inc/bitmarkd/account"
 	"github.com/bitmark-inc/bitmarkd/asset"
+	"github.com/bitmark-inc/bitmarkd/block"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/mode"
 	"github.com/bitmark-inc/bitmarkd/storage"
@@ -42,8 +45,14 @@ func assetRegister(assets []*transactionrecord.AssetData) ([]AssetStatus, []byte
 
 	// pack each transaction
 	packed := []byte{}
+	number := block.GetHeight() + 1
 	for i, argument := range assets {
 
+		err := block.CheckAccounts(argument, number)
+		if nil != err {
+			return nil, nil, err
+		}
+
 		index, packedAsset, err := asset.Cache(argument)
 		if nil != err {
 			return nil, nil, err
@@ -92,6 +101,9 @@ func (assets *Assets) Get(arguments *AssetGetArguments, reply *AssetGetReply) er
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
 
 	log.Infof("Assets.Get: %v", arguments)
 
@@ -162,3 +174,121 @@ loop:
 
 // 	return nil
 // }
+
+// Assets list by registrant
+// -------------------------
+
+type AssetsListByRegistrantArguments struct {
+	Registrant *account.Account `json:"registrant"` // base58
+	Cursor     string           `json:"cursor"`     // empty to start, otherwise the previous reply's cursor
+	Count      int              `json:"count"`      // number of assets
+}
+
+type AssetsListReply struct {
+	Assets []AssetRecord `json:"assets"`
+	Cursor string        `json:"cursor,omitempty"` // omitted when there are no more assets
+}
+
+func (assets *Assets) ListByRegistrant(arguments *AssetsListByRegistrantArguments, reply *AssetsListReply) error {
+
+	log := assets.log
+	log.Infof("Assets.ListByRegistrant: %v", arguments)
+
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
+
+	if nil == arguments.Registrant {
+		return fault.ErrInvalidOwnerOrRegistrant
+	}
+	if arguments.Count <= 0 || arguments.Count > maximumAssets {
+		return fault.ErrInvalidCount
+	}
+	cursor, err := decodeCursor(arguments.Cursor)
+	if nil != err {
+		return err
+	}
+
+	indexes, next, err := block.ListAssetsFor(arguments.Registrant, cursor, arguments.Count)
+	if nil != err {
+		return err
+	}
+	return assetList(indexes, next, reply)
+}
+
+// Assets search
+// -------------
+
+// exactly one of name or metadata key must be given
+type AssetsSearchArguments struct {
+	Name        string `json:"name"`        // prefix of the asset name
+	MetadataKey string `json:"metadataKey"` // a key present in the metadata
+	Cursor      string `json:"cursor"`      // empty to start, otherwise the previous reply's cursor
+	Count       int    `json:"count"`       // number of assets
+}
+
+func (assets *Assets) Search(arguments *AssetsSearchArguments, reply *AssetsListReply) error {
+
+	log := assets.log
+	log.Infof("Assets.Search: %v", arguments)
+
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
+
+	if arguments.Count <= 0 || arguments.Count > maximumAssets {
+		return fault.ErrInvalidCount
+	}
+	cursor, err := decodeCursor(arguments.Cursor)
+	if nil != err {
+		return err
+	}
+
+	indexes, next, err := block.SearchAssets(arguments.Name, arguments.MetadataKey, cursor, arguments.Count)
+	if nil != err {
+		return err
+	}
+	return assetList(indexes, next, reply)
+}
+
+// paging cursors are passed as hex
+func decodeCursor(cursor string) ([]byte, error) {
+	if "" == cursor {
+		return nil, nil
+	}
+	b, err := hex.DecodeString(cursor)
+	if nil != err {
+		return nil, fault.ErrInvalidCursor
+	}
+	return b, nil
+}
+
+// fetch the confirmed assets for a list reply
+func assetList(indexes []transactionrecord.AssetIndex, next []byte, reply *AssetsListReply) error {
+
+	a := make([]AssetRecord, len(indexes))
+	for i, assetIndex := range indexes {
+		packedAsset := storage.Pool.Assets.Get(assetIndex[:])
+		if nil == packedAsset {
+			return fault.ErrAssetNotFound
+		}
+		assetTx, _, err := transactionrecord.Packed(packedAsset).Unpack()
+		if nil != err {
+			return err
+		}
+
+		record, _ := transactionrecord.RecordName(assetTx)
+		a[i] = AssetRecord{
+			Record:     record,
+			Confirmed:  true,
+			AssetIndex: assetIndex,
+			Data:       assetTx,
+		}
+	}
+
+	reply.Assets = a
+	if nil != next {
+		reply.Cursor = hex.EncodeToString(next)
+	}
+	return nil
+}
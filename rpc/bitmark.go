@@ -5,11 +5,15 @@
 package rpc
 
 import (
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/asset"
+	"github.com/bitmark-inc/bitmarkd/block"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/merkle"
 	"github.com/bitmark-inc/bitmarkd/messagebus"
 	"github.com/bitmark-inc/bitmarkd/mode"
 	"github.com/bitmark-inc/bitmarkd/pay"
+	"github.com/bitmark-inc/bitmarkd/peer"
 	"github.com/bitmark-inc/bitmarkd/reservoir"
 	"github.com/bitmark-inc/bitmarkd/storage"
 	"github.com/bitmark-inc/bitmarkd/transactionrecord"
@@ -26,11 +30,12 @@ type Bitmark struct {
 // Bitmark transfer
 // ----------------
 
+// payments is the first of the payment alternatives, kept for older clients
 type BitmarkTransferReply struct {
-	TxId     merkle.Digest                `json:"txId"`
-	PayId    pay.PayId                    `json:"payId"`
-	Payments []*transactionrecord.Payment `json:"payments"`
-	//PaymentAlternatives []block.MinerAddress `json:"paymentAlternatives"`// ***** FIX THIS: where to get addresses?
+	TxId                merkle.Digest                          `json:"txId"`
+	PayId               pay.PayId                              `json:"payId"`
+	Payments            transactionrecord.PaymentAlternative   `json:"payments"`
+	PaymentAlternatives []transactionrecord.PaymentAlternative `json:"paymentAlternatives"`
 }
 
 func (bitmark *Bitmark) Transfer(arguments *transactionrecord.BitmarkTransfer, reply *BitmarkTransferReply) error {
@@ -42,6 +47,14 @@ func (bitmark *Bitmark) Transfer(arguments *transactionrecord.BitmarkTransfer, r
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
+
+	err := block.CheckAccounts(arguments, block.GetHeight()+1)
+	if nil != err {
+		return err
+	}
 
 	stored, duplicate, err := reservoir.StoreTransfer(arguments)
 	//txId, packedTransfer, previousTransfer, ownerData, err := block.VerifyTransfer(arguments)
@@ -57,7 +70,8 @@ func (bitmark *Bitmark) Transfer(arguments *transactionrecord.BitmarkTransfer, r
 	log.Infof("id: %v", txId)
 	reply.TxId = txId
 	reply.PayId = payId
-	reply.Payments = stored.Payments
+	reply.Payments = stored.Payments[0]
+	reply.PaymentAlternatives = stored.Payments
 
 	// announce transaction block to other peers
 	if !duplicate {
@@ -67,6 +81,86 @@ func (bitmark *Bitmark) Transfer(arguments *transactionrecord.BitmarkTransfer, r
 	return nil
 }
 
+// Bitmark burn
+// ------------
+
+// a burn is paid for in the same way as a transfer
+func (bitmark *Bitmark) Burn(arguments *transactionrecord.BitmarkBurn, reply *BitmarkTransferReply) error {
+
+	log := bitmark.log
+
+	log.Infof("Bitmark.Burn: %v", arguments)
+
+	if !mode.Is(mode.Normal) {
+		return fault.ErrNotAvailableDuringSynchronise
+	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
+
+	stored, duplicate, err := reservoir.StoreBurn(arguments)
+	if nil != err {
+		return err
+	}
+
+	log.Infof("id: %v", stored.TxId)
+	reply.TxId = stored.TxId
+	reply.PayId = stored.Id
+	reply.Payments = stored.Payments[0]
+	reply.PaymentAlternatives = stored.Payments
+
+	// announce transaction block to other peers
+	if !duplicate {
+		messagebus.Bus.Broadcast.Send("transfer", stored.Packed)
+	}
+
+	return nil
+}
+
+// Bitmark batch transfer
+// ----------------------
+
+// move many bitmarks of the same owner with one record and one payment
+//
+// after confirmation each bitmark is transferred onwards by linking to
+// its batch link id, see: transactionrecord.BatchLinkId
+func (bitmark *Bitmark) BatchTransfer(arguments *transactionrecord.BitmarkBatchTransfer, reply *BitmarkTransferReply) error {
+
+	log := bitmark.log
+
+	log.Infof("Bitmark.BatchTransfer: %v", arguments)
+
+	if !mode.Is(mode.Normal) {
+		return fault.ErrNotAvailableDuringSynchronise
+	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
+
+	err := block.CheckAccounts(arguments, block.GetHeight()+1)
+	if nil != err {
+		return err
+	}
+
+	stored, duplicate, err := reservoir.StoreBatchTransfer(arguments)
+	if nil != err {
+		return err
+	}
+
+	log.Infof("id: %v", stored.TxId)
+	reply.TxId = stored.TxId
+	reply.PayId = stored.Id
+	reply.Payments = stored.Payments[0]
+	reply.PaymentAlternatives = stored.Payments
+
+	// announce transaction block to other peers
+	if !duplicate {
+		messagebus.Bus.Broadcast.Send("transfer", stored.Packed)
+	}
+
+	return nil
+}
+
 // Trace the history of a property
 // -------------------------------
 
@@ -105,9 +199,22 @@ func (bitmark *Bitmark) Provenance(arguments *ProvenanceArguments, reply *Proven
 loop:
 	for i := 0; i < count; i += 1 {
 
+		txId := id
+		previous := merkle.Digest{}
 		packed := storage.Pool.Transactions.Get(id[:])
 		if nil == packed {
-			break loop
+
+			// one of the bitmarks moved by a batch transfer
+			batchTxId, link, ok := block.BatchLink(id)
+			if !ok {
+				break loop
+			}
+			txId = batchTxId
+			previous = link
+			packed = storage.Pool.Transactions.Get(txId[:])
+			if nil == packed {
+				break loop
+			}
 		}
 
 		transaction, _, err := transactionrecord.Packed(packed).Unpack()
@@ -119,7 +226,7 @@ loop:
 		h := ProvenanceRecord{
 			Record:     record,
 			IsOwner:    false,
-			TxId:       id,
+			TxId:       txId,
 			AssetIndex: nil,
 			Data:       transaction,
 		}
@@ -169,6 +276,39 @@ loop:
 			provenance = append(provenance, h)
 			id = tx.Link
 
+		case *transactionrecord.BitmarkTransferCountersigned:
+
+			if 0 == i {
+				dKey := append(tx.Owner.Bytes(), id[:]...)
+				if nil != storage.Pool.OwnerDigest.Get(dKey) {
+					h.IsOwner = true
+				}
+			}
+
+			provenance = append(provenance, h)
+			id = tx.Link
+
+		case *transactionrecord.BitmarkBurn:
+			provenance = append(provenance, h)
+			id = tx.Link
+
+		case *transactionrecord.BitmarkBatchTransfer:
+
+			// a batch is only followed from one of its batch link ids
+			if txId == id {
+				break loop
+			}
+
+			if 0 == i {
+				dKey := append(tx.Owner.Bytes(), id[:]...)
+				if nil != storage.Pool.OwnerDigest.Get(dKey) {
+					h.IsOwner = true
+				}
+			}
+
+			provenance = append(provenance, h)
+			id = previous
+
 		default:
 			break loop
 		}
@@ -178,3 +318,283 @@ loop:
 
 	return nil
 }
+
+// Full history of a property
+// --------------------------
+
+const (
+	maximumHistoryCount = 100
+)
+
+type HistoryArguments struct {
+	TxId  merkle.Digest `json:"txId"`  // latest transaction to start from
+	Count int           `json:"count"` // maximum number of transactions to return
+}
+
+// one step in the history, can be any of the transaction records
+type HistoryRecord struct {
+	Record      string                     `json:"record"`
+	Status      string                     `json:"status"`
+	IsOwner     bool                       `json:"isOwner"`
+	TxId        interface{}                `json:"txId,omitempty"`
+	AssetIndex  interface{}                `json:"index,omitempty"`
+	BlockNumber uint64                     `json:"blockNumber,string,omitempty"` // only when confirmed
+	Timestamp   uint64                     `json:"timestamp,string,omitempty"`   // of the block
+	Payment     *transactionrecord.Payment `json:"payment,omitempty"`
+	Data        interface{}                `json:"data"`
+}
+
+type HistoryReply struct {
+	Data []HistoryRecord `json:"data"`
+	Next interface{}     `json:"next,omitempty"` // tx id for the next call, omitted when the issue was reached
+}
+
+// trace back from a transaction to the issue and its asset
+//
+// each record includes the block it was confirmed in or its status in
+// the reservoir; any missing link is reported as an error
+func (bitmark *Bitmark) History(arguments *HistoryArguments, reply *HistoryReply) error {
+	log := bitmark.log
+
+	log.Infof("Bitmark.History: %v", arguments)
+
+	if arguments.Count <= 0 || arguments.Count > maximumHistoryCount {
+		return fault.ErrInvalidCount
+	}
+
+	id := arguments.TxId
+	history := make([]HistoryRecord, 0, arguments.Count+1)
+
+	for i := 0; i < arguments.Count; i += 1 {
+
+		h, transaction, err := historyFor(id)
+		if nil != err {
+			log.Errorf("Bitmark.History: tx id: %v  error: %v", id, err)
+			return err
+		}
+
+		switch tx := transaction.(type) {
+
+		case *transactionrecord.BitmarkIssue:
+			h.IsOwner = isCurrentOwner(tx.Owner, id, h.BlockNumber)
+			history = append(history, h)
+
+			a, err := historyForAsset(tx.AssetIndex)
+			if nil != err {
+				log.Errorf("Bitmark.History: asset: %v  error: %v", tx.AssetIndex, err)
+				return err
+			}
+			reply.Data = append(history, a)
+			return nil
+
+		case *transactionrecord.BitmarkTransfer:
+			h.IsOwner = isCurrentOwner(tx.Owner, id, h.BlockNumber)
+			h.Payment = tx.Payment
+			history = append(history, h)
+			id = tx.Link
+
+		case *transactionrecord.BitmarkTransferCountersigned:
+			h.IsOwner = isCurrentOwner(tx.Owner, id, h.BlockNumber)
+			h.Payment = tx.Payment
+			history = append(history, h)
+			id = tx.Link
+
+		case *transactionrecord.BitmarkBurn:
+			history = append(history, h)
+			id = tx.Link
+
+		case *transactionrecord.BitmarkBatchTransfer:
+
+			// a batch is only followed from one of its batch link ids
+			_, previous, ok := block.BatchLink(id)
+			if !ok {
+				return fault.ErrTransactionIsNotAnIssueOrATransfer
+			}
+			h.IsOwner = isCurrentOwner(tx.Owner, id, h.BlockNumber)
+			history = append(history, h)
+			id = previous
+
+		default:
+			return fault.ErrTransactionIsNotAnIssueOrATransfer
+		}
+	}
+
+	reply.Data = history
+	reply.Next = id
+
+	return nil
+}
+
+// Current owner of a bitmark
+// --------------------------
+
+type BitmarkOwnerArguments struct {
+	TxId merkle.Digest `json:"txId"`
+}
+
+type BitmarkOwnerReply struct {
+	TxId        merkle.Digest    `json:"txId"`
+	Owner       *account.Account `json:"owner"` // null for a burn
+	BlockNumber uint64           `json:"blockNumber,string"`
+	IsOwner     bool             `json:"isOwner"` // the transaction has not been transferred
+}
+
+// the owner set by a confirmed issue or transfer
+//
+// in light mode the transaction is fetched from the full peers and
+// checked against the local headers; it is only reported as the
+// current owner if several peers agree
+func (bitmark *Bitmark) Owner(arguments *BitmarkOwnerArguments, reply *BitmarkOwnerReply) error {
+
+	bitmark.log.Infof("Bitmark.Owner: %v", arguments)
+
+	txId := arguments.TxId
+
+	blockNumber := uint64(0)
+	packed := transactionrecord.Packed(nil)
+	isOwner := false
+	isBatchLink := false
+	if mode.IsLight() {
+		proof, current, err := peer.TransactionProof(txId)
+		if nil != err {
+			return err
+		}
+		blockNumber = proof.BlockNumber
+		packed = proof.Packed
+		isOwner = current
+		isBatchLink = packed.MakeLink() != txId
+	} else {
+
+		// one of the bitmarks moved by a batch transfer
+		lookupId := txId
+		if batchTxId, _, ok := block.BatchLink(txId); ok {
+			lookupId = batchTxId
+			isBatchLink = true
+		}
+
+		n, p, err := confirmedTransaction(lookupId)
+		if nil != err {
+			return err
+		}
+		blockNumber = n
+		packed = p
+	}
+
+	transaction, _, err := packed.Unpack()
+	if nil != err {
+		return err
+	}
+
+	switch tx := transaction.(type) {
+	case *transactionrecord.BitmarkIssue:
+		reply.Owner = tx.Owner
+	case *transactionrecord.BitmarkTransfer:
+		reply.Owner = tx.Owner
+	case *transactionrecord.BitmarkTransferCountersigned:
+		reply.Owner = tx.Owner
+	case *transactionrecord.BitmarkBurn:
+		// no owner
+	case *transactionrecord.BitmarkBatchTransfer:
+		if !isBatchLink {
+			// a batch has no single owner record, only its batch link ids do
+			return fault.ErrTransactionIsNotAnIssueOrATransfer
+		}
+		reply.Owner = tx.Owner
+	default:
+		return fault.ErrTransactionIsNotAnIssueOrATransfer
+	}
+
+	if !mode.IsLight() && nil != reply.Owner {
+		isOwner = isCurrentOwner(reply.Owner, txId, blockNumber)
+	}
+
+	reply.TxId = txId
+	reply.BlockNumber = blockNumber
+	reply.IsOwner = isOwner
+
+	return nil
+}
+
+// fetch a confirmed or pending transaction with its block details
+func historyFor(txId merkle.Digest) (HistoryRecord, interface{}, error) {
+
+	h := HistoryRecord{
+		TxId:   txId,
+		Status: reservoir.StateConfirmed.String(),
+	}
+
+	packed := transactionrecord.Packed(storage.Pool.Transactions.Get(txId[:]))
+	if nil == packed {
+
+		// one of the bitmarks moved by a batch transfer
+		if batchTxId, _, ok := block.BatchLink(txId); ok {
+			txId = batchTxId
+			h.TxId = txId
+			packed = storage.Pool.Transactions.Get(txId[:])
+		}
+	}
+	if nil != packed {
+		n, _, err := block.LocateTransaction(txId)
+		if nil != err {
+			return h, nil, err
+		}
+		header, err := block.HeaderForBlock(n)
+		if nil != err {
+			return h, nil, err
+		}
+		h.BlockNumber = n
+		h.Timestamp = header.Timestamp
+	} else {
+		pending, state := reservoir.PendingTransaction(txId)
+		if nil == pending {
+			return h, nil, fault.ErrTransactionNotFound
+		}
+		packed = pending
+		h.Status = state.String()
+	}
+
+	transaction, _, err := packed.Unpack()
+	if nil != err {
+		return h, nil, err
+	}
+	h.Record, _ = transactionrecord.RecordName(transaction)
+	h.Data = transaction
+
+	return h, transaction, nil
+}
+
+// fetch a confirmed or pending asset
+func historyForAsset(assetIndex transactionrecord.AssetIndex) (HistoryRecord, error) {
+
+	h := HistoryRecord{
+		AssetIndex: assetIndex,
+		Status:     reservoir.StateConfirmed.String(),
+	}
+
+	packed := transactionrecord.Packed(storage.Pool.Assets.Get(assetIndex[:]))
+	if nil == packed {
+		packed = asset.Get(assetIndex)
+		if nil == packed {
+			return h, fault.ErrAssetNotFound
+		}
+		h.Status = reservoir.StatePending.String()
+	}
+
+	transaction, _, err := packed.Unpack()
+	if nil != err {
+		return h, err
+	}
+	h.Record, _ = transactionrecord.RecordName(transaction)
+	h.Data = transaction
+
+	return h, nil
+}
+
+// check if a confirmed transaction holds the current ownership
+func isCurrentOwner(owner *account.Account, txId merkle.Digest, blockNumber uint64) bool {
+	if 0 == blockNumber {
+		return false
+	}
+	dKey := append(owner.Bytes(), txId[:]...)
+	return nil != storage.Pool.OwnerDigest.Get(dKey)
+}
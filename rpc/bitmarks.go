@@ -6,6 +6,7 @@ package rpc
 
 import (
 	"encoding/hex"
+	"github.com/bitmark-inc/bitmarkd/block"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/merkle"
 	"github.com/bitmark-inc/bitmarkd/messagebus"
@@ -53,13 +54,12 @@ type CreateArguments struct {
 }
 
 type CreateReply struct {
-	Assets     []AssetStatus      `json:"assets"`
-	Issues     []IssueStatus      `json:"issues"`
-	PayId      pay.PayId          `json:"payId"`
-	PayNonce   reservoir.PayNonce `json:"payNonce"`
-	Difficulty string             `json:"difficulty"`
-	//PaymentAlternatives []block.MinerAddress `json:"paymentAlternatives"`// ***** FIX THIS: where to get addresses?
-
+	Assets              []AssetStatus                          `json:"assets"`
+	Issues              []IssueStatus                          `json:"issues"`
+	PayId               pay.PayId                              `json:"payId"`
+	PayNonce            reservoir.PayNonce                     `json:"payNonce"`
+	Difficulty          string                                 `json:"difficulty"`
+	PaymentAlternatives []transactionrecord.PaymentAlternative `json:"paymentAlternatives,omitempty"`
 }
 
 func (bitmarks *Bitmarks) Create(arguments *CreateArguments, reply *CreateReply) error {
@@ -77,6 +77,9 @@ func (bitmarks *Bitmarks) Create(arguments *CreateArguments, reply *CreateReply)
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
 
 	log.Infof("Bitmarks.Create: %v", arguments)
 
@@ -93,6 +96,13 @@ func (bitmarks *Bitmarks) Create(arguments *CreateArguments, reply *CreateReply)
 	var stored *reservoir.IssueInfo
 	duplicate := false
 	if issueCount > 0 {
+		number := block.GetHeight() + 1
+		for _, issue := range arguments.Issues {
+			err := block.CheckAccounts(issue, number)
+			if nil != err {
+				return err
+			}
+		}
 		stored, duplicate, err = reservoir.StoreIssues(arguments.Issues, false)
 		if nil != err {
 			return err
@@ -120,6 +130,7 @@ func (bitmarks *Bitmarks) Create(arguments *CreateArguments, reply *CreateReply)
 		result.PayId = stored.Id
 		result.PayNonce = stored.Nonce
 		result.Difficulty = stored.Difficulty.GoString()
+		result.PaymentAlternatives = stored.Payments
 
 		// announce transaction block to other peers
 		if !duplicate {
@@ -150,6 +161,9 @@ func (bitmarks *Bitmarks) Proof(arguments *ProofArguments, reply *ProofReply) er
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
 
 	// arbitrary byte size limit
 	size := hex.DecodedLen(len(arguments.Nonce))
@@ -204,6 +218,9 @@ func (bitmarks *Bitmarks) Pay(arguments *PayArguments, reply *PayReply) error {
 	if !mode.Is(mode.Normal) {
 		return fault.ErrNotAvailableDuringSynchronise
 	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
 
 	// arbitrary byte size limit
 	size := hex.DecodedLen(len(arguments.Receipt))
@@ -0,0 +1,168 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"github.com/bitmark-inc/bitmarkd/block"
+	"github.com/bitmark-inc/bitmarkd/blockdigest"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/genesis"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"github.com/bitmark-inc/logger"
+)
+
+// Blocks type
+// -----------
+
+type Blocks struct {
+	log *logger.L
+}
+
+const (
+	maximumBlocks = 20
+)
+
+// an unpacked block
+type BlockRecord struct {
+	Number       uint64              `json:"number,string"`
+	Digest       blockdigest.Digest  `json:"digest"`
+	Header       *blockrecord.Header `json:"header"`
+	Transactions []BlockTransaction  `json:"transactions"`
+}
+
+// can be any of the transaction records
+type BlockTransaction struct {
+	Record string        `json:"record"`
+	TxId   merkle.Digest `json:"txId"`
+	Data   interface{}   `json:"data"`
+}
+
+// Blocks get
+// ----------
+
+// select a block by digest, or by number if no digest is given
+type BlocksGetArguments struct {
+	Number uint64              `json:"number,string"`
+	Digest *blockdigest.Digest `json:"digest"`
+}
+
+type BlocksGetReply struct {
+	Block BlockRecord `json:"block"`
+}
+
+func (blocks *Blocks) Get(arguments *BlocksGetArguments, reply *BlocksGetReply) error {
+
+	log := blocks.log
+	log.Infof("Blocks.Get: %v", arguments)
+
+	number := arguments.Number
+	if nil != arguments.Digest {
+		n, err := block.NumberForDigest(*arguments.Digest)
+		if nil != err {
+			return err
+		}
+		number = n
+	}
+
+	record, err := blockRecord(number)
+	if nil != err {
+		return err
+	}
+	reply.Block = record
+	return nil
+}
+
+// Blocks range
+// ------------
+
+type BlocksRangeArguments struct {
+	Start uint64 `json:"start,string"` // first block number
+	Count int    `json:"count"`        // number of blocks
+}
+
+type BlocksRangeReply struct {
+	Next   uint64        `json:"next,string"` // start value for the next call
+	Blocks []BlockRecord `json:"blocks"`
+}
+
+func (blocks *Blocks) Range(arguments *BlocksRangeArguments, reply *BlocksRangeReply) error {
+
+	log := blocks.log
+	log.Infof("Blocks.Range: %v", arguments)
+
+	if arguments.Count <= 0 || arguments.Count > maximumBlocks {
+		return fault.ErrInvalidCount
+	}
+
+	start := arguments.Start
+	if start < genesis.BlockNumber {
+		start = genesis.BlockNumber
+	}
+
+	height := block.GetHeight()
+	records := make([]BlockRecord, 0, arguments.Count)
+	n := start
+	for ; n <= height && len(records) < arguments.Count; n += 1 {
+		record, err := blockRecord(n)
+		if nil != err {
+			return err
+		}
+		records = append(records, record)
+	}
+
+	reply.Next = n
+	reply.Blocks = records
+	return nil
+}
+
+// Blocks latest
+// -------------
+
+type BlocksLatestArguments struct {
+}
+
+type BlocksLatestReply struct {
+	Block BlockRecord `json:"block"`
+}
+
+func (blocks *Blocks) Latest(arguments *BlocksLatestArguments, reply *BlocksLatestReply) error {
+
+	blocks.log.Info("Blocks.Latest")
+
+	record, err := blockRecord(block.GetHeight())
+	if nil != err {
+		return err
+	}
+	reply.Block = record
+	return nil
+}
+
+// fetch a block and decode all of its transactions
+func blockRecord(number uint64) (BlockRecord, error) {
+
+	header, digest, txs, err := block.GetBlock(number)
+	if nil != err {
+		return BlockRecord{}, err
+	}
+
+	transactions := make([]BlockTransaction, len(txs))
+	for i, tx := range txs {
+		record, _ := transactionrecord.RecordName(tx.Unpacked)
+		transactions[i] = BlockTransaction{
+			Record: record,
+			TxId:   tx.TxId,
+			Data:   tx.Unpacked,
+		}
+	}
+
+	return BlockRecord{
+		Number:       number,
+		Digest:       digest,
+		Header:       header,
+		Transactions: transactions,
+	}, nil
+}
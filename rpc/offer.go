@@ -0,0 +1,159 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/block"
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/messagebus"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/reservoir"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
+	"github.com/bitmark-inc/logger"
+	"time"
+)
+
+// Offer
+// -----
+
+// countersigned transfers that are waiting for the new owner
+type Offer struct {
+	log *logger.L
+}
+
+// Offer create
+// ------------
+
+type OfferCreateReply struct {
+	Id      merkle.Digest `json:"id"`
+	Expires time.Time     `json:"expires"`
+}
+
+// store a countersigned transfer that only has the current owner's signature
+func (offer *Offer) Create(arguments *transactionrecord.BitmarkTransferCountersigned, reply *OfferCreateReply) error {
+
+	log := offer.log
+
+	log.Infof("Offer.Create: %v", arguments)
+
+	if err := offerAvailable(); nil != err {
+		return err
+	}
+
+	err := block.CheckAccounts(arguments, block.GetHeight()+1)
+	if nil != err {
+		return err
+	}
+
+	stored, _, err := reservoir.StoreOffer(arguments)
+	if nil != err {
+		return err
+	}
+
+	reply.Id = stored.Id
+	reply.Expires = stored.Expires
+
+	return nil
+}
+
+// Offer list
+// ----------
+
+type OfferListArguments struct {
+	Owner *account.Account `json:"owner"` // base58
+}
+
+type OfferListReply struct {
+	Offers []reservoir.Offer `json:"offers"`
+}
+
+// offers made by or to an account
+func (offer *Offer) List(arguments *OfferListArguments, reply *OfferListReply) error {
+
+	offer.log.Infof("Offer.List: %v", arguments)
+
+	if nil == arguments || nil == arguments.Owner {
+		return fault.ErrInvalidOwnerOrRegistrant
+	}
+
+	reply.Offers = reservoir.ListOffers(arguments.Owner)
+
+	return nil
+}
+
+// Offer accept
+// ------------
+
+type OfferAcceptArguments struct {
+	Id               merkle.Digest     `json:"id"`
+	Countersignature account.Signature `json:"countersignature"` // hex: by the new owner
+}
+
+// countersign an offer, the completed transfer is then paid for in
+// the same way as a normal transfer
+func (offer *Offer) Accept(arguments *OfferAcceptArguments, reply *BitmarkTransferReply) error {
+
+	log := offer.log
+
+	log.Infof("Offer.Accept: %v", arguments)
+
+	if err := offerAvailable(); nil != err {
+		return err
+	}
+
+	stored, duplicate, err := reservoir.AcceptOffer(arguments.Id, arguments.Countersignature)
+	if nil != err {
+		return err
+	}
+
+	log.Infof("id: %v", stored.TxId)
+	reply.TxId = stored.TxId
+	reply.PayId = stored.Id
+	reply.Payments = stored.Payments[0]
+	reply.PaymentAlternatives = stored.Payments
+
+	// announce transaction block to other peers
+	if !duplicate {
+		messagebus.Bus.Broadcast.Send("transfer", stored.Packed)
+	}
+
+	return nil
+}
+
+// Offer reject
+// ------------
+
+type OfferRejectArguments struct {
+	Id        merkle.Digest     `json:"id"`
+	Signature account.Signature `json:"signature"` // hex: of the id by either party
+}
+
+type OfferRejectReply struct {
+}
+
+// remove an offer, this is also used by the current owner to withdraw it
+func (offer *Offer) Reject(arguments *OfferRejectArguments, reply *OfferRejectReply) error {
+
+	offer.log.Infof("Offer.Reject: %v", arguments)
+
+	if err := offerAvailable(); nil != err {
+		return err
+	}
+
+	return reservoir.RejectOffer(arguments.Id, arguments.Signature)
+}
+
+// offers are held in the reservoir of a full node
+func offerAvailable() error {
+	if !mode.Is(mode.Normal) {
+		return fault.ErrNotAvailableDuringSynchronise
+	}
+	if mode.IsLight() {
+		return fault.ErrNotAvailableInLightMode
+	}
+	return nil
+}
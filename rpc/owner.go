@@ -9,16 +9,19 @@ import (
 	"github.com/bitmark-inc/bitmarkd/block"
 	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/notify"
 	"github.com/bitmark-inc/bitmarkd/storage"
 	"github.com/bitmark-inc/bitmarkd/transactionrecord"
 	"github.com/bitmark-inc/logger"
+	"time"
 )
 
 // Owner
 // -------
 
 type Owner struct {
-	log *logger.L
+	log          *logger.L
+	subscription *notify.Subscription // events for this connection
 }
 
 // Owner bitmarks
@@ -146,3 +149,76 @@ func (owner *Owner) Bitmarks(arguments *OwnerBitmarksArguments, reply *OwnerBitm
 	}
 	return nil
 }
+
+// Owner subscribe
+// ---------------
+
+type OwnerSubscribeArguments struct {
+	Owners []*account.Account `json:"owners"` // base58
+}
+
+type OwnerSubscribeReply struct {
+	Count int `json:"count"` // total accounts watched by this connection
+}
+
+// watch accounts for ownership changes
+//
+// the events are collected by Owner.Events on the same connection and
+// the watch ends when the connection is closed
+func (owner *Owner) Subscribe(arguments *OwnerSubscribeArguments, reply *OwnerSubscribeReply) error {
+	log := owner.log
+	log.Infof("Owner.Subscribe: %v", arguments)
+
+	if 0 == len(arguments.Owners) || len(arguments.Owners) > notify.MaximumOwners {
+		return fault.ErrInvalidCount
+	}
+
+	for _, account := range arguments.Owners {
+		if nil == account {
+			return fault.ErrInvalidOwnerOrRegistrant
+		}
+		err := owner.subscription.Watch(account)
+		if nil != err {
+			return err
+		}
+	}
+
+	reply.Count = owner.subscription.Count()
+	return nil
+}
+
+// Owner events
+// ------------
+
+const (
+	maximumEventWait = 60 * time.Second
+)
+
+type OwnerEventsArguments struct {
+	Count   int `json:"count"`   // maximum number of events
+	Timeout int `json:"timeout"` // seconds to wait if no events are queued
+}
+
+type OwnerEventsReply struct {
+	Events []notify.Event `json:"events"`
+	Lost   int            `json:"lost"` // events dropped because they were not collected in time
+}
+
+// wait for events on the accounts watched by this connection
+func (owner *Owner) Events(arguments *OwnerEventsArguments, reply *OwnerEventsReply) error {
+
+	if arguments.Count <= 0 || arguments.Count > 100 {
+		return fault.ErrInvalidCount
+	}
+	if 0 == owner.subscription.Count() {
+		return fault.ErrNotInitialised
+	}
+
+	timeout := time.Duration(arguments.Timeout) * time.Second
+	if timeout < 0 || timeout > maximumEventWait {
+		timeout = maximumEventWait
+	}
+
+	reply.Events, reply.Lost = owner.subscription.Wait(arguments.Count, timeout)
+	return nil
+}
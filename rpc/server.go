@@ -6,6 +6,7 @@ package rpc
 
 import (
 	"github.com/bitmark-inc/bitmarkd/counter"
+	"github.com/bitmark-inc/bitmarkd/notify"
 	"github.com/bitmark-inc/logger"
 	"io"
 	"net/rpc"
@@ -50,10 +51,20 @@ func Callback(conn io.ReadWriteCloser, argument interface{}) {
 		log: serverArgument.Log,
 	}
 
-	owner := &Owner{
+	blocks := &Blocks{
+		log: serverArgument.Log,
+	}
+
+	offer := &Offer{
 		log: serverArgument.Log,
 	}
 
+	owner := &Owner{
+		log:          serverArgument.Log,
+		subscription: notify.NewSubscription(),
+	}
+	defer owner.subscription.Close()
+
 	node := &Node{
 		log:   serverArgument.Log,
 		start: serverArgument.StartTime,
@@ -69,6 +80,8 @@ func Callback(conn io.ReadWriteCloser, argument interface{}) {
 	server.Register(assets)
 	server.Register(bitmark)
 	server.Register(bitmarks)
+	server.Register(blocks)
+	server.Register(offer)
 	server.Register(owner)
 	server.Register(node)
 	server.Register(transaction)
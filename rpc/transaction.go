@@ -5,8 +5,16 @@
 package rpc
 
 import (
+	"github.com/bitmark-inc/bitmarkd/block"
+	"github.com/bitmark-inc/bitmarkd/blockdigest"
+	"github.com/bitmark-inc/bitmarkd/blockrecord"
+	"github.com/bitmark-inc/bitmarkd/fault"
 	"github.com/bitmark-inc/bitmarkd/merkle"
+	"github.com/bitmark-inc/bitmarkd/mode"
+	"github.com/bitmark-inc/bitmarkd/peer"
 	"github.com/bitmark-inc/bitmarkd/reservoir"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"github.com/bitmark-inc/bitmarkd/transactionrecord"
 	"github.com/bitmark-inc/logger"
 	"time"
 )
@@ -24,11 +32,134 @@ type TransactionArguments struct {
 
 // TransactionStatus is a struct for an rpc reply
 type TransactionStatusReply struct {
-	Status string `json:"status"`
+	Status  string                   `json:"status"`
+	Payment reservoir.TrackingStatus `json:"payment,omitempty"` // only set if the payment was rejected
+	Reason  string                   `json:"reason,omitempty"`
 }
 
 // Status is an rpc api for query transaction status
 func (t *Transaction) Status(arguments *TransactionArguments, reply *TransactionStatusReply) error {
 	reply.Status = reservoir.TransactionStatus(arguments.TxId).String()
+	status, err := reservoir.PaymentStatus(arguments.TxId)
+	if nil != err {
+		reply.Payment = status
+		reply.Reason = err.Error()
+	}
+	return nil
+}
+
+// TransactionGetReply is a confirmed transaction with its block details
+type TransactionGetReply struct {
+	Record        string             `json:"record"`
+	TxId          merkle.Digest      `json:"txId"`
+	BlockNumber   uint64             `json:"blockNumber,string"`
+	BlockDigest   blockdigest.Digest `json:"blockDigest"`
+	Confirmations uint64             `json:"confirmations,string"` // 1 when in the latest block
+	Data          interface{}        `json:"data"`
+}
+
+// Get is an rpc api to fetch a confirmed transaction
+// in light mode it is fetched from a peer and checked against the local headers
+func (t *Transaction) Get(arguments *TransactionArguments, reply *TransactionGetReply) error {
+
+	t.log.Infof("Transaction.Get: %v", arguments)
+
+	txId := arguments.TxId
+
+	blockNumber, packed, err := confirmedTransaction(txId)
+	if nil != err {
+		return err
+	}
+	digest, err := block.DigestForBlock(blockNumber)
+	if nil != err {
+		return err
+	}
+
+	transaction, _, err := packed.Unpack()
+	if nil != err {
+		return err
+	}
+
+	reply.Record, _ = transactionrecord.RecordName(transaction)
+	reply.TxId = txId
+	reply.BlockNumber = blockNumber
+	reply.BlockDigest = digest
+	if height := block.GetHeight(); height >= blockNumber {
+		reply.Confirmations = height - blockNumber + 1
+	}
+	reply.Data = transaction
+
+	return nil
+}
+
+// fetch a confirmed transaction and the number of its block
+func confirmedTransaction(txId merkle.Digest) (uint64, transactionrecord.Packed, error) {
+
+	if mode.IsLight() {
+		proof, _, err := peer.TransactionProof(txId)
+		if nil != err {
+			return 0, nil, err
+		}
+		return proof.BlockNumber, proof.Packed, nil
+	}
+
+	blockNumber, _, err := block.LocateTransaction(txId)
+	if nil != err {
+		return 0, nil, err
+	}
+	packed := storage.Pool.Transactions.Get(txId[:])
+	if nil == packed {
+		return 0, nil, fault.ErrTransactionNotFound
+	}
+	return blockNumber, transactionrecord.Packed(packed), nil
+}
+
+// TransactionInclusionProofReply has the data needed to show that a
+// transaction is in a block: the siblings and position recompute the
+// merkle root of the header, see: merkle.VerifyInclusion
+type TransactionInclusionProofReply struct {
+	TxId        merkle.Digest       `json:"txId"`
+	BlockNumber uint64              `json:"blockNumber,string"`
+	BlockDigest blockdigest.Digest  `json:"blockDigest"`
+	Header      *blockrecord.Header `json:"header"`
+	Index       int                 `json:"index"`
+	Siblings    []merkle.Digest     `json:"siblings"`
+}
+
+// InclusionProof is an rpc api to fetch the merkle proof that a
+// transaction was confirmed in a block
+func (t *Transaction) InclusionProof(arguments *TransactionArguments, reply *TransactionInclusionProofReply) error {
+
+	t.log.Infof("Transaction.InclusionProof: %v", arguments)
+
+	txId := arguments.TxId
+
+	var proof *block.TransactionProof
+	var err error
+	if mode.IsLight() {
+		proof, _, err = peer.TransactionProof(txId)
+	} else {
+		proof, err = block.ProveTransaction(txId)
+	}
+	if nil != err {
+		return err
+	}
+
+	header, err := block.HeaderForBlock(proof.BlockNumber)
+	if nil != err {
+		return err
+	}
+	digest, err := block.DigestForBlock(proof.BlockNumber)
+	if nil != err {
+		return err
+	}
+
+	reply.TxId = txId
+	reply.BlockNumber = proof.BlockNumber
+	reply.BlockDigest = digest
+	reply.Header = header
+	reply.Index = proof.Index
+	reply.Siblings = proof.Siblings
+
 	return nil
 }
@@ -0,0 +1,134 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/syndtr/goleveldb/leveldb"
+	"sort"
+)
+
+// a set of pool updates that are written to the database as a single unit
+//
+// nothing is visible to the database until Commit is called, so
+// discarding the batch (or crashing before commit) leaves the
+// database unchanged
+type Batch struct {
+	batch    *leveldb.Batch
+	pending  map[string][]byte // prefixed key → value; nil value is a pending delete
+	isolated map[byte]bool     // prefixes of pools that never read from the database
+}
+
+// start a new empty batch
+func NewBatch() *Batch {
+	return &Batch{
+		batch:    new(leveldb.Batch),
+		pending:  make(map[string][]byte),
+		isolated: make(map[byte]bool),
+	}
+}
+
+// start a new empty batch in which the given pools appear empty
+//
+// reads of these pools only see values queued in the batch, which
+// allows the pools to be recomputed from scratch without touching
+// their current contents
+func NewIsolatedBatch(pools ...*PoolHandle) *Batch {
+	b := NewBatch()
+	for _, p := range pools {
+		b.isolated[p.prefix] = true
+	}
+	return b
+}
+
+// queue storing a key/value bytes pair to a pool
+func (b *Batch) Put(p *PoolHandle, key []byte, value []byte) {
+	prefixedKey := p.prefixKey(key)
+	b.batch.Put(prefixedKey, value)
+
+	// copy so later changes by the caller do not affect reads
+	v := make([]byte, len(value))
+	copy(v, value)
+	b.pending[string(prefixedKey)] = v
+}
+
+// queue removing a key from a pool
+func (b *Batch) Delete(p *PoolHandle, key []byte) {
+	prefixedKey := p.prefixKey(key)
+	b.batch.Delete(prefixedKey)
+	b.pending[string(prefixedKey)] = nil
+}
+
+// read a value for a given key
+//
+// changes already queued in this batch take precedence over the database
+func (b *Batch) Get(p *PoolHandle, key []byte) []byte {
+	if value, ok := b.pending[string(p.prefixKey(key))]; ok {
+		if nil == value {
+			return nil
+		}
+		result := make([]byte, len(value))
+		copy(result, value)
+		return result
+	}
+	if b.isolated[p.prefix] {
+		return nil
+	}
+	return p.Get(key)
+}
+
+// Check if a key exists, including any changes queued in this batch
+func (b *Batch) Has(p *PoolHandle, key []byte) bool {
+	if value, ok := b.pending[string(p.prefixKey(key))]; ok {
+		return nil != value
+	}
+	if b.isolated[p.prefix] {
+		return false
+	}
+	return p.Has(key)
+}
+
+// all values queued for a pool, in key order
+//
+// pending deletes are not included
+func (b *Batch) Elements(p *PoolHandle) []Element {
+	keys := make([]string, 0, len(b.pending))
+	for k, value := range b.pending {
+		if p.prefix == k[0] && nil != value {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	elements := make([]Element, len(keys))
+	for i, k := range keys {
+		value := b.pending[k]
+		elements[i].Key = []byte(k[1:])
+		elements[i].Value = make([]byte, len(value))
+		copy(elements[i].Value, value)
+	}
+	return elements
+}
+
+// number of queued changes
+func (b *Batch) Len() int {
+	return b.batch.Len()
+}
+
+// write all queued changes to the database atomically
+//
+// the batch is empty afterwards and can be reused
+func (b *Batch) Commit() {
+	err := poolData.database.Write(b.batch, nil)
+	fault.PanicIfError("batch.Commit", err)
+
+	b.Discard()
+}
+
+// drop all queued changes without writing anything
+func (b *Batch) Discard() {
+	b.batch.Reset()
+	b.pending = make(map[string][]byte)
+}
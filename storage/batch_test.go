@@ -0,0 +1,134 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package storage_test
+
+import (
+	"bytes"
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"testing"
+)
+
+// queue some changes across two pools
+func fillBatch(batch *storage.Batch) {
+	batch.Put(storage.Pool.TestData, []byte("key-one"), []byte("batch-one"))
+	batch.Put(storage.Pool.TestData, []byte("key-new"), []byte("batch-new"))
+	batch.Delete(storage.Pool.TestData, []byte("key-two"))
+	batch.Put(storage.Pool.Blocks, []byte("key-one"), []byte("block-one"))
+}
+
+// queue changes then fail before commit
+func crashingWriter(t *testing.T, batch *storage.Batch) {
+	defer func() {
+		if r := recover(); nil == r {
+			t.Fatalf("expected a panic")
+		}
+	}()
+
+	fillBatch(batch)
+	panic("simulated crash")
+}
+
+// check the pools are unchanged from their initial values
+func checkUnchanged(t *testing.T, title string) {
+	p := storage.Pool.TestData
+	if value := p.Get([]byte("key-one")); !bytes.Equal(value, []byte("data-one")) {
+		t.Errorf("%s: key-one: %q  expected: %q", title, value, "data-one")
+	}
+	if !p.Has([]byte("key-two")) {
+		t.Errorf("%s: key-two was deleted", title)
+	}
+	if p.Has([]byte("key-new")) {
+		t.Errorf("%s: key-new was stored", title)
+	}
+	if storage.Pool.Blocks.Has([]byte("key-one")) {
+		t.Errorf("%s: block key-one was stored", title)
+	}
+}
+
+// check all batch changes are present
+func checkCommitted(t *testing.T, title string) {
+	p := storage.Pool.TestData
+	if value := p.Get([]byte("key-one")); !bytes.Equal(value, []byte("batch-one")) {
+		t.Errorf("%s: key-one: %q  expected: %q", title, value, "batch-one")
+	}
+	if p.Has([]byte("key-two")) {
+		t.Errorf("%s: key-two was not deleted", title)
+	}
+	if value := p.Get([]byte("key-new")); !bytes.Equal(value, []byte("batch-new")) {
+		t.Errorf("%s: key-new: %q  expected: %q", title, value, "batch-new")
+	}
+	if value := storage.Pool.Blocks.Get([]byte("key-one")); !bytes.Equal(value, []byte("block-one")) {
+		t.Errorf("%s: block key-one: %q  expected: %q", title, value, "block-one")
+	}
+}
+
+// reads through a batch see the queued changes
+func TestBatchGet(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	p := storage.Pool.TestData
+	poolPut(t, p, "key-one", "data-one")
+	poolPut(t, p, "key-two", "data-two")
+
+	batch := storage.NewBatch()
+	fillBatch(batch)
+
+	if 4 != batch.Len() {
+		t.Errorf("batch length: %d  expected: 4", batch.Len())
+	}
+	if value := batch.Get(p, []byte("key-one")); !bytes.Equal(value, []byte("batch-one")) {
+		t.Errorf("batch key-one: %q  expected: %q", value, "batch-one")
+	}
+	if nil != batch.Get(p, []byte("key-two")) || batch.Has(p, []byte("key-two")) {
+		t.Errorf("batch key-two: still present")
+	}
+	if !batch.Has(p, []byte("key-new")) {
+		t.Errorf("batch key-new: missing")
+	}
+
+	// nothing written yet
+	checkUnchanged(t, "before commit")
+
+	batch.Commit()
+	checkCommitted(t, "after commit")
+
+	if 0 != batch.Len() {
+		t.Errorf("batch length after commit: %d  expected: 0", batch.Len())
+	}
+}
+
+// a crash before commit must leave the database unchanged
+func TestBatchCrash(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	p := storage.Pool.TestData
+	poolPut(t, p, "key-one", "data-one")
+	poolPut(t, p, "key-two", "data-two")
+
+	batch := storage.NewBatch()
+	crashingWriter(t, batch)
+	checkUnchanged(t, "after crash")
+
+	// restart the database
+	storage.Finalise()
+	storage.Initialise(databaseFileName)
+	checkUnchanged(t, "after restart")
+
+	// discarded changes are never written
+	batch = storage.NewBatch()
+	fillBatch(batch)
+	batch.Discard()
+	batch.Commit()
+	checkUnchanged(t, "after discard")
+
+	// a committed batch survives a restart
+	fillBatch(batch)
+	batch.Commit()
+	storage.Finalise()
+	storage.Initialise(databaseFileName)
+	checkCommitted(t, "committed after restart")
+}
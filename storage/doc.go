@@ -26,18 +26,34 @@
 //
 //   B ++ block number          - block store
 //                                data: header ++ base transaction ++ (concat transactions)
-//   F ++ block number          - current block owner
-//                                data: owner ++ currency ++ currency address
+//   F ++ block number          - payment addresses of the block owner
+//                                data: count ++ (currency ++ currency address) for each currency
+//                                (see: transactionrecord.PackPaymentAddresses)
+//   H ++ block digest          - block number for a block digest
+//                                data: block number
 //
 // Transactions:
 //
 //   T ++ txId                  - confirmed transactions
 //                                data: packed transaction data
+//   L ++ txId                  - location of a confirmed transaction
+//                                data: block number ++ byte offset in block (big endian uint64, 8 bytes)
+//   S ++ batch link id         - one bitmark moved by a confirmed batch transfer
+//                                data: batch txId ++ previous link
 //
 // Assets:
 //
 //   A ++ asset index           - confirmed asset
 //                                data: packed asset data
+//   R ++ registrant ++ block number ++ asset index
+//                              - confirmed assets of a registrant in order of registration
+//                                data: empty
+//   E ++ name ++ 0x00 ++ asset index
+//                              - confirmed assets by name (lower case)
+//                                data: empty
+//   M ++ metadata key ++ 0x00 ++ asset index
+//                              - confirmed assets by each metadata key (lower case)
+//                                data: empty
 //
 // Ownership:
 //
@@ -53,9 +69,19 @@
 //   C ++ currency(uint64)      - currency processing
 //                                data: latest block number (big endian uint64, 8 bytes)
 //
-//   P ++ payId                 - payment confirmation (array of addresses + values)
+//   P ++ payId ++ txId         - payment confirmation (array of addresses + values) for one currency transaction
 //                                data: currency(varint) ++ txId_bytes(varint) ++ txId ++ [ count(varint) ++ address ++ value(varint) ]
 //
+// Reservoir:
+//
+//   U ++ payId                 - unverified transactions waiting for payment or proof
+//                                data: expiry time(unix seconds) ++ pay nonce ++ difficulty bits(zero if none) ++
+//                                      fee block number(zero if none) ++ packed records
+//   V ++ txId                  - verified transaction waiting to be included in a block
+//                                data: packed records
+//
+//   packed records are the transactions preceded by any of their assets that are not yet confirmed
+//
 //
 // Testing:
 //   Z ++ key                   - testing data
@@ -30,24 +30,50 @@ type PoolHandle struct {
 //
 // note all must be exported (i.e. initial capital) or initialisation will panic
 type pools struct {
-	Blocks       *PoolHandle `prefix:"B"`
-	BlockOwners  *PoolHandle `prefix:"F"`
-	Assets       *PoolHandle `prefix:"A"`
-	Transactions *PoolHandle `prefix:"T"`
-	OwnerCount   *PoolHandle `prefix:"N"`
-	Ownership    *PoolHandle `prefix:"K"`
-	OwnerDigest  *PoolHandle `prefix:"D"`
-	Currency     *PoolHandle `prefix:"C"`
-	Payment      *PoolHandle `prefix:"P"`
-	TestData     *PoolHandle `prefix:"Z"`
+	Blocks           *PoolHandle `prefix:"B"`
+	BlockOwners      *PoolHandle `prefix:"F"`
+	BlockNumbers     *PoolHandle `prefix:"H"`
+	Assets           *PoolHandle `prefix:"A"`
+	AssetRegistrants *PoolHandle `prefix:"R"`
+	AssetNames       *PoolHandle `prefix:"E"`
+	AssetMetadata    *PoolHandle `prefix:"M"`
+	Transactions     *PoolHandle `prefix:"T"`
+	TxBlock          *PoolHandle `prefix:"L"`
+	BatchLinks       *PoolHandle `prefix:"S"`
+	OwnerCount       *PoolHandle `prefix:"N"`
+	Ownership        *PoolHandle `prefix:"K"`
+	OwnerDigest      *PoolHandle `prefix:"D"`
+	Currency         *PoolHandle `prefix:"C"`
+	Payment          *PoolHandle `prefix:"P"`
+	PendingEntries   *PoolHandle `prefix:"U"`
+	VerifiedEntries  *PoolHandle `prefix:"V"`
+	TestData         *PoolHandle `prefix:"Z"`
 }
 
 // the instance
 var Pool pools
 
 // for database version
+//
+// change the version whenever a pool is added or the format of a value
+// changes, an older database is then refused and must be rebuilt by
+// synchronising from an empty database since its indexes are incomplete
+//
+// 2: initial
+// 3: F holds a list of payment addresses
+// 4: L transaction to block number
+// 5: L value adds the byte offset in the block
+// 6: H block digest to block number
+// 7: R, E and M asset indexes
+// 8: S batch links
+// 9: P key adds the currency transaction id, U value adds the fee block number
 var versionKey = []byte{0x00, 'V', 'E', 'R', 'S', 'I', 'O', 'N'}
-var currentVersion = []byte{0x00, 0x00, 0x00, 0x02}
+var currentVersion = []byte{0x00, 0x00, 0x00, 0x09}
+
+// for database mode: a light database only holds block headers
+var modeKey = []byte{0x00, 'M', 'O', 'D', 'E'}
+var fullMode = []byte{'F', 'U', 'L', 'L'}
+var lightMode = []byte{'L', 'I', 'G', 'H', 'T'}
 
 // holds the database handle
 var poolData struct {
@@ -121,6 +147,35 @@ func Initialise(database string) error {
 	return nil
 }
 
+// record the mode of a new database or ensure that an existing
+// database was created in the same mode
+//
+// a database cannot switch between full and light mode since light
+// mode does not store the transactions
+func CheckMode(light bool) error {
+	poolData.Lock()
+	defer poolData.Unlock()
+
+	if nil == poolData.database {
+		return fault.ErrNotInitialised
+	}
+
+	expected := fullMode
+	if light {
+		expected = lightMode
+	}
+
+	modeValue, err := poolData.database.Get(modeKey, nil)
+	if leveldb.ErrNotFound == err {
+		return poolData.database.Put(modeKey, expected, nil)
+	} else if nil != err {
+		return err
+	} else if !bytes.Equal(modeValue, expected) {
+		return fmt.Errorf("incompatible database mode: expected: %s  actual: %s", expected, modeValue)
+	}
+	return nil
+}
+
 // close the database connection
 func Finalise() {
 	poolData.Lock()
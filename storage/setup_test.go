@@ -0,0 +1,36 @@
+// Copyright (c) 2014-2017 Bitmark Inc.
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package storage_test
+
+import (
+	"github.com/bitmark-inc/bitmarkd/storage"
+	"testing"
+)
+
+// the first mode is recorded and a different mode is refused
+func TestCheckMode(t *testing.T) {
+	setup(t)
+	defer teardown(t)
+
+	if err := storage.CheckMode(true); nil != err {
+		t.Fatalf("new database: error: %v", err)
+	}
+	if err := storage.CheckMode(true); nil != err {
+		t.Errorf("same mode: error: %v", err)
+	}
+
+	// the mode survives a restart
+	storage.Finalise()
+	err := storage.Initialise(databaseFileName)
+	if nil != err {
+		t.Fatalf("storage initialise error: %s", err)
+	}
+	if err := storage.CheckMode(false); nil == err {
+		t.Errorf("full node accepted a light database")
+	}
+	if err := storage.CheckMode(true); nil != err {
+		t.Errorf("reopened: error: %v", err)
+	}
+}
@@ -6,7 +6,9 @@ package transactionrecord
 
 import (
 	"github.com/bitmark-inc/bitmarkd/account"
+	"github.com/bitmark-inc/bitmarkd/currency"
 	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/merkle"
 	"github.com/bitmark-inc/bitmarkd/util"
 	"strings"
 	"unicode/utf8"
@@ -48,6 +50,70 @@ func (baseData *BaseData) Pack(address *account.Account) (Packed, error) {
 	return appendBytes(message, baseData.Signature), nil
 }
 
+// pack BlockFoundation
+//
+// Pack Varint64(tag) followed by the payment addresses, see:
+// PackPaymentAddresses, then the remaining fields in order as struct
+// above with signature last
+//
+// NOTE: returns the "unsigned" message on signature failure - for
+//       debugging/testing
+func (foundation *BlockFoundation) Pack(address *account.Account) (Packed, error) {
+	if len(foundation.Signature) > maxSignatureLength {
+		return nil, fault.ErrSignatureTooLong
+	}
+
+	if nil == foundation.Owner || nil == address {
+		return nil, fault.ErrInvalidOwnerOrRegistrant
+	}
+
+	// one to the number of currencies entries, each currency once
+	if 0 == len(foundation.Payments) || len(foundation.Payments) > maxPaymentAddresses {
+		return nil, fault.ErrInvalidCount
+	}
+	seen := make(map[currency.Currency]struct{}, len(foundation.Payments))
+	for _, a := range foundation.Payments {
+		if a.Currency < currency.First || a.Currency > currency.Last {
+			return nil, fault.ErrInvalidCurrency
+		}
+		if _, ok := seen[a.Currency]; ok {
+			return nil, fault.ErrDuplicatePaymentCurrency
+		}
+		seen[a.Currency] = struct{}{}
+
+		if utf8.RuneCountInString(a.Address) > maxPaymentAddressLength {
+			return nil, fault.ErrPaymentAddressTooLong
+		}
+	}
+
+	// concatenate bytes
+	message := util.ToVarint64(uint64(BlockFoundationTag))
+	message = append(message, PackPaymentAddresses(foundation.Payments)...)
+	message = appendAccount(message, foundation.Owner)
+	message = appendUint64(message, foundation.Nonce)
+
+	// signature
+	err := address.CheckSignature(message, foundation.Signature)
+	if nil != err {
+		return message, err
+	}
+	// Signature Last
+	return appendBytes(message, foundation.Signature), nil
+}
+
+// pack a list of payment addresses
+//
+// Varint64(count) followed by Varint64(currency) and the address for
+// each entry
+func PackPaymentAddresses(addresses []PaymentAddress) []byte {
+	buffer := appendUint64(nil, uint64(len(addresses)))
+	for _, a := range addresses {
+		buffer = appendUint64(buffer, a.Currency.Uint64())
+		buffer = appendString(buffer, a.Address)
+	}
+	return buffer
+}
+
 // pack AssetData
 //
 // Pack Varint64(tag) followed by fields in order as struct above with
@@ -160,36 +226,187 @@ func (transfer *BitmarkTransfer) Pack(address *account.Account) (Packed, error)
 		return nil, fault.ErrSignatureTooLong
 	}
 
-	if nil == transfer.Owner || nil == address {
+	if nil == address {
+		return nil, fault.ErrInvalidOwnerOrRegistrant
+	}
+
+	message, err := packTransfer(BitmarkTransferTag, transfer.Link, transfer.Payment, transfer.Owner)
+	if nil != err {
+		return nil, err
+	}
+
+	// signature
+	err = address.CheckSignature(message, transfer.Signature)
+	if nil != err {
+		return message, err
+	}
+
+	// Signature Last
+	return appendBytes(message, transfer.Signature), nil
+}
+
+// local function to pack BitmarkTransferCountersigned
+//
+// Pack Varint64(tag) followed by fields in order as struct above with
+// the signature and countersignature last, both are over the same
+// message
+//
+// NOTE: returns the "unsigned" message on signature failure - for
+//       debugging/testing
+func (transfer *BitmarkTransferCountersigned) Pack(address *account.Account) (Packed, error) {
+	if len(transfer.Countersignature) > maxSignatureLength {
+		return nil, fault.ErrSignatureTooLong
+	}
+
+	offer, err := transfer.PackOffer(address)
+	if nil != err {
+		return offer, err
+	}
+
+	// the message is the offer without its signature
+	message, _ := packTransfer(BitmarkTransferCountersignedTag, transfer.Link, transfer.Payment, transfer.Owner)
+
+	// countersignature
+	err = transfer.Owner.CheckSignature(message, transfer.Countersignature)
+	if nil != err {
+		return message, fault.ErrInvalidCountersignature
+	}
+
+	// Countersignature Last
+	return appendBytes(offer, transfer.Countersignature), nil
+}
+
+// pack a BitmarkTransferCountersigned without its countersignature
+//
+// only the current owner's signature is checked, this is the form
+// held while waiting for the new owner to accept
+//
+// NOTE: returns the "unsigned" message on signature failure - for
+//       debugging/testing
+func (transfer *BitmarkTransferCountersigned) PackOffer(address *account.Account) (Packed, error) {
+	if len(transfer.Signature) > maxSignatureLength {
+		return nil, fault.ErrSignatureTooLong
+	}
+
+	if nil == address {
+		return nil, fault.ErrInvalidOwnerOrRegistrant
+	}
+
+	message, err := packTransfer(BitmarkTransferCountersignedTag, transfer.Link, transfer.Payment, transfer.Owner)
+	if nil != err {
+		return nil, err
+	}
+
+	// signature
+	err = address.CheckSignature(message, transfer.Signature)
+	if nil != err {
+		return message, err
+	}
+
+	return appendBytes(message, transfer.Signature), nil
+}
+
+// local function to pack BitmarkBatchTransfer
+//
+// Pack Varint64(tag) followed by Varint64(count) and the links, then
+// the owner with signature last
+//
+// NOTE: returns the "unsigned" message on signature failure - for
+//       debugging/testing
+func (batch *BitmarkBatchTransfer) Pack(address *account.Account) (Packed, error) {
+	if len(batch.Signature) > maxSignatureLength {
+		return nil, fault.ErrSignatureTooLong
+	}
+
+	if nil == batch.Owner || nil == address {
+		return nil, fault.ErrInvalidOwnerOrRegistrant
+	}
+
+	if 0 == len(batch.Links) || len(batch.Links) > MaximumBatchLinks {
+		return nil, fault.ErrInvalidCount
+	}
+
+	// each bitmark can only be moved once
+	seen := make(map[merkle.Digest]struct{}, len(batch.Links))
+	for _, link := range batch.Links {
+		if _, ok := seen[link]; ok {
+			return nil, fault.ErrDoubleTransferAttempt
+		}
+		seen[link] = struct{}{}
+	}
+
+	// concatenate bytes
+	message := util.ToVarint64(uint64(BitmarkBatchTransferTag))
+	message = appendUint64(message, uint64(len(batch.Links)))
+	for _, link := range batch.Links {
+		message = appendBytes(message, link[:])
+	}
+	message = appendAccount(message, batch.Owner)
+
+	// signature
+	err := address.CheckSignature(message, batch.Signature)
+	if nil != err {
+		return message, err
+	}
+
+	// Signature Last
+	return appendBytes(message, batch.Signature), nil
+}
+
+// the unsigned message common to both kinds of transfer
+func packTransfer(tag TagType, link merkle.Digest, payment *Payment, owner *account.Account) (Packed, error) {
+
+	if nil == owner {
 		return nil, fault.ErrInvalidOwnerOrRegistrant
 	}
 
 	// concatenate bytes
-	message := util.ToVarint64(uint64(BitmarkTransferTag))
-	message = appendBytes(message, transfer.Link[:])
+	message := util.ToVarint64(uint64(tag))
+	message = appendBytes(message, link[:])
 
-	if nil == transfer.Payment {
+	if nil == payment {
 		message = append(message, 0)
 	} else {
-		if utf8.RuneCountInString(transfer.Payment.Address) > maxPaymentAddressLength {
+		if utf8.RuneCountInString(payment.Address) > maxPaymentAddressLength {
 			return nil, fault.ErrPaymentAddressTooLong
 		}
 		message = append(message, 1)
-		message = appendUint64(message, transfer.Payment.Currency.Uint64())
-		message = appendString(message, transfer.Payment.Address)
-		message = appendUint64(message, transfer.Payment.Amount)
+		message = appendUint64(message, payment.Currency.Uint64())
+		message = appendString(message, payment.Address)
+		message = appendUint64(message, payment.Amount)
 	}
 
-	message = appendAccount(message, transfer.Owner)
+	return appendAccount(message, owner), nil
+}
+
+// local function to pack BitmarkBurn
+//
+// Pack Varint64(tag) followed by fields in order as struct above with
+// signature last
+//
+// NOTE: returns the "unsigned" message on signature failure - for
+//       debugging/testing
+func (burn *BitmarkBurn) Pack(address *account.Account) (Packed, error) {
+	if len(burn.Signature) > maxSignatureLength {
+		return nil, fault.ErrSignatureTooLong
+	}
+
+	if nil == address {
+		return nil, fault.ErrInvalidOwnerOrRegistrant
+	}
+
+	// concatenate bytes
+	message := util.ToVarint64(uint64(BitmarkBurnTag))
+	message = appendBytes(message, burn.Link[:])
 
 	// signature
-	err := address.CheckSignature(message, transfer.Signature)
+	err := address.CheckSignature(message, burn.Signature)
 	if nil != err {
 		return message, err
 	}
 
 	// Signature Last
-	return appendBytes(message, transfer.Signature), nil
+	return appendBytes(message, burn.Signature), nil
 }
 
 // append a single field to a buffer
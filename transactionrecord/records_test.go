@@ -19,6 +19,7 @@ import (
 	"github.com/bitmark-inc/bitmarkd/util"
 	"golang.org/x/crypto/ed25519"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -1064,3 +1065,574 @@ func TestPackBitmarkTransferThree(t *testing.T) {
 		t.Fatalf("different, original: %v  recovered: %v", r, *bmt)
 	}
 }
+
+// test the packing/unpacking of Bitmark burn record
+//
+// ensures that pack->unpack returns the same original value
+func TestPackBitmarkBurn(t *testing.T) {
+
+	ownerOneAccount := makeAccount(ownerOne.publicKey)
+
+	var link merkle.Digest
+	err := merkleDigestFromLE("630c041cd1f586bcb9097e816189185c1e0379f67bbfc2f0626724f542047873", &link)
+	if nil != err {
+		t.Fatalf("hex to link error: %v", err)
+	}
+
+	r := transactionrecord.BitmarkBurn{
+		Link: link,
+	}
+
+	expected := []byte{
+		0x05, 0x20, 0x63, 0x0c, 0x04, 0x1c, 0xd1, 0xf5,
+		0x86, 0xbc, 0xb9, 0x09, 0x7e, 0x81, 0x61, 0x89,
+		0x18, 0x5c, 0x1e, 0x03, 0x79, 0xf6, 0x7b, 0xbf,
+		0xc2, 0xf0, 0x62, 0x67, 0x24, 0xf5, 0x42, 0x04,
+		0x78, 0x73,
+	}
+
+	expectedTxId := merkle.Digest{
+		0xbb, 0x08, 0xab, 0x44, 0xdf, 0xf2, 0xb9, 0x30,
+		0xa8, 0x65, 0xa5, 0xc2, 0x83, 0x5b, 0x2e, 0xbb,
+		0xdb, 0x7d, 0x81, 0xd3, 0xe0, 0xba, 0xc0, 0xb8,
+		0x12, 0xc0, 0x73, 0xb8, 0x1f, 0xa1, 0x52, 0x19,
+	}
+
+	// manually sign the record and attach signature to "expected"
+	signature := ed25519.Sign(ownerOne.privateKey, expected)
+	r.Signature = signature[:]
+	l := util.ToVarint64(uint64(len(signature)))
+	expected = append(expected, l...)
+	expected = append(expected, signature[:]...)
+
+	// test the packer
+	packed, err := r.Pack(ownerOneAccount)
+	if nil != err {
+		t.Errorf("pack error: %v", err)
+	}
+
+	// if either of above fail we will have the message _without_ a signature
+	if !bytes.Equal(packed, expected) {
+		t.Errorf("pack record: %x  expected: %x", packed, expected)
+		t.Errorf("*** GENERATED Packed:\n%s", util.FormatBytes("expected", packed))
+		t.Fatal("fatal error")
+	}
+
+	t.Logf("Packed length: %d bytes", len(packed))
+
+	// check txId
+	txId := packed.MakeLink()
+
+	if txId != expectedTxId {
+		t.Errorf("pack txId: %#v  expected: %x", txId, expectedTxId)
+		t.Errorf("*** GENERATED txId:\n%s", util.FormatBytes("expectedTxId", txId[:]))
+		t.Fatal("fatal error")
+	}
+
+	// test the unpacker
+	unpacked, n, err := packed.Unpack()
+	if nil != err {
+		t.Fatalf("unpack error: %v", err)
+	}
+	if len(packed) != n {
+		t.Errorf("did not unpack all data: only used: %d of: %d bytes", n, len(packed))
+	}
+
+	bmb, ok := unpacked.(*transactionrecord.BitmarkBurn)
+	if !ok {
+		t.Fatalf("did not unpack to BitmarkBurn")
+	}
+
+	// display a JSON version for information
+	item := struct {
+		TxId        merkle.Digest
+		BitmarkBurn *transactionrecord.BitmarkBurn
+	}{
+		txId,
+		bmb,
+	}
+	b, err := json.MarshalIndent(item, "", "  ")
+	if nil != err {
+		t.Fatalf("json error: %v", err)
+	}
+
+	t.Logf("Bitmark Burn: JSON: %s", b)
+
+	// check that structure is preserved through Pack/Unpack
+	// note reg is a pointer here
+	if !reflect.DeepEqual(r, *bmb) {
+		t.Fatalf("different, original: %v  recovered: %v", r, *bmb)
+	}
+
+	// a burn can only be signed by the owner in the linked record
+	issuerAccount := makeAccount(issuer.publicKey)
+	if _, err := r.Pack(issuerAccount); nil == err {
+		t.Fatalf("pack with wrong owner did not fail")
+	}
+}
+
+// test the packing/unpacking of countersigned Bitmark transfer record
+//
+// ensures that pack->unpack returns the same original value
+func TestPackBitmarkTransferCountersigned(t *testing.T) {
+
+	issuerAccount := makeAccount(issuer.publicKey)
+	ownerOneAccount := makeAccount(ownerOne.publicKey)
+
+	var link merkle.Digest
+	err := merkleDigestFromLE("79a67be2b3d313bd490363fb0d27901c46ed53d3f7b21f60d48bc42439b06084", &link)
+	if nil != err {
+		t.Fatalf("hex to link error: %v", err)
+	}
+
+	r := transactionrecord.BitmarkTransferCountersigned{
+		Link:  link,
+		Owner: ownerOneAccount,
+	}
+
+	expected := []byte{
+		0x06, 0x20, 0x79, 0xa6, 0x7b, 0xe2, 0xb3, 0xd3,
+		0x13, 0xbd, 0x49, 0x03, 0x63, 0xfb, 0x0d, 0x27,
+		0x90, 0x1c, 0x46, 0xed, 0x53, 0xd3, 0xf7, 0xb2,
+		0x1f, 0x60, 0xd4, 0x8b, 0xc4, 0x24, 0x39, 0xb0,
+		0x60, 0x84, 0x00, 0x21, 0x13, 0x27, 0x64, 0x0e,
+		0x4a, 0xab, 0x92, 0xd8, 0x7b, 0x4a, 0x6a, 0x2f,
+		0x30, 0xb8, 0x81, 0xf4, 0x49, 0x29, 0xf8, 0x66,
+		0x04, 0x3a, 0x84, 0x1c, 0x38, 0x14, 0xb1, 0x66,
+		0xb8, 0x89, 0x44, 0xb0, 0x92,
+	}
+
+	expectedTxId := merkle.Digest{
+		0x34, 0x91, 0x19, 0x53, 0x78, 0x9d, 0x9f, 0xfd,
+		0x59, 0x90, 0x97, 0x7e, 0xed, 0x38, 0x04, 0xb8,
+		0xc4, 0xfd, 0xfb, 0x60, 0x7d, 0x48, 0xdf, 0xca,
+		0x04, 0xad, 0x85, 0xd5, 0xa7, 0x7a, 0xca, 0x0e,
+	}
+
+	// manually sign the record and attach both signatures to "expected"
+	signature := ed25519.Sign(issuer.privateKey, expected)
+	r.Signature = signature[:]
+	countersignature := ed25519.Sign(ownerOne.privateKey, expected)
+	r.Countersignature = countersignature[:]
+	l := util.ToVarint64(uint64(len(signature)))
+	expected = append(expected, l...)
+	expected = append(expected, signature[:]...)
+	offer := append([]byte{}, expected...)
+	l = util.ToVarint64(uint64(len(countersignature)))
+	expected = append(expected, l...)
+	expected = append(expected, countersignature[:]...)
+
+	// the offer only needs the current owner's signature
+	packedOffer, err := r.PackOffer(issuerAccount)
+	if nil != err {
+		t.Errorf("pack offer error: %v", err)
+	}
+	if !bytes.Equal(packedOffer, offer) {
+		t.Errorf("pack offer: %x  expected: %x", packedOffer, offer)
+	}
+
+	// test the packer
+	packed, err := r.Pack(issuerAccount)
+	if nil != err {
+		t.Errorf("pack error: %v", err)
+	}
+
+	// if either of above fail we will have the message _without_ a signature
+	if !bytes.Equal(packed, expected) {
+		t.Errorf("pack record: %x  expected: %x", packed, expected)
+		t.Errorf("*** GENERATED Packed:\n%s", util.FormatBytes("expected", packed))
+		t.Fatal("fatal error")
+	}
+
+	t.Logf("Packed length: %d bytes", len(packed))
+
+	// check txId
+	txId := packed.MakeLink()
+
+	if txId != expectedTxId {
+		t.Errorf("pack txId: %#v  expected: %x", txId, expectedTxId)
+		t.Errorf("*** GENERATED txId:\n%s", util.FormatBytes("expectedTxId", txId[:]))
+		t.Fatal("fatal error")
+	}
+
+	// test the unpacker
+	unpacked, n, err := packed.Unpack()
+	if nil != err {
+		t.Fatalf("unpack error: %v", err)
+	}
+	if len(packed) != n {
+		t.Errorf("did not unpack all data: only used: %d of: %d bytes", n, len(packed))
+	}
+
+	bmt, ok := unpacked.(*transactionrecord.BitmarkTransferCountersigned)
+	if !ok {
+		t.Fatalf("did not unpack to BitmarkTransferCountersigned")
+	}
+
+	// display a JSON version for information
+	item := struct {
+		TxId                         merkle.Digest
+		BitmarkTransferCountersigned *transactionrecord.BitmarkTransferCountersigned
+	}{
+		txId,
+		bmt,
+	}
+	b, err := json.MarshalIndent(item, "", "  ")
+	if nil != err {
+		t.Fatalf("json error: %v", err)
+	}
+
+	t.Logf("Bitmark Transfer Countersigned: JSON: %s", b)
+
+	// check that structure is preserved through Pack/Unpack
+	// note reg is a pointer here
+	if !reflect.DeepEqual(r, *bmt) {
+		t.Fatalf("different, original: %v  recovered: %v", r, *bmt)
+	}
+
+	// the countersignature must be from the new owner
+	r.Countersignature = signature[:]
+	if _, err := r.Pack(issuerAccount); fault.ErrInvalidCountersignature != err {
+		t.Errorf("pack with wrong countersignature: error: %v  expected: %v", err, fault.ErrInvalidCountersignature)
+	}
+	if _, err := r.Pack(ownerOneAccount); fault.ErrInvalidSignature != err {
+		t.Errorf("pack with wrong owner: error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+}
+
+// test the packing/unpacking of Bitmark batch transfer record
+//
+// ensures that pack->unpack returns the same original value
+func TestPackBitmarkBatchTransfer(t *testing.T) {
+
+	issuerAccount := makeAccount(issuer.publicKey)
+	ownerOneAccount := makeAccount(ownerOne.publicKey)
+
+	links := make([]merkle.Digest, 2)
+	for i, s := range []string{
+		"79a67be2b3d313bd490363fb0d27901c46ed53d3f7b21f60d48bc42439b06084",
+		"630c041cd1f586bcb9097e816189185c1e0379f67bbfc2f0626724f542047873",
+	} {
+		err := merkleDigestFromLE(s, &links[i])
+		if nil != err {
+			t.Fatalf("hex to link error: %v", err)
+		}
+	}
+
+	r := transactionrecord.BitmarkBatchTransfer{
+		Links: links,
+		Owner: ownerOneAccount,
+	}
+
+	expected := []byte{
+		0x07, 0x02, 0x20, 0x79, 0xa6, 0x7b, 0xe2, 0xb3,
+		0xd3, 0x13, 0xbd, 0x49, 0x03, 0x63, 0xfb, 0x0d,
+		0x27, 0x90, 0x1c, 0x46, 0xed, 0x53, 0xd3, 0xf7,
+		0xb2, 0x1f, 0x60, 0xd4, 0x8b, 0xc4, 0x24, 0x39,
+		0xb0, 0x60, 0x84, 0x20, 0x63, 0x0c, 0x04, 0x1c,
+		0xd1, 0xf5, 0x86, 0xbc, 0xb9, 0x09, 0x7e, 0x81,
+		0x61, 0x89, 0x18, 0x5c, 0x1e, 0x03, 0x79, 0xf6,
+		0x7b, 0xbf, 0xc2, 0xf0, 0x62, 0x67, 0x24, 0xf5,
+		0x42, 0x04, 0x78, 0x73, 0x21, 0x13, 0x27, 0x64,
+		0x0e, 0x4a, 0xab, 0x92, 0xd8, 0x7b, 0x4a, 0x6a,
+		0x2f, 0x30, 0xb8, 0x81, 0xf4, 0x49, 0x29, 0xf8,
+		0x66, 0x04, 0x3a, 0x84, 0x1c, 0x38, 0x14, 0xb1,
+		0x66, 0xb8, 0x89, 0x44, 0xb0, 0x92,
+	}
+
+	expectedTxId := merkle.Digest{
+		0x19, 0x7a, 0x25, 0xe3, 0xb4, 0xca, 0xdc, 0x77,
+		0x2e, 0x9b, 0x7b, 0x14, 0xab, 0xf9, 0x69, 0x34,
+		0x1f, 0xdb, 0xe2, 0xd2, 0x8d, 0x43, 0x4a, 0x4d,
+		0xdf, 0x7a, 0x31, 0x76, 0x10, 0x8b, 0x38, 0xf0,
+	}
+
+	// manually sign the record and attach signature to "expected"
+	signature := ed25519.Sign(issuer.privateKey, expected)
+	r.Signature = signature[:]
+	l := util.ToVarint64(uint64(len(signature)))
+	expected = append(expected, l...)
+	expected = append(expected, signature[:]...)
+
+	// test the packer
+	packed, err := r.Pack(issuerAccount)
+	if nil != err {
+		t.Errorf("pack error: %v", err)
+	}
+
+	// if either of above fail we will have the message _without_ a signature
+	if !bytes.Equal(packed, expected) {
+		t.Errorf("pack record: %x  expected: %x", packed, expected)
+		t.Errorf("*** GENERATED Packed:\n%s", util.FormatBytes("expected", packed))
+		t.Fatal("fatal error")
+	}
+
+	t.Logf("Packed length: %d bytes", len(packed))
+
+	// check txId
+	txId := packed.MakeLink()
+
+	if txId != expectedTxId {
+		t.Errorf("pack txId: %#v  expected: %x", txId, expectedTxId)
+		t.Errorf("*** GENERATED txId:\n%s", util.FormatBytes("expectedTxId", txId[:]))
+		t.Fatal("fatal error")
+	}
+
+	// test the unpacker
+	unpacked, n, err := packed.Unpack()
+	if nil != err {
+		t.Fatalf("unpack error: %v", err)
+	}
+	if len(packed) != n {
+		t.Errorf("did not unpack all data: only used: %d of: %d bytes", n, len(packed))
+	}
+
+	bmb, ok := unpacked.(*transactionrecord.BitmarkBatchTransfer)
+	if !ok {
+		t.Fatalf("did not unpack to BitmarkBatchTransfer")
+	}
+
+	// display a JSON version for information
+	item := struct {
+		TxId                 merkle.Digest
+		BitmarkBatchTransfer *transactionrecord.BitmarkBatchTransfer
+	}{
+		txId,
+		bmb,
+	}
+	b, err := json.MarshalIndent(item, "", "  ")
+	if nil != err {
+		t.Fatalf("json error: %v", err)
+	}
+
+	t.Logf("Bitmark Batch Transfer: JSON: %s", b)
+
+	// check that structure is preserved through Pack/Unpack
+	// note reg is a pointer here
+	if !reflect.DeepEqual(r, *bmb) {
+		t.Fatalf("different, original: %v  recovered: %v", r, *bmb)
+	}
+
+	// each bitmark gets its own link for the next transfer
+	if transactionrecord.BatchLinkId(txId, links[0]) == transactionrecord.BatchLinkId(txId, links[1]) {
+		t.Errorf("batch link ids are the same")
+	}
+
+	// invalid link lists
+	for _, test := range []struct {
+		title string
+		links []merkle.Digest
+		err   error
+	}{
+		{"no links", []merkle.Digest{}, fault.ErrInvalidCount},
+		{"too many links", make([]merkle.Digest, transactionrecord.MaximumBatchLinks+1), fault.ErrInvalidCount},
+		{"repeated link", []merkle.Digest{links[0], links[1], links[0]}, fault.ErrDoubleTransferAttempt},
+	} {
+		r.Links = test.links
+		if _, err := r.Pack(issuerAccount); test.err != err {
+			t.Errorf("%s: error: %v  expected: %v", test.title, err, test.err)
+		}
+	}
+}
+
+// test the packing/unpacking of a block foundation record
+//
+// ensures that pack->unpack returns the same original value
+func TestPackBlockFoundation(t *testing.T) {
+
+	proofedbyAccount := makeAccount(proofedby.publicKey)
+
+	r := transactionrecord.BlockFoundation{
+		Payments: []transactionrecord.PaymentAddress{
+			{
+				Currency: currency.Bitcoin,
+				Address:  "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn",
+			},
+		},
+		Owner: proofedbyAccount,
+		Nonce: 0x12345678,
+	}
+
+	expected := []byte{
+		0x08, 0x01, 0x01, 0x22, 0x6d, 0x69, 0x70, 0x63,
+		0x42, 0x62, 0x46, 0x67, 0x39, 0x67, 0x4d, 0x69,
+		0x43, 0x68, 0x38, 0x31, 0x4b, 0x6a, 0x38, 0x74,
+		0x71, 0x71, 0x64, 0x67, 0x6f, 0x5a, 0x75, 0x62,
+		0x31, 0x5a, 0x4a, 0x52, 0x66, 0x6e, 0x21, 0x13,
+		0x55, 0xb2, 0x98, 0x88, 0x17, 0xf7, 0xea, 0xec,
+		0x37, 0x74, 0x1b, 0x82, 0x44, 0x71, 0x63, 0xca,
+		0xaa, 0x5a, 0x9d, 0xb2, 0xb6, 0xf0, 0xce, 0x72,
+		0x26, 0x26, 0x33, 0x8e, 0x5e, 0x3f, 0xd7, 0xf7,
+		0xf8, 0xac, 0xd1, 0x91, 0x01,
+	}
+
+	expectedTxId := merkle.Digest{
+		0x87, 0x10, 0x2a, 0xd2, 0x03, 0xf7, 0x2d, 0x2a,
+		0xff, 0xe5, 0xd9, 0x57, 0xaa, 0x9c, 0xd9, 0x5a,
+		0x46, 0xc0, 0x57, 0x0e, 0x08, 0xbc, 0xf0, 0xb6,
+		0xa9, 0x13, 0xce, 0x40, 0xeb, 0xb1, 0x7e, 0x83,
+	}
+
+	// manually sign the record and attach signature to "expected"
+	signature := ed25519.Sign(proofedby.privateKey, expected)
+	r.Signature = signature[:]
+	l := util.ToVarint64(uint64(len(signature)))
+	expected = append(expected, l...)
+	expected = append(expected, signature[:]...)
+
+	// test the packer
+	packed, err := r.Pack(proofedbyAccount)
+	if nil != err {
+		t.Errorf("pack error: %v", err)
+	}
+
+	// if either of above fail we will have the message _without_ a signature
+	if !bytes.Equal(packed, expected) {
+		t.Errorf("pack record: %x  expected: %x", packed, expected)
+		t.Errorf("*** GENERATED Packed:\n%s", util.FormatBytes("expected", packed))
+		t.Fatal("fatal error")
+	}
+
+	// check the record type
+	if transactionrecord.BlockFoundationTag != packed.Type() {
+		t.Fatalf("pack record type: %x  expected: %x", packed.Type(), transactionrecord.BlockFoundationTag)
+	}
+
+	t.Logf("Packed length: %d bytes", len(packed))
+
+	// check txId
+	txId := packed.MakeLink()
+
+	if txId != expectedTxId {
+		t.Errorf("pack txId: %#v  expected: %x", txId, expectedTxId)
+		t.Errorf("*** GENERATED txId:\n%s", util.FormatBytes("expectedTxId", txId[:]))
+		t.Fatal("fatal error")
+	}
+
+	// test the unpacker
+	unpacked, n, err := packed.Unpack()
+	if nil != err {
+		t.Fatalf("unpack error: %v", err)
+	}
+	if len(packed) != n {
+		t.Errorf("did not unpack all data: only used: %d of: %d bytes", n, len(packed))
+	}
+
+	foundation, ok := unpacked.(*transactionrecord.BlockFoundation)
+	if !ok {
+		t.Fatalf("did not unpack to BlockFoundation")
+	}
+
+	// display a JSON version for information
+	item := struct {
+		TxId            merkle.Digest
+		BlockFoundation *transactionrecord.BlockFoundation
+	}{
+		txId,
+		foundation,
+	}
+	b, err := json.MarshalIndent(item, "", "  ")
+	if nil != err {
+		t.Fatalf("json error: %v", err)
+	}
+
+	t.Logf("Block Foundation: JSON: %s", b)
+
+	// check that structure is preserved through Pack/Unpack
+	// note reg is a pointer here
+	if !reflect.DeepEqual(r, *foundation) {
+		t.Fatalf("different, original: %v  recovered: %v", r, *foundation)
+	}
+
+	// invalid payment address lists
+	for _, test := range []struct {
+		title    string
+		payments []transactionrecord.PaymentAddress
+		err      error
+	}{
+		{"no addresses", []transactionrecord.PaymentAddress{}, fault.ErrInvalidCount},
+		{"more addresses than currencies", append(r.Payments, r.Payments...), fault.ErrInvalidCount},
+		{"no currency", []transactionrecord.PaymentAddress{{Currency: currency.Nothing, Address: "nulladdress"}}, fault.ErrInvalidCurrency},
+		{"address too long", []transactionrecord.PaymentAddress{{Currency: currency.Bitcoin, Address: strings.Repeat("m", 65)}}, fault.ErrPaymentAddressTooLong},
+	} {
+		r.Payments = test.payments
+		if _, err := r.Pack(proofedbyAccount); test.err != err {
+			t.Errorf("%s: error: %v  expected: %v", test.title, err, test.err)
+		}
+	}
+}
+
+// test the packing/unpacking of records with an ECDSA P-256 owner
+//
+// the signature is not deterministic so only the round trip is checked
+func TestPackBitmarkTransferECDSAP256(t *testing.T) {
+
+	// the key from RFC 6979 A.2.5
+	scalar := []byte{
+		0xc9, 0xaf, 0xa9, 0xd8, 0x45, 0xba, 0x75, 0x16,
+		0x6b, 0x5c, 0x21, 0x57, 0x67, 0xb1, 0xd6, 0x93,
+		0x4e, 0x50, 0xc3, 0xdb, 0x36, 0xe8, 0x9b, 0x12,
+		0x7b, 0x8a, 0x62, 0x2b, 0x12, 0x0f, 0x67, 0x21,
+	}
+	privateKey, err := account.PrivateKeyFromBytes(append([]byte{account.ECDSAP256<<4 | 0x02}, scalar...))
+	if nil != err {
+		t.Fatalf("private key error: %v", err)
+	}
+	signer := privateKey.PrivateKeyInterface.(*account.ECDSAP256PrivateKey)
+	ownerAccount := privateKey.Account()
+
+	var link merkle.Digest
+	err = merkleDigestFromLE("79a67be2b3d313bd490363fb0d27901c46ed53d3f7b21f60d48bc42439b06084", &link)
+	if nil != err {
+		t.Fatalf("hex to link error: %v", err)
+	}
+
+	issue := transactionrecord.BitmarkIssue{
+		AssetIndex: transactionrecord.NewAssetIndex([]byte("ecdsa fingerprint")),
+		Owner:      ownerAccount,
+		Nonce:      99,
+	}
+	message, _ := issue.Pack(ownerAccount)
+	issue.Signature, err = signer.Sign(message)
+	if nil != err {
+		t.Fatalf("sign error: %v", err)
+	}
+	packed, err := issue.Pack(ownerAccount)
+	if nil != err {
+		t.Fatalf("pack issue error: %v", err)
+	}
+	unpacked, _, err := packed.Unpack()
+	if nil != err {
+		t.Fatalf("unpack issue error: %v", err)
+	}
+	if !reflect.DeepEqual(issue, *unpacked.(*transactionrecord.BitmarkIssue)) {
+		t.Errorf("different, original: %v  recovered: %v", issue, unpacked)
+	}
+
+	transfer := transactionrecord.BitmarkTransfer{
+		Link:  link,
+		Owner: makeAccount(ownerOne.publicKey),
+	}
+	message, _ = transfer.Pack(ownerAccount)
+	transfer.Signature, err = signer.Sign(message)
+	if nil != err {
+		t.Fatalf("sign error: %v", err)
+	}
+	packed, err = transfer.Pack(ownerAccount)
+	if nil != err {
+		t.Fatalf("pack transfer error: %v", err)
+	}
+	unpacked, _, err = packed.Unpack()
+	if nil != err {
+		t.Fatalf("unpack transfer error: %v", err)
+	}
+	if !reflect.DeepEqual(transfer, *unpacked.(*transactionrecord.BitmarkTransfer)) {
+		t.Errorf("different, original: %v  recovered: %v", transfer, unpacked)
+	}
+
+	// an ED25519 signature cannot be used for the ECDSA owner
+	transfer.Signature = ed25519.Sign(ownerOne.privateKey, message)
+	if _, err := transfer.Pack(ownerAccount); fault.ErrInvalidSignature != err {
+		t.Errorf("pack with ED25519 signature: error: %v  expected: %v", err, fault.ErrInvalidSignature)
+	}
+}
@@ -25,6 +25,11 @@ const (
 	AssetDataTag       = TagType(iota)
 	BitmarkIssueTag    = TagType(iota)
 	BitmarkTransferTag = TagType(iota)
+	BitmarkBurnTag     = TagType(iota)
+
+	BitmarkTransferCountersignedTag = TagType(iota)
+	BitmarkBatchTransferTag         = TagType(iota)
+	BlockFoundationTag              = TagType(iota)
 
 	// this item must be last
 	InvalidTag = TagType(iota)
@@ -38,6 +43,11 @@ type Transaction interface {
 	Pack(account *account.Account) (Packed, error)
 }
 
+// the most bitmarks that can be moved by one batch transfer
+const (
+	MaximumBatchLinks = 1000
+)
+
 // byte sizes for various fields
 const (
 	minNameLength           = 1
@@ -48,6 +58,7 @@ const (
 	maxSignatureLength      = 1024
 	maxTimestampLength      = len("2014-06-21T14:32:16Z")
 	maxPaymentAddressLength = 64
+	maxPaymentAddresses     = int(currency.Last - currency.First + 1)
 )
 
 // the unpacked Proofer Data structure
@@ -59,6 +70,23 @@ type BaseData struct {
 	Signature      account.Signature `json:"signature,"`     // hex
 }
 
+// one way to pay the owner of a block
+type PaymentAddress struct {
+	Currency currency.Currency `json:"currency"` // utf-8 → Enum
+	Address  string            `json:"address"`  // utf-8
+}
+
+// the unpacked Block Foundation structure
+//
+// this replaces BaseData so that the owner of a block can be paid in
+// any of the listed currencies
+type BlockFoundation struct {
+	Payments  []PaymentAddress  `json:"payments"`     // one address for each currency
+	Owner     *account.Account  `json:"owner"`        // base58
+	Nonce     uint64            `json:"nonce,string"` // unsigned 0..N
+	Signature account.Signature `json:"signature"`    // hex
+}
+
 // the unpacked Asset Data structure
 type AssetData struct {
 	Name        string            `json:"name"`        // utf-8
@@ -83,6 +111,9 @@ type Payment struct {
 	Amount   uint64            `json:"amount,string"` // number as string, in terms of smallest currency unit
 }
 
+// all of the payments needed for a transfer in a single currency
+type PaymentAlternative []*Payment
+
 // the unpacked BitmarkTransfer structure
 type BitmarkTransfer struct {
 	Link      merkle.Digest     `json:"link"`      // previous record
@@ -91,6 +122,34 @@ type BitmarkTransfer struct {
 	Signature account.Signature `json:"signature"` // hex: corresponds to owner in linked record
 }
 
+// the unpacked BitmarkTransferCountersigned structure
+//
+// both signatures are over the same message, so the new owner must
+// accept the transfer before it can be recorded
+type BitmarkTransferCountersigned struct {
+	Link             merkle.Digest     `json:"link"`             // previous record
+	Payment          *Payment          `json:"payment"`          // optional payment address
+	Owner            *account.Account  `json:"owner"`            // base58: the "destination" owner
+	Signature        account.Signature `json:"signature"`        // hex: corresponds to owner in linked record
+	Countersignature account.Signature `json:"countersignature"` // hex: corresponds to owner in this record
+}
+
+// the unpacked BitmarkBatchTransfer structure
+//
+// all links must have the same current owner; after confirmation a
+// later transfer of one of the bitmarks links to its BatchLinkId
+type BitmarkBatchTransfer struct {
+	Links     []merkle.Digest   `json:"links"`     // previous records
+	Owner     *account.Account  `json:"owner"`     // base58: the "destination" owner
+	Signature account.Signature `json:"signature"` // hex: corresponds to owner in all linked records
+}
+
+// the unpacked BitmarkBurn structure
+type BitmarkBurn struct {
+	Link      merkle.Digest     `json:"link"`      // previous record
+	Signature account.Signature `json:"signature"` // hex: corresponds to owner in linked record
+}
+
 // determine the record type code
 func (record Packed) Type() TagType {
 	recordType, _ := util.FromVarint64(record)
@@ -103,6 +162,9 @@ func RecordName(record interface{}) (string, bool) {
 	case *BaseData, BaseData:
 		return "BaseData", true
 
+	case *BlockFoundation, BlockFoundation:
+		return "BlockFoundation", true
+
 	case *AssetData, AssetData:
 		return "AssetData", true
 
@@ -112,6 +174,15 @@ func RecordName(record interface{}) (string, bool) {
 	case *BitmarkTransfer, BitmarkTransfer:
 		return "BitmarkTransfer", true
 
+	case *BitmarkBurn, BitmarkBurn:
+		return "BitmarkBurn", true
+
+	case *BitmarkTransferCountersigned, BitmarkTransferCountersigned:
+		return "BitmarkTransferCountersigned", true
+
+	case *BitmarkBatchTransfer, BitmarkBatchTransfer:
+		return "BitmarkBatchTransfer", true
+
 	default:
 		return "*unknown*", false
 	}
@@ -122,6 +193,13 @@ func (assetData *AssetData) AssetIndex() AssetIndex {
 	return NewAssetIndex([]byte(assetData.Fingerprint))
 }
 
+// the id that stands for one bitmark moved by a batch transfer
+//
+// this is the link used by the next transfer of that bitmark
+func BatchLinkId(txId merkle.Digest, link merkle.Digest) merkle.Digest {
+	return merkle.NewDigest(append(txId[:], link[:]...))
+}
+
 // Create an link for a packed record
 func (p Packed) MakeLink() merkle.Digest {
 	return merkle.NewDigest(p)
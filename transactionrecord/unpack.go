@@ -77,6 +77,43 @@ func (record Packed) Unpack() (Transaction, int, error) {
 		}
 		return r, n, nil
 
+	case BlockFoundationTag:
+
+		// payment addresses
+		payments, paymentsLength, err := UnpackPaymentAddresses(record[n:])
+		if nil != err {
+			return nil, 0, err
+		}
+		n += paymentsLength
+
+		// owner public key
+		ownerLength, ownerOffset := util.FromVarint64(record[n:])
+		n += ownerOffset
+		owner, err := account.AccountFromBytes(record[n : n+int(ownerLength)])
+		if nil != err {
+			return nil, 0, err
+		}
+		n += int(ownerLength)
+
+		// nonce
+		nonce, nonceLength := util.FromVarint64(record[n:])
+		n += int(nonceLength)
+
+		// signature is remainder of record
+		signatureLength, signatureOffset := util.FromVarint64(record[n:])
+		signature := make(account.Signature, signatureLength)
+		n += signatureOffset
+		copy(signature, record[n:])
+		n += int(signatureLength)
+
+		r := &BlockFoundation{
+			Payments:  payments,
+			Owner:     owner,
+			Nonce:     nonce,
+			Signature: signature,
+		}
+		return r, n, nil
+
 	case AssetDataTag:
 
 		// name
@@ -169,52 +206,73 @@ func (record Packed) Unpack() (Transaction, int, error) {
 
 	case BitmarkTransferTag:
 
-		// link
-		linkLength, linkOffset := util.FromVarint64(record[n:])
-		n += linkOffset
-		var link merkle.Digest
-		err := merkle.DigestFromBytes(&link, record[n:n+int(linkLength)])
+		link, payment, owner, n, err := unpackTransfer(record, n)
 		if nil != err {
 			return nil, 0, err
 		}
-		n += int(linkLength)
 
-		// optional payment
-		payment := (*Payment)(nil)
+		// signature is remainder of record
+		signatureLength, signatureOffset := util.FromVarint64(record[n:])
+		signature := make(account.Signature, signatureLength)
+		n += signatureOffset
+		copy(signature, record[n:])
+		n += int(signatureLength)
+
+		r := &BitmarkTransfer{
+			Link:      link,
+			Payment:   payment,
+			Owner:     owner,
+			Signature: signature,
+		}
+		return r, n, nil
+
+	case BitmarkTransferCountersignedTag:
+
+		link, payment, owner, n, err := unpackTransfer(record, n)
+		if nil != err {
+			return nil, 0, err
+		}
+
+		// signature of the current owner
+		signatureLength, signatureOffset := util.FromVarint64(record[n:])
+		signature := make(account.Signature, signatureLength)
+		n += signatureOffset
+		copy(signature, record[n:])
+		n += int(signatureLength)
+
+		// countersignature of the new owner is remainder of record
+		countersignatureLength, countersignatureOffset := util.FromVarint64(record[n:])
+		countersignature := make(account.Signature, countersignatureLength)
+		n += countersignatureOffset
+		copy(countersignature, record[n:])
+		n += int(countersignatureLength)
+
+		r := &BitmarkTransferCountersigned{
+			Link:             link,
+			Payment:          payment,
+			Owner:            owner,
+			Signature:        signature,
+			Countersignature: countersignature,
+		}
+		return r, n, nil
 
-		if 0 == record[n] {
-			n += 1
-		} else if 1 == record[n] {
-			n += 1
+	case BitmarkBatchTransferTag:
 
-			// currency
-			c := uint64(0)
-			var currencyLength int
-			c, currencyLength = util.FromVarint64(record[n:])
-			n += int(currencyLength)
-			currency, err := currency.FromUint64(c)
+		// links
+		count, countLength := util.FromVarint64(record[n:])
+		n += countLength
+		if 0 == count || count > MaximumBatchLinks {
+			return nil, 0, fault.ErrInvalidCount
+		}
+		links := make([]merkle.Digest, count)
+		for i := range links {
+			linkLength, linkOffset := util.FromVarint64(record[n:])
+			n += linkOffset
+			err := merkle.DigestFromBytes(&links[i], record[n:n+int(linkLength)])
 			if nil != err {
 				return nil, 0, err
 			}
-
-			// address
-			addressLength, addressOffset := util.FromVarint64(record[n:])
-			address := make([]byte, addressLength)
-			n += addressOffset
-			copy(address, record[n:])
-			n += int(addressLength)
-
-			// amount
-			amount, amountLength := util.FromVarint64(record[n:])
-			n += int(amountLength)
-
-			payment = &Payment{
-				Currency: currency,
-				Address:  string(address),
-				Amount:   amount,
-			}
-		} else {
-			return nil, 0, fault.ErrNotTransactionPack
+			n += int(linkLength)
 		}
 
 		// owner public key
@@ -233,15 +291,140 @@ func (record Packed) Unpack() (Transaction, int, error) {
 		copy(signature, record[n:])
 		n += int(signatureLength)
 
-		r := &BitmarkTransfer{
-			Link:      link,
-			Payment:   payment,
+		r := &BitmarkBatchTransfer{
+			Links:     links,
 			Owner:     owner,
 			Signature: signature,
 		}
 		return r, n, nil
 
+	case BitmarkBurnTag:
+
+		// link
+		linkLength, linkOffset := util.FromVarint64(record[n:])
+		n += linkOffset
+		var link merkle.Digest
+		err := merkle.DigestFromBytes(&link, record[n:n+int(linkLength)])
+		if nil != err {
+			return nil, 0, err
+		}
+		n += int(linkLength)
+
+		// signature is remainder of record
+		signatureLength, signatureOffset := util.FromVarint64(record[n:])
+		signature := make(account.Signature, signatureLength)
+		n += signatureOffset
+		copy(signature, record[n:])
+		n += int(signatureLength)
+
+		r := &BitmarkBurn{
+			Link:      link,
+			Signature: signature,
+		}
+		return r, n, nil
+
 	default:
 	}
 	return nil, 0, fault.ErrNotTransactionPack
 }
+
+// unpack a list of payment addresses, see: PackPaymentAddresses
+//
+// returns the number of bytes used
+func UnpackPaymentAddresses(buffer []byte) ([]PaymentAddress, int, error) {
+
+	count, n := util.FromVarint64(buffer)
+	if 0 == count || count > uint64(maxPaymentAddresses) {
+		return nil, 0, fault.ErrInvalidCount
+	}
+
+	addresses := make([]PaymentAddress, count)
+	for i := range addresses {
+
+		// currency
+		c, currencyLength := util.FromVarint64(buffer[n:])
+		n += currencyLength
+		paymentCurrency, err := currency.FromUint64(c)
+		if nil != err {
+			return nil, 0, err
+		}
+
+		// address
+		addressLength, addressOffset := util.FromVarint64(buffer[n:])
+		n += addressOffset
+		if n+int(addressLength) > len(buffer) {
+			return nil, 0, fault.ErrInvalidLength
+		}
+		addresses[i] = PaymentAddress{
+			Currency: paymentCurrency,
+			Address:  string(buffer[n : n+int(addressLength)]),
+		}
+		n += int(addressLength)
+	}
+	return addresses, n, nil
+}
+
+// unpack the fields common to both kinds of transfer
+//
+// returns the offset of the first signature
+func unpackTransfer(record Packed, n int) (merkle.Digest, *Payment, *account.Account, int, error) {
+
+	// link
+	linkLength, linkOffset := util.FromVarint64(record[n:])
+	n += linkOffset
+	var link merkle.Digest
+	err := merkle.DigestFromBytes(&link, record[n:n+int(linkLength)])
+	if nil != err {
+		return link, nil, nil, 0, err
+	}
+	n += int(linkLength)
+
+	// optional payment
+	payment := (*Payment)(nil)
+
+	if 0 == record[n] {
+		n += 1
+	} else if 1 == record[n] {
+		n += 1
+
+		// currency
+		c := uint64(0)
+		var currencyLength int
+		c, currencyLength = util.FromVarint64(record[n:])
+		n += int(currencyLength)
+		currency, err := currency.FromUint64(c)
+		if nil != err {
+			return link, nil, nil, 0, err
+		}
+
+		// address
+		addressLength, addressOffset := util.FromVarint64(record[n:])
+		address := make([]byte, addressLength)
+		n += addressOffset
+		copy(address, record[n:])
+		n += int(addressLength)
+
+		// amount
+		amount, amountLength := util.FromVarint64(record[n:])
+		n += int(amountLength)
+
+		payment = &Payment{
+			Currency: currency,
+			Address:  string(address),
+			Amount:   amount,
+		}
+	} else {
+		return link, nil, nil, 0, fault.ErrNotTransactionPack
+	}
+
+	// owner public key
+	ownerLength, ownerOffset := util.FromVarint64(record[n:])
+	n += ownerOffset
+	owner, err := account.AccountFromBytes(record[n : n+int(ownerLength)])
+	if nil != err {
+		return link, nil, nil, 0, err
+	}
+	n += int(ownerLength)
+
+	return link, payment, owner, n, nil
+}
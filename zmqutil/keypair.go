@@ -7,6 +7,7 @@ package zmqutil
 import (
 	"encoding/hex"
 	"github.com/bitmark-inc/bitmarkd/fault"
+	"github.com/bitmark-inc/bitmarkd/keyfile"
 	"github.com/bitmark-inc/bitmarkd/util"
 	zmq "github.com/pebbe/zmq4"
 	"io/ioutil"
@@ -77,12 +78,14 @@ func ReadPrivateKeyFile(keyFileName string) ([]byte, error) {
 	return data, err
 }
 
-// read a public key from a file returning it as a 32 byte string
+// read a public or private key from a file returning it as a 32 byte string
+//
+// an encrypted file is decrypted, see: keyfile.Read
 func ReadKeyFile(keyFileName string) ([]byte, bool, error) {
 	if !util.EnsureFileExists(keyFileName) {
 		return []byte{}, false, fault.ErrKeyFileNotFound
 	}
-	data, err := ioutil.ReadFile(keyFileName)
+	data, err := keyfile.Read(keyFileName)
 	if err != nil {
 		return []byte{}, false, err
 	}